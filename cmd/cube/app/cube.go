@@ -17,6 +17,9 @@ limitations under the License.
 package app
 
 import (
+	"context"
+	"time"
+
 	"github.com/kubecube-io/kubecube/cmd/cube/app/options"
 	"github.com/kubecube-io/kubecube/cmd/cube/app/options/flags"
 	"github.com/kubecube-io/kubecube/pkg/apiserver"
@@ -25,6 +28,14 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/ctrlmgr"
 	"github.com/kubecube-io/kubecube/pkg/cube"
+	"github.com/kubecube-io/kubecube/pkg/features"
+	"github.com/kubecube-io/kubecube/pkg/metrics"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/catalog"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/inventory"
+	"github.com/kubecube-io/kubecube/pkg/quota"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/international"
 
 	"github.com/urfave/cli/v2"
@@ -62,9 +73,31 @@ func run(s *options.CubeOptions, stop <-chan struct{}) {
 	// init cube logger first
 	clog.InitCubeLoggerWithOpts(flags.CubeOpts.CubeLoggerOpts)
 
+	// initialize global feature gates, deprecated flag of version conversion is
+	// applied first, then feature gates given are merged on it, so it is only
+	// overridden when VersionConversion is given explicitly in feature gates
+	if s.APIServerOpts.EnableVersionConversion {
+		_ = features.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(features.VersionConversion): true})
+	}
+	if err := features.InitFeatureGatesWithOpts(s.FeatureGateOpts); err != nil {
+		clog.Fatal("cube initialized feature gates failed: %v", err)
+	}
+
+	// initialize tracing, spans are exported only if otlp endpoint given
+	shutdownTracing, err := tracing.Init("kubecube", s.TracingOpts)
+	if err != nil {
+		clog.Fatal("cube initialized tracing failed: %v", err)
+	}
+
 	// initialize cube client set
 	clients.InitCubeClientSetWithOpts(s.ClientMgrOpts)
 
+	// export metrics of clusters heartbeat and cube resource quotas
+	metrics.Registry.MustRegister(
+		multicluster.NewScoutCollector(),
+		quota.NewCollector(clients.Interface().Kubernetes(constants.LocalCluster).Cache()),
+	)
+
 	// initialize language managers
 	m, err := international.InitGi18nManagers()
 	if err != nil {
@@ -75,6 +108,8 @@ func run(s *options.CubeOptions, stop <-chan struct{}) {
 	c := cube.New(s.GenericCubeOpts)
 	c.IntegrateWith("cube-controller-manager", ctrlmgr.NewCtrlMgrWithOpts(s.CtrlMgrOpts))
 	c.IntegrateWith("cube-apiserver", apiserver.NewAPIServerWithOpts(s.APIServerOpts))
+	c.IntegrateWith("cube-inventory", inventory.NewAggregatorWithOpts(s.InventoryOpts))
+	c.IntegrateWith("cube-catalog", catalog.NewCatalogWithOpts(s.CatalogOpts))
 
 	err = c.Initialize()
 	if err != nil {
@@ -82,4 +117,10 @@ func run(s *options.CubeOptions, stop <-chan struct{}) {
 	}
 
 	c.Run(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		clog.Warn("shutdown tracing failed: %v", err)
+	}
 }
@@ -57,7 +57,7 @@ func init() {
 			Name:        "ca-key",
 			Destination: &CubeOpts.APIServerOpts.CaKey,
 		},
-		// todo(weilaaa): move this flag to suitable place
+		// Deprecated: use feature gate VersionConversion instead
 		&cli.BoolFlag{
 			Name:        "enable-version-conversion",
 			Value:       false,
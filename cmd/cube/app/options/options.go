@@ -28,6 +28,10 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/ctrlmgr"
 	"github.com/kubecube-io/kubecube/pkg/cube"
+	"github.com/kubecube-io/kubecube/pkg/features"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/catalog"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/inventory"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 )
 
 const (
@@ -42,6 +46,10 @@ type CubeOptions struct {
 	ClientMgrOpts   *clients.Config
 	CubeLoggerOpts  *clog.Config
 	AuthMgrOpts     *authentication.Config
+	InventoryOpts   *inventory.Config
+	CatalogOpts     *catalog.Config
+	TracingOpts     *tracing.Config
+	FeatureGateOpts *features.Config
 }
 
 func NewCubeOptions() *CubeOptions {
@@ -52,6 +60,10 @@ func NewCubeOptions() *CubeOptions {
 		ClientMgrOpts:   &clients.Config{},
 		CubeLoggerOpts:  &clog.Config{},
 		AuthMgrOpts:     &authentication.Config{},
+		InventoryOpts:   &inventory.Config{},
+		CatalogOpts:     &catalog.Config{},
+		TracingOpts:     &tracing.Config{},
+		FeatureGateOpts: &features.Config{},
 	}
 
 	return cubeOpts
@@ -89,6 +101,10 @@ func (s *CubeOptions) Validate() []error {
 	errs = append(errs, s.APIServerOpts.Validate()...)
 	errs = append(errs, s.ClientMgrOpts.Validate()...)
 	errs = append(errs, s.CtrlMgrOpts.Validate()...)
+	errs = append(errs, s.InventoryOpts.Validate()...)
+	errs = append(errs, s.CatalogOpts.Validate()...)
+	errs = append(errs, s.TracingOpts.Validate()...)
+	errs = append(errs, s.FeatureGateOpts.Validate()...)
 
 	return errs
 }
@@ -68,11 +68,45 @@ var (
 			Destination: &WardenOpts.GenericWardenOpts.TlsKey,
 		},
 
+		// metrics
+		&cli.IntFlag{
+			Name:        "metrics-port",
+			Value:       9779,
+			Destination: &WardenOpts.GenericWardenOpts.MetricsPort,
+		},
+
+		// feature gates, like "WardenAuthProxy=false"
+		&cli.StringFlag{
+			Name:        "feature-gates",
+			Destination: &WardenOpts.FeatureGateOpts.FeatureGates,
+		},
+
+		// tracing
+		&cli.StringFlag{
+			Name:        "otlp-endpoint",
+			Destination: &WardenOpts.TracingOpts.OTLPEndpoint,
+		},
+		&cli.BoolFlag{
+			Name:        "otlp-insecure",
+			Value:       false,
+			Destination: &WardenOpts.TracingOpts.OTLPInsecure,
+		},
+		&cli.Float64Flag{
+			Name:        "trace-sample-ratio",
+			Value:       1,
+			Destination: &WardenOpts.TracingOpts.SampleRatio,
+		},
+
 		// reporter
 		&cli.StringFlag{
 			Name:        "pivot-cube-host",
 			Destination: &WardenOpts.GenericWardenOpts.PivotCubeHost,
 		},
+		&cli.StringFlag{
+			Name:        "cube-ca-cert",
+			Usage:       "ca bundle to verify certificate of KubeCube, not verified if empty",
+			Destination: &WardenOpts.GenericWardenOpts.CubeCACert,
+		},
 		&cli.IntFlag{
 			Name:        "period-second",
 			Value:       3,
@@ -84,6 +118,13 @@ var (
 			Destination: &WardenOpts.GenericWardenOpts.WaitSecond,
 		},
 
+		// tunnel
+		&cli.BoolFlag{
+			Name:        "enable-tunnel",
+			Value:       false,
+			Destination: &WardenOpts.GenericWardenOpts.EnableTunnel,
+		},
+
 		// local manager
 		&cli.BoolFlag{
 			Name:        "leader-elect",
@@ -18,18 +18,24 @@ package options
 
 import (
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/features"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 	"github.com/kubecube-io/kubecube/pkg/warden"
 )
 
 type WardenOptions struct {
 	GenericWardenOpts *warden.Config
 	CubeLoggerOpts    *clog.Config
+	TracingOpts       *tracing.Config
+	FeatureGateOpts   *features.Config
 }
 
 func NewWardenOptions() *WardenOptions {
 	wardenOpts := &WardenOptions{
 		GenericWardenOpts: &warden.Config{},
 		CubeLoggerOpts:    &clog.Config{},
+		TracingOpts:       &tracing.Config{},
+		FeatureGateOpts:   &features.Config{},
 	}
 
 	return wardenOpts
@@ -39,6 +45,8 @@ func (s *WardenOptions) Validate() []error {
 	var errs []error
 
 	errs = append(errs, s.GenericWardenOpts.Validate()...)
+	errs = append(errs, s.TracingOpts.Validate()...)
+	errs = append(errs, s.FeatureGateOpts.Validate()...)
 
 	return errs
 }
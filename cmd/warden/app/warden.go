@@ -17,8 +17,13 @@ limitations under the License.
 package app
 
 import (
+	"context"
+	"time"
+
 	"github.com/kubecube-io/kubecube/cmd/warden/app/options"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/features"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 	"github.com/kubecube-io/kubecube/pkg/warden"
 
 	"github.com/urfave/cli/v2"
@@ -42,12 +47,29 @@ func run(s *options.WardenOptions, stop <-chan struct{}) {
 	// init cube logger first
 	clog.InitCubeLoggerWithOpts(s.CubeLoggerOpts)
 
+	// initialize global feature gates, they can be overridden by Cluster in pivot cluster
+	if err := features.InitFeatureGatesWithOpts(s.FeatureGateOpts); err != nil {
+		clog.Fatal("warden initialized feature gates failed: %v", err)
+	}
+
+	// initialize tracing, spans are exported only if otlp endpoint given
+	shutdownTracing, err := tracing.Init("warden", s.TracingOpts, tracing.ClusterKey.String(s.GenericWardenOpts.Cluster))
+	if err != nil {
+		clog.Fatal("warden initialized tracing failed: %v", err)
+	}
+
 	w := warden.NewWardenWithOpts(s.GenericWardenOpts)
 
-	err := w.Initialize()
+	err = w.Initialize()
 	if err != nil {
 		clog.Fatal("warden initialized failed: %v", err)
 	}
 
 	w.Run(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		clog.Warn("shutdown tracing failed: %v", err)
+	}
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,11 +46,66 @@ const (
 	ClusterAbnormal ClusterState = "abnormal"
 )
 
+// condition types of cluster
+const (
+	// ClusterWardenDeployed means warden and its dependencies are deployed to cluster
+	ClusterWardenDeployed = "WardenDeployed"
+
+	// ClusterHeartbeatHealthy means KubeCube receives heartbeat of warden in time
+	ClusterHeartbeatHealthy = "HeartbeatHealthy"
+
+	// ClusterAPIServerReachable means KubeCube can connect to kube-apiserver of cluster
+	ClusterAPIServerReachable = "APIServerReachable"
+
+	// ClusterMetricsAvailable means metrics api of cluster is available
+	ClusterMetricsAvailable = "MetricsAvailable"
+
+	// ClusterWritable means workloads can be deployed on cluster
+	ClusterWritable = "Writable"
+
+	// ClusterComponentsReady means all components of warden are ready
+	ClusterComponentsReady = "ComponentsReady"
+
+	// ClusterCredentialsValid means the latest kubeconfig of cluster is in use
+	ClusterCredentialsValid = "CredentialsValid"
+)
+
+// condition reasons of cluster
+const (
+	ReasonConnecting         = "Connecting"
+	ReasonConnected          = "Connected"
+	ReasonConnectFailed      = "ConnectFailed"
+	ReasonReconnectTimeout   = "ReconnectTimeout"
+	ReasonDeployed           = "Deployed"
+	ReasonDeployFailed       = "DeployFailed"
+	ReasonHeartbeatReceived  = "HeartbeatReceived"
+	ReasonHeartbeatTimeout   = "HeartbeatTimeout"
+	ReasonScoutFailed        = "ScoutFailed"
+	ReasonMetricsAvailable   = "MetricsAPIAvailable"
+	ReasonMetricsUnavailable = "MetricsAPIUnavailable"
+	ReasonComponentsReady    = "ComponentsReady"
+	ReasonComponentsNotReady = "ComponentsNotReady"
+	ReasonWritable           = "WritableBySpec"
+	ReasonReadOnly           = "ReadOnlyBySpec"
+	ReasonCredentialRotated  = "CredentialRotated"
+	ReasonRotateFailed       = "CredentialRotateFailed"
+	ReasonKubeConfigNotFound = "KubeConfigNotFound"
+)
+
 // ClusterSpec defines the desired state of Cluster
 type ClusterSpec struct {
-	// KubeConfig contains cluster raw kubeConfig
+	// KubeConfig contains cluster raw kubeConfig.
+	// Deprecated: use KubeConfigSecretRef instead, inline kubeConfig
+	// will be moved into secret by KubeCube.
+	// +optional
 	KubeConfig []byte `json:"kubeconfig,omitempty"`
 
+	// KubeConfigSecretRef references the secret which stores the raw
+	// kubeConfig of cluster with key "kubeconfig", the secret must be in
+	// namespace of KubeCube
+	// +optional
+	KubeConfigSecretRef *corev1.SecretReference `json:"kubeConfigSecretRef,omitempty"`
+
 	// Kubernetes API Server endpoint. Example: https://10.10.0.1:6443
 	KubernetesAPIEndpoint string `json:"kubernetesAPIEndpoint,omitempty"`
 
@@ -74,19 +130,212 @@ type ClusterSpec struct {
 
 	// Is this cluster writable and if true then some resources such as workloads can be deployed on this cluster
 	IsWritable bool `json:"isWritable"`
+
+	// Maintenance means cluster is under maintenance such as upgrading, writes
+	// from KubeCube, resources sync and hotplug changes are paused until cleared
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// DeletionPolicies decides to retain or purge each category of resources
+	// created by KubeCube in member cluster when cluster deleted
+	// +optional
+	DeletionPolicies []CategoryDeletionPolicy `json:"deletionPolicies,omitempty"`
+
+	// ConnectionMode is how KubeCube connects to kube-apiserver of cluster,
+	// defaults to Direct
+	// +optional
+	ConnectionMode ConnectionMode `json:"connectionMode,omitempty"`
+
+	// ClientSettings tunes the clients KubeCube used to connect cluster,
+	// the global defaults of KubeCube are used for the settings not given
+	// +optional
+	ClientSettings *ClientSettings `json:"clientSettings,omitempty"`
+
+	// FeatureGates overrides the global feature gates of KubeCube for this
+	// cluster, they take precedence over the feature gates in annotation
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// ConnectionMode is how KubeCube connects to kube-apiserver of cluster
+// +kubebuilder:validation:Enum=Direct;Tunnel
+type ConnectionMode string
+
+const (
+	// ConnectionDirect connects to kube-apiserver of cluster by the address in kubeconfig
+	ConnectionDirect ConnectionMode = "Direct"
+
+	// ConnectionTunnel connects to kube-apiserver of cluster through the reverse
+	// tunnel opened by warden, used for clusters KubeCube can not reach directly.
+	// The tunnel is held only by the KubeCube replica warden connected to, so
+	// KubeCube should run a single replica or route warden and requests of the
+	// cluster to the same replica.
+	ConnectionTunnel ConnectionMode = "Tunnel"
+)
+
+// ClientSettings tunes the clients connect to cluster
+type ClientSettings struct {
+	// QPS is the maximum queries per second to kube-apiserver of cluster
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	QPS int32 `json:"qps,omitempty"`
+
+	// Burst is the maximum burst of queries to kube-apiserver of cluster
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+
+	// Timeout is the timeout of a single request to kube-apiserver of cluster,
+	// long-running requests such as watch and exec are not limited
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// ResyncPeriod is the resync period of informers cache of cluster
+	// +optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+}
+
+// ResourceCategory is a category of resources created by KubeCube in member cluster
+// +kubebuilder:validation:Enum=Warden;Webhooks;CRDs;SyncedResources;HotplugReleases
+type ResourceCategory string
+
+const (
+	// ResourceCategoryWarden contains namespace of KubeCube where warden
+	// running in and the cluster role of KubeCube, purge is refused while
+	// archives of tenants are stored in the namespace
+	ResourceCategoryWarden ResourceCategory = "Warden"
+
+	// ResourceCategoryWebhooks contains validating webhook of warden
+	ResourceCategoryWebhooks ResourceCategory = "Webhooks"
+
+	// ResourceCategoryCRDs contains crds dispatched by KubeCube
+	ResourceCategoryCRDs ResourceCategory = "CRDs"
+
+	// ResourceCategorySyncedResources contains tenants, projects and
+	// rbac resources synced from pivot cluster by warden
+	ResourceCategorySyncedResources ResourceCategory = "SyncedResources"
+
+	// ResourceCategoryHotplugReleases contains helm releases installed by hotplug
+	ResourceCategoryHotplugReleases ResourceCategory = "HotplugReleases"
+)
+
+// DeletionPolicy decides what to do with resources of member cluster when cluster deleted
+// +kubebuilder:validation:Enum=Retain;Purge
+type DeletionPolicy string
+
+const (
+	// DeletionRetain keeps resources in member cluster
+	DeletionRetain DeletionPolicy = "Retain"
+
+	// DeletionPurge deletes resources from member cluster
+	DeletionPurge DeletionPolicy = "Purge"
+)
+
+// CategoryDeletionPolicy is the deletion policy of a category of resources
+type CategoryDeletionPolicy struct {
+	Category ResourceCategory `json:"category"`
+	Policy   DeletionPolicy   `json:"policy"`
 }
 
 // ClusterStatus defines the observed state of Cluster
 type ClusterStatus struct {
-	State         *ClusterState `json:"state,omitempty"`
-	Reason        string        `json:"reason,omitempty"`
-	LastHeartbeat *metav1.Time  `json:"lastHeartbeat,omitempty"`
+	// State is derived from conditions of cluster
+	State *ClusterState `json:"state,omitempty"`
+
+	// Reason is the message of condition which State derived from
+	Reason string `json:"reason,omitempty"`
+
+	// LastHeartbeat is the heartbeat time when cluster status synced, the
+	// real-time heartbeat of warden is recorded by lease in pivot cluster
+	LastHeartbeat *metav1.Time `json:"lastHeartbeat,omitempty"`
+
+	// LastCredentialRotation is the time when kubeconfig of cluster rotated successfully
+	// +optional
+	LastCredentialRotation *metav1.Time `json:"lastCredentialRotation,omitempty"`
+
+	// WardenVersion is the build version of warden running in cluster
+	// +optional
+	WardenVersion string `json:"wardenVersion,omitempty"`
+
+	// KubernetesVersion is the version of cluster kube-apiserver
+	// +optional
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// Latency is the round-trip time of heartbeat between warden and KubeCube
+	// +optional
+	Latency *metav1.Duration `json:"latency,omitempty"`
+
+	// NodeCount is the number of nodes in cluster
+	// +optional
+	NodeCount *int32 `json:"nodeCount,omitempty"`
+
+	// PodCount is the number of pods in cluster
+	// +optional
+	PodCount *int32 `json:"podCount,omitempty"`
+
+	// Components is the readiness of components of warden
+	// +optional
+	Components []ComponentStatus `json:"components,omitempty"`
+
+	// ReconnectAttempts is the number of consecutive failed attempts to
+	// connect cluster, it is reset once cluster connected again
+	// +optional
+	ReconnectAttempts int32 `json:"reconnectAttempts,omitempty"`
+
+	// LastReconnectError is the error of the last failed attempt to connect cluster
+	// +optional
+	LastReconnectError string `json:"lastReconnectError,omitempty"`
+
+	// LastReconnectTime is the time of the last failed attempt to connect cluster
+	// +optional
+	LastReconnectTime *metav1.Time `json:"lastReconnectTime,omitempty"`
+
+	// Conditions of cluster
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// DeletionResults records the outcome of cleaning up member cluster when cluster deleting
+	// +optional
+	DeletionResults []DeletionResult `json:"deletionResults,omitempty"`
+}
+
+// DeletionResult is the outcome of cleaning up a category of resources in member cluster
+type DeletionResult struct {
+	Category ResourceCategory `json:"category"`
+	Policy   DeletionPolicy   `json:"policy"`
+
+	// Resources found in member cluster of the category
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+
+	// Succeeded indicates if all resources were purged or retained as expected
+	Succeeded bool `json:"succeeded"`
+
+	// Message is the reason of failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ComponentStatus is the readiness of a component of warden
+type ComponentStatus struct {
+	// Name of component
+	Name string `json:"name"`
+
+	// Ready indicates if component is ready
+	Ready bool `json:"ready"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:categories="cluster",scope="Cluster"
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.kubernetesVersion"
+//+kubebuilder:printcolumn:name="Nodes",type="integer",JSONPath=".status.nodeCount",priority=1
+//+kubebuilder:printcolumn:name="Latency",type="string",JSONPath=".status.latency",priority=1
 
 // Cluster is the Schema for the clusters API
 type Cluster struct {
@@ -0,0 +1,163 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetClusterCondition sets condition into status of cluster and
+// derives the state of cluster from conditions
+func SetClusterCondition(status *ClusterStatus, condition metav1.Condition) {
+	meta.SetStatusCondition(&status.Conditions, condition)
+
+	// deleting is the terminal state of cluster
+	if status.State != nil && *status.State == ClusterDeleting {
+		return
+	}
+
+	state, reason := ClusterStateFromConditions(status.Conditions)
+	status.State = &state
+	status.Reason = reason
+}
+
+// ClusterStateFromConditions derives the state of cluster and its reason from conditions
+func ClusterStateFromConditions(conditions []metav1.Condition) (ClusterState, string) {
+	reachable := meta.FindStatusCondition(conditions, ClusterAPIServerReachable)
+	if reachable != nil && reachable.Status == metav1.ConditionFalse {
+		if reachable.Reason == ReasonReconnectTimeout {
+			return ClusterReconnectedFailed, reachable.Message
+		}
+		return ClusterInitFailed, reachable.Message
+	}
+
+	deployed := meta.FindStatusCondition(conditions, ClusterWardenDeployed)
+	if deployed != nil && deployed.Status == metav1.ConditionFalse {
+		return ClusterInitFailed, deployed.Message
+	}
+
+	heartbeat := meta.FindStatusCondition(conditions, ClusterHeartbeatHealthy)
+	if heartbeat != nil {
+		switch heartbeat.Status {
+		case metav1.ConditionTrue:
+			return ClusterNormal, heartbeat.Message
+		case metav1.ConditionFalse:
+			return ClusterAbnormal, heartbeat.Message
+		}
+	}
+
+	if reachable != nil && reachable.Status == metav1.ConditionUnknown {
+		return ClusterProcessing, reachable.Message
+	}
+
+	return ClusterProcessing, "wait for heartbeat of warden"
+}
+
+// WritableCondition makes Writable condition by writable spec of cluster
+func WritableCondition(writable bool) metav1.Condition {
+	if writable {
+		return metav1.Condition{
+			Type:    ClusterWritable,
+			Status:  metav1.ConditionTrue,
+			Reason:  ReasonWritable,
+			Message: "cluster is writable",
+		}
+	}
+
+	return metav1.Condition{
+		Type:    ClusterWritable,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonReadOnly,
+		Message: "cluster is read only",
+	}
+}
+
+// IsKubeConfigChanged tells if the kubeConfig of cluster changed between old
+// and new one. Moving inline kubeConfig into secret is not regarded as changed.
+func IsKubeConfigChanged(old, new *Cluster) bool {
+	if len(new.Spec.KubeConfig) > 0 && !bytes.Equal(old.Spec.KubeConfig, new.Spec.KubeConfig) {
+		return true
+	}
+
+	oldRef, newRef := old.Spec.KubeConfigSecretRef, new.Spec.KubeConfigSecretRef
+	if oldRef != nil && newRef != nil && *oldRef != *newRef {
+		return true
+	}
+
+	return false
+}
+
+// IsConnectionChanged tells if the connection mode or client settings of
+// cluster changed between old and new one
+func IsConnectionChanged(old, new *Cluster) bool {
+	return old.Spec.ConnectionMode != new.Spec.ConnectionMode ||
+		!reflect.DeepEqual(old.Spec.ClientSettings, new.Spec.ClientSettings)
+}
+
+// ResourceCategories are all categories of resources in member cluster in the
+// order of cleaning up. Warden goes before synced resources so that they will
+// not be synced back, and crds go last cause custom resources are gone with them.
+var ResourceCategories = []ResourceCategory{
+	ResourceCategoryHotplugReleases,
+	ResourceCategoryWarden,
+	ResourceCategoryWebhooks,
+	ResourceCategorySyncedResources,
+	ResourceCategoryCRDs,
+}
+
+// EffectiveDeletionPolicies returns the deletion policy of every category in the order
+// of cleaning up. The category not given in policies is retained if retainByDefault,
+// or else only warden and its webhooks will be purged. Webhooks are always purged
+// together with warden, otherwise the dangling webhooks would reject requests.
+func EffectiveDeletionPolicies(policies []CategoryDeletionPolicy, retainByDefault bool) []CategoryDeletionPolicy {
+	given := make(map[ResourceCategory]DeletionPolicy, len(policies))
+	for _, p := range policies {
+		given[p.Category] = p.Policy
+	}
+
+	policyOf := func(category ResourceCategory) DeletionPolicy {
+		if p, ok := given[category]; ok {
+			return p
+		}
+		if retainByDefault {
+			return DeletionRetain
+		}
+		switch category {
+		case ResourceCategoryWarden, ResourceCategoryWebhooks:
+			return DeletionPurge
+		default:
+			return DeletionRetain
+		}
+	}
+
+	wardenPurged := policyOf(ResourceCategoryWarden) == DeletionPurge
+
+	effective := make([]CategoryDeletionPolicy, 0, len(ResourceCategories))
+	for _, category := range ResourceCategories {
+		policy := policyOf(category)
+		if category == ResourceCategoryWebhooks && wardenPurged {
+			policy = DeletionPurge
+		}
+		effective = append(effective, CategoryDeletionPolicy{Category: category, Policy: policy})
+	}
+
+	return effective
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func condition(conditionType string, status metav1.ConditionStatus, reason string) metav1.Condition {
+	return metav1.Condition{Type: conditionType, Status: status, Reason: reason, Message: reason}
+}
+
+func TestSetClusterCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	status := &ClusterStatus{}
+
+	SetClusterCondition(status, condition(ClusterAPIServerReachable, metav1.ConditionUnknown, ReasonConnecting))
+	assert.Equal(ClusterProcessing, *status.State)
+
+	SetClusterCondition(status, condition(ClusterAPIServerReachable, metav1.ConditionFalse, ReasonConnectFailed))
+	assert.Equal(ClusterInitFailed, *status.State)
+	assert.Equal(ReasonConnectFailed, status.Reason)
+
+	SetClusterCondition(status, condition(ClusterAPIServerReachable, metav1.ConditionFalse, ReasonReconnectTimeout))
+	assert.Equal(ClusterReconnectedFailed, *status.State)
+
+	SetClusterCondition(status, condition(ClusterAPIServerReachable, metav1.ConditionTrue, ReasonConnected))
+	SetClusterCondition(status, condition(ClusterWardenDeployed, metav1.ConditionFalse, ReasonDeployFailed))
+	assert.Equal(ClusterInitFailed, *status.State)
+
+	SetClusterCondition(status, condition(ClusterWardenDeployed, metav1.ConditionTrue, ReasonDeployed))
+	assert.Equal(ClusterProcessing, *status.State)
+
+	SetClusterCondition(status, condition(ClusterHeartbeatHealthy, metav1.ConditionTrue, ReasonHeartbeatReceived))
+	assert.Equal(ClusterNormal, *status.State)
+
+	// informational conditions do not affect state
+	SetClusterCondition(status, condition(ClusterMetricsAvailable, metav1.ConditionFalse, ReasonMetricsUnavailable))
+	assert.Equal(ClusterNormal, *status.State)
+
+	SetClusterCondition(status, condition(ClusterHeartbeatHealthy, metav1.ConditionFalse, ReasonHeartbeatTimeout))
+	assert.Equal(ClusterAbnormal, *status.State)
+	assert.Len(status.Conditions, 4)
+
+	// deleting is terminal
+	deleting := ClusterDeleting
+	status.State = &deleting
+	SetClusterCondition(status, condition(ClusterHeartbeatHealthy, metav1.ConditionTrue, ReasonHeartbeatReceived))
+	assert.Equal(ClusterDeleting, *status.State)
+}
+
+func TestIsKubeConfigChanged(t *testing.T) {
+	assert := assert.New(t)
+
+	ref := &corev1.SecretReference{Name: "cluster-kubeconfig-member-1", Namespace: "kubecube-system"}
+
+	old := &Cluster{Spec: ClusterSpec{KubeConfig: []byte("old")}}
+	new := &Cluster{Spec: ClusterSpec{KubeConfig: []byte("new")}}
+	assert.True(IsKubeConfigChanged(old, new))
+
+	// inline kubeconfig moved into secret
+	new = &Cluster{Spec: ClusterSpec{KubeConfigSecretRef: ref}}
+	assert.False(IsKubeConfigChanged(old, new))
+
+	// refer to another secret
+	old = new.DeepCopy()
+	new = &Cluster{Spec: ClusterSpec{KubeConfigSecretRef: &corev1.SecretReference{Name: "other", Namespace: "kubecube-system"}}}
+	assert.True(IsKubeConfigChanged(old, new))
+	assert.False(IsKubeConfigChanged(old, old.DeepCopy()))
+}
+
+func TestEffectiveDeletionPolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	policiesOf := func(effective []CategoryDeletionPolicy) map[ResourceCategory]DeletionPolicy {
+		m := make(map[ResourceCategory]DeletionPolicy)
+		for _, p := range effective {
+			m[p.Category] = p.Policy
+		}
+		return m
+	}
+
+	// retain all by default
+	effective := EffectiveDeletionPolicies(nil, true)
+	assert.Len(effective, len(ResourceCategories))
+	for _, p := range effective {
+		assert.Equal(DeletionRetain, p.Policy)
+	}
+
+	// purge warden and webhooks by default
+	m := policiesOf(EffectiveDeletionPolicies(nil, false))
+	assert.Equal(DeletionPurge, m[ResourceCategoryWarden])
+	assert.Equal(DeletionPurge, m[ResourceCategoryWebhooks])
+	assert.Equal(DeletionRetain, m[ResourceCategoryCRDs])
+
+	// webhooks are purged with warden
+	m = policiesOf(EffectiveDeletionPolicies([]CategoryDeletionPolicy{
+		{Category: ResourceCategoryWarden, Policy: DeletionPurge},
+		{Category: ResourceCategoryWebhooks, Policy: DeletionRetain},
+		{Category: ResourceCategoryCRDs, Policy: DeletionPurge},
+	}, true))
+	assert.Equal(DeletionPurge, m[ResourceCategoryWebhooks])
+	assert.Equal(DeletionPurge, m[ResourceCategoryCRDs])
+	assert.Equal(DeletionRetain, m[ResourceCategoryHotplugReleases])
+}
@@ -38,6 +38,12 @@ type DeployResult struct {
 // HotplugSpec defines the desired state of Hotplug
 type HotplugSpec struct {
 	Component []ComponentConfig `json:"component,omitempty"`
+	// ClusterSelector selects clusters by labels, the components of selected
+	// clusters are overridden by this hotplug on top of common hotplug and under
+	// the hotplug named after cluster. Hotplugs with selector are applied in
+	// order of name.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
 }
 
 // HotplugStatus defines the observed state of Hotplug
@@ -33,13 +33,40 @@ type ProjectSpec struct {
 	Namespace string `json:"namespace,omitempty"`
 
 	IngressDomainSuffix []string `json:"ingressDomainSuffix,omitempty"`
+
+	// Template is the name of ProjectTemplate applied to namespaces of project
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// NetworkExceptions are the peers out of tenant allowed to access
+	// namespaces of project when network isolation of tenant enabled
+	// +optional
+	NetworkExceptions []NetworkPeer `json:"networkExceptions,omitempty"`
+}
+
+// NetworkPeer is a peer allowed to access namespaces of project, exactly one
+// of the fields should be set
+type NetworkPeer struct {
+	// Tenant allows traffic from all namespaces of tenant
+	// +optional
+	Tenant string `json:"tenant,omitempty"`
+
+	// Project allows traffic from all namespaces of project
+	// +optional
+	Project string `json:"project,omitempty"`
+
+	// CIDR allows traffic from the ip block, such as clients out of cluster
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
 }
 
 // ProjectStatus defines the observed state of Project
 type ProjectStatus struct {
+	CommonStatus `json:",inline"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 
 // Project is the Schema for the projects API
 // +kubebuilder:resource:categories="kubecube",scope="Cluster"
@@ -47,6 +74,8 @@ type ProjectStatus struct {
 // +kubebuilder:printcolumn:name="DisplayName",type=string,JSONPath=`.spec.displayName`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 // +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".spec.namespace"
+// +kubebuilder:printcolumn:name="Template",type="string",JSONPath=".spec.template",priority=1
+// +kubebuilder:printcolumn:name="Members",type="integer",JSONPath=".status.memberCount",priority=1
 type Project struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
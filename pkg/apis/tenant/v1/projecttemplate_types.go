@@ -0,0 +1,106 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProjectTemplateSpec declares the resources bootstrapped into namespaces of
+// projects which reference the template
+type ProjectTemplateSpec struct {
+	// +kubebuilder:validation:MaxLength=200
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// LimitRanges are created in every namespace of project
+	// +optional
+	LimitRanges []TemplateLimitRange `json:"limitRanges,omitempty"`
+
+	// NetworkPolicies are created in every namespace of project
+	// +optional
+	NetworkPolicies []TemplateNetworkPolicy `json:"networkPolicies,omitempty"`
+
+	// RoleBindings are created in namespace of project and propagated to
+	// the namespaces under project by HNC
+	// +optional
+	RoleBindings []TemplateRoleBinding `json:"roleBindings,omitempty"`
+
+	// ResourceQuota is created in namespace of project which has no resource
+	// quota, the resource quota created by KubeCube takes precedence over it
+	// +optional
+	ResourceQuota *TemplateResourceQuota `json:"resourceQuota,omitempty"`
+
+	// ConfigMaps are created in every namespace of project
+	// +optional
+	ConfigMaps []TemplateConfigMap `json:"configMaps,omitempty"`
+}
+
+type TemplateLimitRange struct {
+	Name string                `json:"name"`
+	Spec corev1.LimitRangeSpec `json:"spec"`
+}
+
+type TemplateNetworkPolicy struct {
+	Name string                         `json:"name"`
+	Spec networkingv1.NetworkPolicySpec `json:"spec"`
+}
+
+type TemplateRoleBinding struct {
+	Name     string           `json:"name"`
+	RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+	Subjects []rbacv1.Subject `json:"subjects,omitempty"`
+}
+
+type TemplateResourceQuota struct {
+	Name string                   `json:"name"`
+	Spec corev1.ResourceQuotaSpec `json:"spec"`
+}
+
+type TemplateConfigMap struct {
+	Name string            `json:"name"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProjectTemplate is the Schema for the projecttemplates API
+// +kubebuilder:resource:categories="kubecube",scope="Cluster"
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+type ProjectTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProjectTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProjectTemplateList contains a list of ProjectTemplate
+type ProjectTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProjectTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProjectTemplate{}, &ProjectTemplateList{})
+}
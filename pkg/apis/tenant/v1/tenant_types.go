@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,19 +32,155 @@ type TenantSpec struct {
 	Description string `json:"description,omitempty"`
 
 	Namespace string `json:"namespace,omitempty"`
+
+	// State is the lifecycle state of tenant, Active by default. Workloads
+	// of Suspended tenant are scaled to zero and writes are blocked. Resources
+	// of Archived tenant are exported to a bundle and deleted, the bundle is
+	// re-applied when tenant turns back to Active or Suspended.
+	// +kubebuilder:validation:Enum=Active;Suspended;Archived
+	// +optional
+	State TenantState `json:"state,omitempty"`
+
+	// NetworkIsolation enables default-deny network isolation of tenant, pods
+	// in namespaces of tenant only accept traffic from namespaces of the same
+	// tenant and system namespaces, which are kube-system and the namespace of
+	// KubeCube. Exceptions are declared by projects of tenant.
+	// +optional
+	NetworkIsolation bool `json:"networkIsolation,omitempty"`
+}
+
+// TenantState is the lifecycle state of tenant
+type TenantState string
+
+const (
+	TenantActive    TenantState = "Active"
+	TenantSuspended TenantState = "Suspended"
+	TenantArchived  TenantState = "Archived"
+)
+
+// condition types of tenant and project
+const (
+	// NamespaceReady means the namespace of tenant or project is active in all
+	// clusters reported
+	NamespaceReady = "NamespaceReady"
+
+	// QuotaBound means a CubeResourceQuota is bound to tenant or project
+	QuotaBound = "QuotaBound"
+
+	// SyncedToClusters means tenant or project is reported by warden of all clusters
+	SyncedToClusters = "SyncedToClusters"
+
+	// Reparented means the namespace of project is under the namespace of
+	// the tenant it belongs to in all clusters reported, it turns false when
+	// project moved to other tenant until its namespaces follow
+	Reparented = "Reparented"
+)
+
+// condition reasons of tenant and project
+const (
+	ReasonNamespaceActive   = "NamespaceActive"
+	ReasonNamespaceNotReady = "NamespaceNotReady"
+	ReasonQuotaFound        = "QuotaFound"
+	ReasonQuotaNotFound     = "QuotaNotFound"
+	ReasonSynced            = "Synced"
+	ReasonNotSynced         = "NotSynced"
+	ReasonParentMatched     = "ParentMatched"
+	ReasonParentMismatched  = "ParentMismatched"
+)
+
+// ClusterSummary is the state of tenant or project in a cluster, which is
+// reported by warden of the cluster
+type ClusterSummary struct {
+	// Name of cluster
+	Name string `json:"name"`
+
+	// NamespaceReady indicates if the namespace of tenant or project is active
+	NamespaceReady bool `json:"namespaceReady"`
+
+	// NamespaceCount is the number of namespaces belong to tenant or project
+	// in cluster, namespaces of tenant and projects themselves are not counted
+	NamespaceCount int32 `json:"namespaceCount"`
+
+	// State is the lifecycle state of tenant applied in cluster, the state
+	// of project follows the tenant it belongs to
+	// +optional
+	State TenantState `json:"state,omitempty"`
+
+	// Parent is the parent namespace of the namespace of tenant or project
+	// in hierarchy of HNC
+	// +optional
+	Parent string `json:"parent,omitempty"`
+}
+
+// QuotaSummary is the quota of tenant or project rolled up from CubeResourceQuota
+type QuotaSummary struct {
+	// Name of CubeResourceQuota
+	Name string `json:"name"`
+
+	// Hard is the enforced hard limits of quota
+	// +optional
+	Hard corev1.ResourceList `json:"hard,omitempty"`
+
+	// Used is the observed usage of quota
+	// +optional
+	Used corev1.ResourceList `json:"used,omitempty"`
+}
+
+// CommonStatus is the observed state shared by tenant and project
+type CommonStatus struct {
+	// Conditions of tenant or project
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Clusters where tenant or project exists
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clusters []ClusterSummary `json:"clusters,omitempty"`
+
+	// NamespaceCount is the number of namespaces in all clusters
+	// +optional
+	NamespaceCount int32 `json:"namespaceCount,omitempty"`
+
+	// MemberCount is the number of users bound to tenant or project
+	// +optional
+	MemberCount int32 `json:"memberCount,omitempty"`
+
+	// Quota is the quota bound to tenant or project
+	// +optional
+	Quota *QuotaSummary `json:"quota,omitempty"`
 }
 
 // TenantStatus defines the observed state of Tenant
 type TenantStatus struct {
+	CommonStatus `json:",inline"`
+
+	// ProjectCount is the number of projects in tenant
+	// +optional
+	ProjectCount int32 `json:"projectCount,omitempty"`
+
+	// State is the lifecycle state applied in all clusters, it is empty
+	// while the state is transiting in any cluster
+	// +optional
+	State TenantState `json:"state,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 
 // Tenant is the Schema for the tenants API
 // +kubebuilder:resource:categories="kubecube",scope="Cluster"
 // +kubebuilder:printcolumn:name="DisplayName",type=string,JSONPath=`.spec.displayName`
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".spec.namespace"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Isolated",type="boolean",JSONPath=".spec.networkIsolation",priority=1
+// +kubebuilder:printcolumn:name="Projects",type="integer",JSONPath=".status.projectCount",priority=1
+// +kubebuilder:printcolumn:name="Members",type="integer",JSONPath=".status.memberCount",priority=1
 type Tenant struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
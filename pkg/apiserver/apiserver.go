@@ -28,12 +28,18 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/cluster"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/healthz"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/key"
+	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/project"
 	resourcemanage "github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/resourcemanage/handle"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/scout"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/user"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/yamldeploy"
 	"github.com/kubecube-io/kubecube/pkg/apiserver/middlewares"
+	"github.com/kubecube-io/kubecube/pkg/apiserver/middlewares/metrics"
+	"github.com/kubecube-io/kubecube/pkg/apiserver/middlewares/precheck"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	cubemetrics "github.com/kubecube-io/kubecube/pkg/metrics"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/tunnel"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	_ "github.com/kubecube-io/kubecube/pkg/utils/errcode"
 
@@ -58,6 +64,9 @@ type APIServer struct {
 func registerCubeAPI(cfg *Config) http.Handler {
 	router := gin.New()
 
+	// requests of all apis are recorded into metrics
+	router.Use(metrics.Metrics())
+
 	// register apis do not need middlewares
 	apisOutsideMiddlewares(router)
 
@@ -70,6 +79,9 @@ func registerCubeAPI(cfg *Config) http.Handler {
 	// authZ apis handler
 	authorization.NewHandler().AddApisTo(router)
 
+	// projects apis handler
+	project.NewHandler().AddApisTo(router)
+
 	router.POST(constants.ApiPathRoot+"/login", user.Login)
 	router.GET(constants.ApiPathRoot+"/oauth/redirect", user.GitHubLogin)
 
@@ -94,13 +106,14 @@ func registerCubeAPI(cfg *Config) http.Handler {
 		keyManage.GET("", key.ListKey)
 	}
 
-	k8sApiProxy := router.Group(constants.ApiPathRoot + "/proxy")
+	// mutating requests to cluster under maintenance are rejected
+	k8sApiProxy := router.Group(constants.ApiPathRoot+"/proxy", precheck.Maintenance())
 	{
-		proxyHandler := resourcemanage.NewProxyHandler(cfg.EnableVersionConversion)
+		proxyHandler := resourcemanage.NewProxyHandler()
 		k8sApiProxy.Any("/clusters/:cluster/*url", proxyHandler.ProxyHandle)
 	}
 
-	k8sApiExtend := router.Group(constants.ApiPathRoot + "/extend")
+	k8sApiExtend := router.Group(constants.ApiPathRoot+"/extend", precheck.Maintenance())
 	{
 		extendHandler := resourcemanage.NewExtendHandler(cfg.NginxNamespace, cfg.NginxTcpServiceConfigMap, cfg.NginxUdpServiceConfigMap)
 		k8sApiExtend.GET("/feature-config", resourcemanage.GetFeatureConfig)
@@ -109,6 +122,7 @@ func registerCubeAPI(cfg *Config) http.Handler {
 		k8sApiExtend.Any("/clusters/:cluster/namespaces/:namespace/:resourceType", extendHandler.ExtendHandle)
 		k8sApiExtend.GET("/clusters/:cluster/namespaces/:namespace/logs/:resourceName", resourcemanage.GetPodContainerLog)
 		k8sApiExtend.POST("/clusters/:cluster/yaml/deploy", yamldeploy.Deploy)
+		k8sApiExtend.POST("/yaml/deploy", yamldeploy.DeployToClusters)
 		k8sApiExtend.GET("/ingressDomainSuffix", resourcemanage.IngressDomainSuffix)
 	}
 
@@ -141,6 +155,7 @@ func withSimpleServer(s *APIServer) *APIServer {
 	url := ginSwagger.URL("/swagger/doc.json")
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 	router.GET("/healthz", healthz.HealthyCheck)
+	router.GET(cubemetrics.Path, gin.WrapH(cubemetrics.Handler()))
 
 	s.SimpleServer = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", s.Config.BindAddr, s.Config.GenericPort),
@@ -153,6 +168,9 @@ func withSimpleServer(s *APIServer) *APIServer {
 func apisOutsideMiddlewares(root *gin.Engine) {
 	scout.AddApisTo(root)
 
+	// tunnels opened by warden of clusters in tunnel mode
+	root.GET(tunnel.Path, gin.WrapH(multicluster.TunnelServer))
+
 	root.GET(constants.ApiPathRoot+"/extend/configmap/:configmap", resourcemanage.GetConfigMap)
 }
 
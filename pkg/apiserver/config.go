@@ -28,7 +28,8 @@ type Config struct {
 	authentication.GenericConfig
 	Gi18nManagers *international.Gi18nManagers
 
-	// EnableVersionConversion means api-server open version conversion
+	// EnableVersionConversion means api-server open version conversion.
+	// Deprecated: use feature gate VersionConversion instead
 	EnableVersionConversion bool
 
 	//this if for ingress nginx controller
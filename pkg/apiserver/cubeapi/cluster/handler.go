@@ -28,10 +28,14 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/wait"
 	userinfo "k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
 
@@ -40,12 +44,17 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/clients"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/catalog"
 	mgrclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/deletion"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/inventory"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/preflight"
 	"github.com/kubecube-io/kubecube/pkg/quota"
 	"github.com/kubecube-io/kubecube/pkg/utils/access"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/errcode"
 	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
+	"github.com/kubecube-io/kubecube/pkg/utils/page"
 	"github.com/kubecube-io/kubecube/pkg/utils/response"
 )
 
@@ -56,12 +65,21 @@ func (h *handler) AddApisTo(root *gin.Engine) {
 	r.GET("info", h.getClusterInfo)
 	r.GET("/:cluster/monitor", h.getClusterMonitorInfo)
 	r.GET("/:cluster/livedata", h.getClusterLivedata)
+	r.GET("inventory", h.getClusterInventory)
+	r.GET("/:cluster/apiresources", h.getClusterAPIResources)
+	r.GET("apiresources/diff", h.diffClusterAPIResources)
 	r.GET("namespaces", h.getClusterNames)
 	r.GET("resources", h.getClusterResource)
 	r.GET("subnamespaces", h.getSubNamespaces)
 	r.POST("register", h.registerCluster)
 	r.POST("add", h.addCluster)
+	r.POST("preflight", h.preflightCluster)
 	r.POST("nsquota", h.createNsAndQuota)
+	r.PUT("/:cluster/kubeconfig", h.updateKubeConfig)
+	r.PUT("/:cluster/labels", h.updateClusterLabels)
+	r.PUT("/:cluster/maintenance", h.updateMaintenance)
+	r.GET("/:cluster/deletionplan", h.getDeletionPlan)
+	r.PUT("/:cluster/deletionpolicies", h.updateDeletionPolicies)
 }
 
 type result struct {
@@ -69,6 +87,17 @@ type result struct {
 	Items []clusterInfo `json:"items"`
 }
 
+type inventoryResult struct {
+	Total int                          `json:"total"`
+	Items []inventory.ClusterInventory `json:"items"`
+}
+
+type apiDiffResult struct {
+	Clusters []string          `json:"clusters"`
+	Total    int               `json:"total"`
+	Items    []catalog.APIDiff `json:"items"`
+}
+
 // clusterInfo contains meta info and livedata info
 type clusterInfo struct {
 	clusterMetaInfo
@@ -76,18 +105,25 @@ type clusterInfo struct {
 }
 
 type clusterMetaInfo struct {
-	ClusterName         string            `json:"clusterName"`
-	ClusterDescription  string            `json:"clusterDescription"`
-	NetworkType         string            `json:"networkType"`
-	HarborAddr          string            `json:"harborAddr"`
-	IsMemberCluster     bool              `json:"isMemberCluster"`
-	IsWritable          bool              `json:"isWritable"`
-	CreateTime          time.Time         `json:"createTime"`
-	KubeApiServer       string            `json:"kubeApiServer"`
-	Status              string            `json:"status"`
-	IngressDomainSuffix string            `json:"ingressDomainSuffix,omitempty"`
-	Labels              map[string]string `json:"labels,omitempty"`
-	Annotations         map[string]string `json:"annotations,omitempty"`
+	ClusterName         string                      `json:"clusterName"`
+	ClusterDescription  string                      `json:"clusterDescription"`
+	NetworkType         string                      `json:"networkType"`
+	HarborAddr          string                      `json:"harborAddr"`
+	IsMemberCluster     bool                        `json:"isMemberCluster"`
+	IsWritable          bool                        `json:"isWritable"`
+	Maintenance         bool                        `json:"maintenance"`
+	CreateTime          time.Time                   `json:"createTime"`
+	KubeApiServer       string                      `json:"kubeApiServer"`
+	Status              string                      `json:"status"`
+	Reason              string                      `json:"reason,omitempty"`
+	KubernetesVersion   string                      `json:"kubernetesVersion,omitempty"`
+	WardenVersion       string                      `json:"wardenVersion,omitempty"`
+	Latency             string                      `json:"latency,omitempty"`
+	Conditions          []metav1.Condition          `json:"conditions,omitempty"`
+	Components          []clusterv1.ComponentStatus `json:"components,omitempty"`
+	IngressDomainSuffix string                      `json:"ingressDomainSuffix,omitempty"`
+	Labels              map[string]string           `json:"labels,omitempty"`
+	Annotations         map[string]string           `json:"annotations,omitempty"`
 }
 
 type clusterLivedataInfo struct {
@@ -128,6 +164,7 @@ func NewHandler() *handler {
 // @Param cluster query string false "cluster info search by cluster name"
 // @Param project query string false "cluster info search by project name"
 // @Param status query string false "cluster info search by cluster status"
+// @Param labelSelector query string false "cluster info search by labels of cluster, such as env=prod,region=east"
 // @Success 200 {object} result "{"total":3,"items":[{"clusterName":"member-1","clusterDescription":"this is member cluster","networkType":"calico","harborAddr":"","isMemberCluster":true,"createTime":"2022-05-06T11:33:15+08:00","kubeApiServer":"https://10.173.33.3:6443","status":"normal","nodeCount":1,"namespaceCount":19,"usedCpu":549,"totalCpu":8000,"usedMem":7276,"totalMem":16648,"totalStorage":0,"usedStorage":0,"totalStorageEphemeral":42208,"usedStorageEphemeral":0,"totalGpu":0,"usedGpu":0,"usedCpuRequest":3300,"usedCpuLimit":4200,"usedMemRequest":3874,"usedMemLimit":7265},{"clusterName":"pivot-cluster","clusterDescription":"There is a pivot cluster dating with KubeCube","networkType":"","harborAddr":"","isMemberCluster":false,"createTime":"2022-04-28T14:41:26+08:00","kubeApiServer":"10.173.33.2:6443","status":"normal","nodeCount":1,"namespaceCount":18,"usedCpu":886,"totalCpu":8000,"usedMem":8996,"totalMem":16648,"totalStorage":0,"usedStorage":0,"totalStorageEphemeral":42208,"usedStorageEphemeral":0,"totalGpu":0,"usedGpu":0,"usedCpuRequest":3000,"usedCpuLimit":3900,"usedMemRequest":3469,"usedMemLimit":6860},{"clusterName":"member-2","clusterDescription":"this is member cluster","networkType":"calico","harborAddr":"","isMemberCluster":true,"createTime":"2022-04-28T16:12:13+08:00","kubeApiServer":"10.173.33.4:6443","status":"normal","nodeCount":1,"namespaceCount":19,"usedCpu":929,"totalCpu":8000,"usedMem":7187,"totalMem":16648,"totalStorage":0,"usedStorage":0,"totalStorageEphemeral":42208,"usedStorageEphemeral":0,"totalGpu":0,"usedGpu":0,"usedCpuRequest":3000,"usedCpuLimit":3900,"usedMemRequest":3469,"usedMemLimit":6860}]}"
 // @Failure 500 {object} errcode.ErrorInfo
 // @Router /api/v1/cube/clusters/info  [get]
@@ -142,8 +179,15 @@ func (h *handler) getClusterInfo(c *gin.Context) {
 	clusterStatus := c.Query("status")
 	projectName := c.Query("project")
 	nodeLabelSelector := c.Query("nodeLabelSelector")
+	clusterLabelSelector := c.Query("labelSelector")
 	pruneInfo := c.Query("prune")
 
+	clusterSelector, err := labels.Parse(clusterLabelSelector)
+	if err != nil {
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "labels selector invalid: %v", err))
+		return
+	}
+
 	switch {
 	// find cluster by given name
 	case len(clusterName) > 0:
@@ -177,6 +221,8 @@ func (h *handler) getClusterInfo(c *gin.Context) {
 		clusterList = clusters
 	}
 
+	clusterList.Items = filterClustersBySelector(clusterList.Items, clusterSelector)
+
 	selector, err := labels.Parse(nodeLabelSelector)
 	if err != nil {
 		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "labels selector invalid: %v", err))
@@ -273,36 +319,187 @@ func (h *handler) getClusterLivedata(c *gin.Context) {
 	response.SuccessReturn(c, info)
 }
 
+// getClusterInventory returns the inventory of clusters refreshed in background
+// @Summary Show inventory of clusters
+// @Description get paginated inventory of clusters, including nodes, capacity, allocatable, allocated resources, namespaces and workloads per tenant and project, the inventory is refreshed periodically rather than at request time
+// @Tags cluster
+// @Param cluster query string false "inventory search by cluster names, such as member-1,member-2"
+// @Param labelSelector query string false "inventory search by labels of cluster, such as env=prod,region=east"
+// @Param pageSize query int false "page size"
+// @Param pageNum query int false "page num"
+// @Success 200 {object} inventoryResult
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/inventory  [get]
+func (h *handler) getClusterInventory(c *gin.Context) {
+	selector, err := labels.Parse(c.Query("labelSelector"))
+	if err != nil {
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "labels selector invalid: %v", err))
+		return
+	}
+
+	items := inventory.Interface().List()
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Cluster)
+	}
+
+	if clusterNames := c.Query("cluster"); len(clusterNames) > 0 {
+		names = sets.NewString(names...).Intersection(sets.NewString(strings.Split(clusterNames, ",")...)).List()
+	}
+
+	names, err = filterClusterNamesBySelector(c.Request.Context(), names, selector)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.InternalServerError)
+		return
+	}
+
+	matched := sets.NewString(names...)
+	filtered := make([]inventory.ClusterInventory, 0, len(names))
+	for _, item := range items {
+		if matched.Has(item.Cluster) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	res := inventoryResult{Total: len(filtered), Items: []inventory.ClusterInventory{}}
+
+	limit, offset := page.ParsePage(c.Query("pageSize"), c.Query("pageNum"))
+	if offset < len(filtered) {
+		end := offset + limit
+		if limit <= 0 || end > len(filtered) {
+			end = len(filtered)
+		}
+		res.Items = filtered[offset:end]
+	}
+
+	response.SuccessReturn(c, res)
+}
+
+// getClusterAPIResources returns the api groups and resources served by cluster
+// @Summary Show api resources of cluster
+// @Description get api groups and resources served by cluster, including crds, with their scope and verbs, the discovery is cached and refreshed when crds of cluster changed
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Param group query string false "resources search by api group, such as apps"
+// @Success 200 {object} catalog.ClusterAPIs
+// @Failure 404 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/apiresources  [get]
+func (h *handler) getClusterAPIResources(c *gin.Context) {
+	cluster := c.Param("cluster")
+
+	apis, ok := catalog.Interface().Get(cluster)
+	if !ok {
+		// discover at once if cluster is not in catalog yet
+		catalog.Interface().RefreshCluster(c.Request.Context(), cluster)
+		apis, ok = catalog.Interface().Get(cluster)
+		if !ok {
+			response.FailReturn(c, errcode.ClusterNotFoundError(cluster))
+			return
+		}
+	}
+
+	if group := c.Query("group"); len(group) > 0 {
+		resources := make([]catalog.APIResource, 0)
+		for _, r := range apis.Resources {
+			if r.Group == group {
+				resources = append(resources, r)
+			}
+		}
+		apis.Resources = resources
+	}
+
+	response.SuccessReturn(c, apis)
+}
+
+// diffClusterAPIResources returns the api resources which differ between clusters
+// @Summary Diff api resources of clusters
+// @Description get the api resources which are missing in some clusters or served with different scope and verbs, resources served the same way by all clusters are omitted
+// @Tags cluster
+// @Param cluster query string false "clusters to compare, such as member-1,member-2, all clusters are compared if empty"
+// @Success 200 {object} apiDiffResult
+// @Router /api/v1/cube/clusters/apiresources/diff  [get]
+func (h *handler) diffClusterAPIResources(c *gin.Context) {
+	var clusters []string
+	if clusterNames := c.Query("cluster"); len(clusterNames) > 0 {
+		clusters = strings.Split(clusterNames, ",")
+	}
+
+	items := catalog.Filter(catalog.Interface().List(), clusters)
+
+	res := apiDiffResult{Clusters: []string{}, Items: catalog.Diff(items)}
+	for _, item := range items {
+		res.Clusters = append(res.Clusters, item.Cluster)
+	}
+	res.Total = len(res.Items)
+
+	response.SuccessReturn(c, res)
+}
+
 // getClusterNames get cluster name where the namespace work in
 // @Summary Show all clusters bind to namespace
 // @Description get cluster name where the namespace work in
 // @Tags cluster
 // @Param namespace query string false "clusters search by namespace"
-// @Success 200 {object} map[string]interface{} "{"items":["member-2","member-1","pivot-cluster"],"total":3}"
+// @Param labelSelector query string false "clusters search by labels of cluster, such as env=prod,region=east"
+// @Success 200 {object} map[string]interface{} "{"items":["member-2","member-1","pivot-cluster"],"total":3,"unavailable":["member-3"]}"
 // @Failure 500 {object} errcode.ErrorInfo
 // @Router /api/v1/cube/clusters/namespaces  [get]
 func (h *handler) getClusterNames(c *gin.Context) {
 	var (
-		namespace    = c.Query("namespace")
-		ctx          = c.Request.Context()
-		clusterNames []string
+		namespace     = c.Query("namespace")
+		labelSelector = c.Query("labelSelector")
+		ctx           = c.Request.Context()
+		clusterNames  []string
+		unavailable   []string
 	)
 
-	if len(namespace) > 0 {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "labels selector invalid: %v", err))
+		return
+	}
+
+	switch {
+	case len(namespace) > 0:
 		clusters, err := getClustersByNamespace(namespace, ctx)
 		if err != nil {
 			response.FailReturn(c, errcode.InternalServerError)
 			return
 		}
 		clusterNames = clusters
-	} else {
+	case !selector.Empty():
+		clusters, err := multicluster.Interface().ListClustersNameBySelector(ctx, selector)
+		matched, ok := multicluster.UnavailableClustersOf(err)
+		if err != nil && !ok {
+			clog.Error(err.Error())
+			response.FailReturn(c, errcode.InternalServerError)
+			return
+		}
+		clusterNames, unavailable = clusters, matched
+	default:
 		clusterNames = listClusterNames()
 	}
 
+	if len(namespace) > 0 && !selector.Empty() {
+		clusterNames, err = filterClusterNamesBySelector(ctx, clusterNames, selector)
+		if err != nil {
+			clog.Error(err.Error())
+			response.FailReturn(c, errcode.InternalServerError)
+			return
+		}
+	}
+
 	res := map[string]interface{}{
 		"total": len(clusterNames),
 		"items": clusterNames,
 	}
+	// clusters matched by selector but not available are told apart from no match
+	if len(unavailable) > 0 {
+		res["unavailable"] = unavailable
+	}
 
 	response.SuccessReturn(c, res)
 }
@@ -499,6 +696,8 @@ type scriptData struct {
 	NetworkType string `json:"networkType,omitempty"`
 	Description string `json:"description,omitempty"`
 	HarborAddr  string `json:"harborAddr,omitempty"`
+	// Labels groups cluster, such as env=prod,region=east
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // addCluster return script which need be execute in member cluster node
@@ -552,10 +751,10 @@ func (h *handler) addCluster(c *gin.Context) {
 
 	cluster := &clusterv1.Cluster{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: d.ClusterName,
+			Name:   d.ClusterName,
+			Labels: d.Labels,
 		},
 		Spec: clusterv1.ClusterSpec{
-			KubeConfig:            kubeConfig,
 			KubernetesAPIEndpoint: config.Host,
 			IsMemberCluster:       true,
 			Description:           d.Description,
@@ -576,7 +775,29 @@ func (h *handler) addCluster(c *gin.Context) {
 		return
 	}
 
-	err = h.Direct().Create(c.Request.Context(), cluster)
+	ctx := c.Request.Context()
+
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: cluster.Name}, &clusterv1.Cluster{})
+	if err == nil {
+		clog.Warn("cluster %v already exist", cluster.Name)
+		response.SuccessJsonReturn(c, "success")
+		return
+	}
+	if !errors.IsNotFound(err) {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	// store kubeconfig in secret rather than cluster cr
+	cluster.Spec.KubeConfigSecretRef, err = kubeconfig.EnsureSecretForCluster(ctx, h.Direct(), cluster.Name, kubeConfig)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	err = h.Direct().Create(ctx, cluster)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			clog.Warn(err.Error())
@@ -590,6 +811,58 @@ func (h *handler) addCluster(c *gin.Context) {
 	response.SuccessJsonReturn(c, "success")
 }
 
+// preflightData is the data to run pre-flight checks
+type preflightData struct {
+	// KubeConfig is base64 encoded kubeconfig of cluster to import
+	KubeConfig string `json:"kubeConfig"`
+
+	// ConnectionMode is how KubeCube will connect to cluster, checks
+	// against kube-apiserver are skipped for tunnel
+	ConnectionMode clusterv1.ConnectionMode `json:"connectionMode,omitempty"`
+}
+
+// preflightCluster checks if cluster can be imported before adding it
+// @Summary Pre-flight checks of cluster
+// @Description check version, required apis, rbac and network of cluster before importing, nothing will be created
+// @Tags cluster
+// @Param preflightData body preflightData true "kubeconfig of cluster"
+// @Success 200 {object} preflight.Report
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/preflight  [post]
+func (h *handler) preflightCluster(c *gin.Context) {
+	d := preflightData{}
+	err := c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// only who can add cluster is allowed to run pre-flight
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.CreateVerb, &clusterv1.Cluster{}); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	kubeConfig, err := base64.StdEncoding.DecodeString(d.KubeConfig)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "kubeConfig invalid: %v", err))
+		return
+	}
+
+	report, err := preflight.Run(c.Request.Context(), kubeConfig, d.ConnectionMode)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "kubeConfig invalid: %v", err))
+		return
+	}
+
+	response.SuccessReturn(c, report)
+}
+
 // registerCluster is a callback api for add cluster to pivot cluster
 func (h *handler) registerCluster(c *gin.Context) {
 	cluster := &clusterv1.Cluster{}
@@ -751,3 +1024,361 @@ func (h *handler) createNsAndQuota(c *gin.Context) {
 
 	response.SuccessJsonReturn(c, "success")
 }
+
+// kubeConfigData is the new kubeconfig of cluster
+type kubeConfigData struct {
+	// KubeConfig is base64 encoded kubeconfig
+	KubeConfig string `json:"kubeConfig"`
+}
+
+// updateKubeConfig rotates the credential of cluster without re-import
+// @Summary Update kubeconfig of cluster
+// @Description rotate the kubeconfig of cluster, the connection with cluster will be rebuilt in place
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Param kubeConfigData body kubeConfigData true "new kubeconfig of cluster"
+// @Success 200 {string} string "success"
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/kubeconfig  [put]
+func (h *handler) updateKubeConfig(c *gin.Context) {
+	clusterName := c.Param("cluster")
+
+	// check access before touching the kubeconfig given, which may point to anywhere
+	ctx := c.Request.Context()
+	cluster := &clusterv1.Cluster{}
+	err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "cluster %v not found", clusterName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, cluster); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	d := kubeConfigData{}
+	err = c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	kubeConfig, err := base64.StdEncoding.DecodeString(d.KubeConfig)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "kubeConfig invalid: %v", err))
+		return
+	}
+
+	config, err := kubeconfig.LoadKubeConfigFromBytes(kubeConfig)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "kubeConfig invalid: %v", err))
+		return
+	}
+
+	// ensure the new kubeconfig is usable before rotation
+	_, err = client.New(config, client.Options{})
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "connect to cluster %v failed: %v", clusterName, err))
+		return
+	}
+
+	// the cluster controller will rebuild connection once kubeconfig changed
+	ref := cluster.Spec.KubeConfigSecretRef
+	if ref != nil {
+		err = kubeconfig.UpdateKubeConfigSecret(ctx, h.Direct(), ref, kubeConfig)
+		if err != nil {
+			clog.Error(err.Error())
+			response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+			return
+		}
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newCluster := &clusterv1.Cluster{}
+		err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, newCluster)
+		if err != nil {
+			return err
+		}
+		// inline kubeconfig will be moved into secret by cluster controller
+		if ref == nil {
+			newCluster.Spec.KubeConfig = kubeConfig
+		}
+		newCluster.Spec.KubernetesAPIEndpoint = config.Host
+		return h.Direct().Update(ctx, newCluster)
+	})
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	response.SuccessJsonReturn(c, "success")
+}
+
+// clusterLabels is the labels used to group clusters
+type clusterLabels struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// updateClusterLabels updates labels of cluster which group clusters
+// @Summary Update labels of cluster
+// @Description replace labels of cluster used by cluster selector, labels reserved by KubeCube are kept
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Param clusterLabels body clusterLabels true "labels of cluster"
+// @Success 200 {string} string "success"
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/labels  [put]
+func (h *handler) updateClusterLabels(c *gin.Context) {
+	clusterName := c.Param("cluster")
+
+	d := clusterLabels{}
+	err := c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	for k, v := range d.Labels {
+		if isReservedLabel(k) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, "label %v is reserved by KubeCube", k))
+			return
+		}
+		if errs := validation.ValidateLabels(map[string]string{k: v}, field.NewPath("labels")); len(errs) > 0 {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, errs.ToAggregate().Error()))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	cluster := &clusterv1.Cluster{}
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "cluster %v not found", clusterName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, cluster); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newCluster := &clusterv1.Cluster{}
+		err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, newCluster)
+		if err != nil {
+			return err
+		}
+		newCluster.Labels = mergeClusterLabels(newCluster.Labels, d.Labels)
+		return h.Direct().Update(ctx, newCluster)
+	})
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	response.SuccessJsonReturn(c, "success")
+}
+
+type clusterMaintenance struct {
+	Maintenance bool `json:"maintenance"`
+}
+
+// updateMaintenance turns on or off maintenance mode of cluster
+// @Summary Update maintenance of cluster
+// @Description mutating requests, resources sync and hotplug changes of cluster are paused under maintenance
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Param clusterMaintenance body clusterMaintenance true "maintenance of cluster"
+// @Success 200 {string} string "success"
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/maintenance  [put]
+func (h *handler) updateMaintenance(c *gin.Context) {
+	clusterName := c.Param("cluster")
+
+	d := clusterMaintenance{}
+	err := c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cluster := &clusterv1.Cluster{}
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "cluster %v not found", clusterName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, cluster); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newCluster := &clusterv1.Cluster{}
+		err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, newCluster)
+		if err != nil {
+			return err
+		}
+		if newCluster.Spec.Maintenance == d.Maintenance {
+			return nil
+		}
+		newCluster.Spec.Maintenance = d.Maintenance
+		return h.Direct().Update(ctx, newCluster)
+	})
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	clog.Info("maintenance of cluster %v set to %v", clusterName, d.Maintenance)
+
+	response.SuccessJsonReturn(c, "success")
+}
+
+// getDeletionPlan lists resources created by KubeCube in cluster and what to do with them when cluster deleted
+// @Summary Get deletion plan of cluster
+// @Description dry-run of cluster deletion, list resources in member cluster by category with retain or purge policy
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Success 200 {object} deletion.Plan
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/deletionplan  [get]
+func (h *handler) getDeletionPlan(c *gin.Context) {
+	clusterName := c.Param("cluster")
+
+	ctx := c.Request.Context()
+	cluster := &clusterv1.Cluster{}
+	err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "cluster %v not found", clusterName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.DeleteVerb, cluster); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	internalCluster, err := multicluster.Interface().Get(clusterName)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, "cluster %v unhealthy", clusterName))
+		return
+	}
+
+	inventory, err := deletion.Collect(ctx, internalCluster.Client.Direct(), cluster)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	response.SuccessReturn(c, deletion.NewPlan(cluster, inventory))
+}
+
+type deletionPolicies struct {
+	Policies []clusterv1.CategoryDeletionPolicy `json:"policies"`
+}
+
+// updateDeletionPolicies decides to retain or purge each category of resources in member cluster when cluster deleted
+// @Summary Update deletion policies of cluster
+// @Description replace deletion policies of cluster, categories not given follow the default policy
+// @Tags cluster
+// @Param cluster path string true "cluster name"
+// @Param deletionPolicies body deletionPolicies true "deletion policies of cluster"
+// @Success 200 {string} string "success"
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/clusters/{cluster}/deletionpolicies  [put]
+func (h *handler) updateDeletionPolicies(c *gin.Context) {
+	clusterName := c.Param("cluster")
+
+	d := deletionPolicies{}
+	err := c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	if err = validateDeletionPolicies(d.Policies); err != nil {
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cluster := &clusterv1.Cluster{}
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "cluster %v not found", clusterName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, cluster); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newCluster := &clusterv1.Cluster{}
+		err := h.Direct().Get(ctx, types.NamespacedName{Name: clusterName}, newCluster)
+		if err != nil {
+			return err
+		}
+		newCluster.Spec.DeletionPolicies = d.Policies
+		return h.Direct().Update(ctx, newCluster)
+	})
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	response.SuccessJsonReturn(c, "success")
+}
@@ -16,6 +16,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -34,11 +35,15 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
 	mgrclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/inventory"
 	"github.com/kubecube-io/kubecube/pkg/quota"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/strproc"
 )
 
+// reservedLabelDomains are the label domains of cluster used by KubeCube
+var reservedLabelDomains = []string{"kubecube.io/"}
+
 type clusterInfoOpts struct {
 	statusFilter      string
 	nodeLabelSelector labels.Selector
@@ -96,59 +101,6 @@ func makeClusterInfos(ctx context.Context, clusters clusterv1.ClusterList, pivot
 	return infos, nil
 }
 
-func podRequestsAndLimits(pod *corev1.Pod) (reqs, limits corev1.ResourceList) {
-	reqs, limits = corev1.ResourceList{}, corev1.ResourceList{}
-	for _, container := range pod.Spec.Containers {
-		addResourceList(reqs, container.Resources.Requests)
-		addResourceList(limits, container.Resources.Limits)
-	}
-	// init containers define the minimum of any resource
-	for _, container := range pod.Spec.InitContainers {
-		maxResourceList(reqs, container.Resources.Requests)
-		maxResourceList(limits, container.Resources.Limits)
-	}
-
-	// Add overhead for running a pod to the sum of requests and to non-zero limits:
-	if pod.Spec.Overhead != nil {
-		addResourceList(reqs, pod.Spec.Overhead)
-
-		for name, quantity := range pod.Spec.Overhead {
-			if value, ok := limits[name]; ok && !value.IsZero() {
-				value.Add(quantity)
-				limits[name] = value
-			}
-		}
-	}
-	return
-}
-
-// addResourceList adds the resources in newList to list
-func addResourceList(list, new corev1.ResourceList) {
-	for name, quantity := range new {
-		if value, ok := list[name]; !ok {
-			list[name] = quantity.DeepCopy()
-		} else {
-			value.Add(quantity)
-			list[name] = value
-		}
-	}
-}
-
-// maxResourceList sets list to the greater of list/newList for every resource
-// either list
-func maxResourceList(list, new corev1.ResourceList) {
-	for name, quantity := range new {
-		if value, ok := list[name]; !ok {
-			list[name] = quantity.DeepCopy()
-			continue
-		} else {
-			if quantity.Cmp(value) > 0 {
-				list[name] = quantity.DeepCopy()
-			}
-		}
-	}
-}
-
 func makeMonitorInfo(ctx context.Context, cluster string) (*monitorInfo, error) {
 	cli := clients.Interface().Kubernetes(cluster)
 	if cli == nil {
@@ -168,30 +120,31 @@ func makeMonitorInfo(ctx context.Context, cluster string) (*monitorInfo, error)
 		info.UsedStorageEphemeral += (strproc.Str2int(m.Usage.StorageEphemeral().String()) + 1) / 1024
 	}
 
-	nodes := corev1.NodeList{}
-	err = cli.Cache().List(ctx, &nodes)
-	if err != nil {
-		return nil, fmt.Errorf("get cluster %v nodes failed: %v", cluster, err)
+	// nodes and namespaces are read from inventory refreshed in background
+	inv, ok := inventoryOf(cluster)
+	if !ok {
+		return nil, fmt.Errorf("inventory of cluster %v not collected yet", cluster)
 	}
 
-	info.NodeCount = len(nodes.Items)
+	capacity := inv.Capacity
+	info.NodeCount = inv.NodeCount
+	info.NamespaceCount = inv.NamespaceCount
+	info.TotalCPU = strproc.Str2int(capacity.Cpu().String()) * 1000
+	info.TotalMem = strproc.Str2int(capacity.Memory().String()) / 1024
+	info.TotalStorage = (strproc.Str2int(capacity.Storage().String()) + 1) / 1024
+	info.TotalStorageEphemeral = (strproc.Str2int(capacity.StorageEphemeral().String()) + 1) / 1024
 
-	for _, n := range nodes.Items {
-		info.TotalCPU += strproc.Str2int(n.Status.Capacity.Cpu().String()) * 1000
-		info.TotalMem += strproc.Str2int(n.Status.Capacity.Memory().String()) / 1024
-		info.TotalStorage += (strproc.Str2int(n.Status.Capacity.Storage().String()) + 1) / 1024
-		info.TotalStorageEphemeral += (strproc.Str2int(n.Status.Capacity.StorageEphemeral().String()) + 1) / 1024
-	}
+	return info, nil
+}
 
-	ns := corev1.NamespaceList{}
-	err = cli.Cache().List(ctx, &ns)
-	if err != nil {
-		return nil, fmt.Errorf("get cluster %v namespace failed: %v", cluster, err)
+// inventoryOf returns the inventory of cluster, false if it is never
+// collected successfully. The last snapshot is kept when refresh failed.
+func inventoryOf(cluster string) (inventory.ClusterInventory, bool) {
+	inv, ok := inventory.Interface().Get(cluster)
+	if !ok || inv.RefreshTime.IsZero() {
+		return inv, false
 	}
-
-	info.NamespaceCount = len(ns.Items)
-
-	return info, nil
+	return inv, true
 }
 
 // makeMetadataInfo just get metadata of cluster.
@@ -214,10 +167,19 @@ func makeMetadataInfo(ctx context.Context, pivotCli mgrclient.Client, clusterNam
 	// set up cluster meta info
 	info.ClusterName = clusterName
 	info.Status = string(*state)
+	info.Reason = cluster.Status.Reason
+	info.KubernetesVersion = cluster.Status.KubernetesVersion
+	info.WardenVersion = cluster.Status.WardenVersion
+	info.Conditions = cluster.Status.Conditions
+	info.Components = cluster.Status.Components
+	if cluster.Status.Latency != nil {
+		info.Latency = cluster.Status.Latency.Duration.String()
+	}
 	info.ClusterDescription = cluster.Spec.Description
 	info.CreateTime = cluster.CreationTimestamp.Time
 	info.IsMemberCluster = cluster.Spec.IsMemberCluster
 	info.IsWritable = cluster.Spec.IsWritable
+	info.Maintenance = cluster.Spec.Maintenance
 	info.HarborAddr = cluster.Spec.HarborAddr
 	info.KubeApiServer = cluster.Spec.KubernetesAPIEndpoint
 	info.NetworkType = cluster.Spec.NetworkType
@@ -260,6 +222,23 @@ func makeLivedataInfo(ctx context.Context, cli mgrclient.Client, cluster string,
 	// or any errors occurred
 	cancel()
 
+	// read from inventory refreshed in background rather than listing nodes
+	// and pods on each request, the inventory is not grouped by node labels
+	if inv, ok := inventoryOf(cluster); ok && opts.nodeLabelSelector.Empty() {
+		capacity, requests, limits := inv.Capacity, inv.Requests, inv.Limits
+		info.NodeCount = inv.NodeCount
+		info.NamespaceCount = inv.NamespaceCount
+		info.TotalCPU = int(capacity.Cpu().MilliValue())                                           // 1000 m
+		info.TotalMem = convertUnit(capacity.Memory().String(), strproc.Mi)                        // 1024 Mi
+		info.TotalStorage = convertUnit(capacity.Storage().String(), strproc.Mi)                   // 1024 Mi
+		info.TotalStorageEphemeral = convertUnit(capacity.StorageEphemeral().String(), strproc.Mi) // 1024 Mi
+		info.UsedCPURequest = int(requests.Cpu().MilliValue())                                     // 1000 m
+		info.UsedCPULimit = int(limits.Cpu().MilliValue())                                         // 1000 m
+		info.UsedMemRequest = convertUnit(requests.Memory().String(), strproc.Mi)                  // 1024 Mi
+		info.UsedMemLimit = convertUnit(limits.Memory().String(), strproc.Mi)                      // 1024 Mi
+		return info, nil
+	}
+
 	// populate node resources info
 	nodes := corev1.NodeList{}
 	err = cli.Cache().List(ctx, &nodes, &client.ListOptions{LabelSelector: opts.nodeLabelSelector})
@@ -297,7 +276,7 @@ func makeLivedataInfo(ctx context.Context, cli mgrclient.Client, cluster string,
 	for i := range podList.Items {
 		statusPhase := podList.Items[i].Status.Phase
 		if nodesName.Has(podList.Items[i].Spec.NodeName) && statusPhase != corev1.PodSucceeded && statusPhase != corev1.PodFailed {
-			req, limit := podRequestsAndLimits(&podList.Items[i])
+			req, limit := inventory.PodRequestsAndLimits(&podList.Items[i])
 			cpuReq, cpuLimit, memoryReq, memoryLimit := req[corev1.ResourceCPU], limit[corev1.ResourceCPU], req[corev1.ResourceMemory], limit[corev1.ResourceMemory]
 			info.UsedCPURequest += int(cpuReq.MilliValue())                    // 1000 m
 			info.UsedCPULimit += int(cpuLimit.MilliValue())                    // 1000 m
@@ -357,6 +336,66 @@ func listClusterNames() []string {
 	return clusterNames
 }
 
+// filterClustersBySelector filters clusters whose labels match given selector
+func filterClustersBySelector(clusters []clusterv1.Cluster, selector labels.Selector) []clusterv1.Cluster {
+	if selector.Empty() {
+		return clusters
+	}
+
+	filtered := make([]clusterv1.Cluster, 0)
+	for _, cluster := range clusters {
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			filtered = append(filtered, cluster)
+		}
+	}
+
+	return filtered
+}
+
+// filterClusterNamesBySelector filters cluster names whose labels match given selector
+func filterClusterNamesBySelector(ctx context.Context, clusterNames []string, selector labels.Selector) ([]string, error) {
+	// clusters of namespace are available ones, unavailable clusters matched are ignored
+	matched, err := multicluster.Interface().ListClustersNameBySelector(ctx, selector)
+	if _, ok := multicluster.UnavailableClustersOf(err); err != nil && !ok {
+		return nil, err
+	}
+
+	set := sets.NewString(matched...)
+	filtered := make([]string, 0)
+	for _, name := range clusterNames {
+		if set.Has(name) {
+			filtered = append(filtered, name)
+		}
+	}
+
+	return filtered, nil
+}
+
+// isReservedLabel tells if the label of cluster is reserved by KubeCube
+func isReservedLabel(key string) bool {
+	for _, domain := range reservedLabelDomains {
+		if strings.HasPrefix(key, domain) || strings.Contains(key, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeClusterLabels replaces labels of cluster with given labels
+// and the labels reserved by KubeCube are kept
+func mergeClusterLabels(old, new map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for k, v := range old {
+		if isReservedLabel(k) {
+			merged[k] = v
+		}
+	}
+	for k, v := range new {
+		merged[k] = v
+	}
+	return merged
+}
+
 // getClustersByNamespace get clusters where the namespace work in
 func getClustersByNamespace(namespace string, ctx context.Context) ([]string, error) {
 	clusterNames := make([]string, 0)
@@ -458,3 +497,28 @@ func getAssignedResource(cli mgrclient.Client, cluster string) (cpu resource.Qua
 	}
 	return cpu, mem, gpu, err
 }
+
+// validateDeletionPolicies ensures every category is known and given once
+func validateDeletionPolicies(policies []clusterv1.CategoryDeletionPolicy) error {
+	categories := sets.NewString()
+	for _, c := range clusterv1.ResourceCategories {
+		categories.Insert(string(c))
+	}
+
+	seen := sets.NewString()
+	for _, p := range policies {
+		if !categories.Has(string(p.Category)) {
+			return fmt.Errorf("unknown category %v, must be one of %v", p.Category, categories.List())
+		}
+		if seen.Has(string(p.Category)) {
+			return fmt.Errorf("category %v given more than once", p.Category)
+		}
+		seen.Insert(string(p.Category))
+
+		if p.Policy != clusterv1.DeletionRetain && p.Policy != clusterv1.DeletionPurge {
+			return fmt.Errorf("unknown policy %v of category %v, must be %v or %v", p.Policy, p.Category, clusterv1.DeletionRetain, clusterv1.DeletionPurge)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	mgrclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
+	"github.com/kubecube-io/kubecube/pkg/utils/access"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/errcode"
+	"github.com/kubecube-io/kubecube/pkg/utils/response"
+)
+
+const subPath = "/projects"
+
+type handler struct {
+	mgrclient.Client
+}
+
+func NewHandler() *handler {
+	h := new(handler)
+	h.Client = clients.Interface().Kubernetes(constants.LocalCluster)
+	return h
+}
+
+func (h *handler) AddApisTo(root *gin.Engine) {
+	r := root.Group(constants.ApiPathRoot + subPath)
+	r.POST("/:project/move", h.moveProject)
+}
+
+type projectMove struct {
+	// Tenant is the destination tenant of project
+	Tenant string `json:"tenant" binding:"required"`
+	// DryRun validates the move and returns its plan without changing anything
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// moveProject moves project with its namespaces, quotas and role bindings to other tenant
+// @Summary Move project to other tenant
+// @Description move project with its namespaces, cube resource quotas and role bindings under other tenant in all clusters, quotas of project are validated against quotas of destination tenant before move
+// @Tags project
+// @Param project path string true "project name"
+// @Param projectMove body projectMove true "destination tenant of project"
+// @Success 200 {object} tenancy.ProjectMove
+// @Failure 400 {object} errcode.ErrorInfo
+// @Failure 500 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/projects/{project}/move  [post]
+func (h *handler) moveProject(c *gin.Context) {
+	projectName := c.Param("project")
+
+	d := projectMove{}
+	err := c.ShouldBindJSON(&d)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project := &tenantv1.Project{}
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: projectName}, project)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "project %v not found", projectName))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	// user should be able to manage both project and destination tenant
+	tenant := &tenantv1.Tenant{}
+	err = h.Direct().Get(ctx, types.NamespacedName{Name: d.Tenant}, tenant)
+	if err != nil {
+		clog.Warn(err.Error())
+		if errors.IsNotFound(err) {
+			response.FailReturn(c, errcode.CustomReturn(http.StatusNotFound, "tenant %v not found", d.Tenant))
+			return
+		}
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, project); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+	if access := access.AllowAccess(constants.LocalCluster, c.Request, constants.UpdateVerb, tenant); !access {
+		clog.Debug("permission check fail")
+		response.FailReturn(c, errcode.ForbiddenErr)
+		return
+	}
+
+	plan, err := tenancy.PlanProjectMove(ctx, h.Direct(), project, d.Tenant)
+	if err != nil {
+		clog.Warn(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusBadRequest, err.Error()))
+		return
+	}
+	if d.DryRun {
+		response.SuccessReturn(c, plan)
+		return
+	}
+
+	plan, err = tenancy.MoveProject(ctx, h.Direct(), project, d.Tenant)
+	if err != nil {
+		clog.Error(err.Error())
+		response.FailReturn(c, errcode.CustomReturn(http.StatusInternalServerError, err.Error()))
+		return
+	}
+
+	clog.Info("user %v moved project %v to tenant %v", c.GetString(constants.UserName), projectName, d.Tenant)
+
+	response.SuccessReturn(c, plan)
+}
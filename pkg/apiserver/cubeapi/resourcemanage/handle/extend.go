@@ -33,6 +33,7 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/apiserver/cubeapi/resourcemanage/resources/enum"
 	"github.com/kubecube-io/kubecube/pkg/clients"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/features"
 	"github.com/kubecube-io/kubecube/pkg/utils/audit"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/env"
@@ -85,6 +86,10 @@ func (e *ExtendHandler) ExtendHandle(c *gin.Context) {
 		response.FailReturn(c, errcode.ClusterNotFoundError(cluster))
 		return
 	}
+	if !featureEnabled(c.Request.Context(), cluster, features.ExtendAPI) {
+		response.FailReturn(c, errcode.FeatureDisabledError(string(features.ExtendAPI), cluster))
+		return
+	}
 	// get user info
 	username := c.GetString(constants.UserName)
 
@@ -0,0 +1,36 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcemanage
+
+import (
+	"context"
+
+	"k8s.io/component-base/featuregate"
+
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/features"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// featureEnabled tells if feature is enabled for cluster by the Cluster in pivot cluster
+func featureEnabled(ctx context.Context, cluster string, feature featuregate.Feature) bool {
+	cli := clients.Interface().Kubernetes(constants.LocalCluster)
+	if cli == nil {
+		return features.DefaultFeatureGate.Enabled(feature)
+	}
+	return features.EnabledForCluster(ctx, cli.Cache(), cluster, feature)
+}
@@ -28,12 +28,16 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/conversion"
+	"github.com/kubecube-io/kubecube/pkg/features"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/catalog"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/errcode"
 	"github.com/kubecube-io/kubecube/pkg/utils/filter"
@@ -45,25 +49,29 @@ import (
 )
 
 type ProxyHandler struct {
-	// enableConvert means proxy handler will convert resources
-	enableConvert bool
 	// converter the version converter for doing resources convert
 	converter conversion.MultiVersionConverter
 }
 
-func NewProxyHandler(enableConvert bool) *ProxyHandler {
+func NewProxyHandler() *ProxyHandler {
 	return &ProxyHandler{
-		enableConvert: enableConvert,
-		converter:     multicluster.NewDefaultMultiVersionConverter(multicluster.Interface()),
+		converter: multicluster.NewMultiVersionConverterWithDiscovery(multicluster.Interface(), catalog.Interface().Discovery),
 	}
 }
 
 // tryVersionConvert try to convert url and request body by given target cluster
-func (h *ProxyHandler) tryVersionConvert(cluster, url string, req *http.Request) (bool, []byte, string, error) {
-	if !h.enableConvert {
+func (h *ProxyHandler) tryVersionConvert(cluster, url string, req *http.Request) (needConvert bool, convertedObj []byte, convertedUrl string, err error) {
+	// version conversion is gated per cluster
+	if !featureEnabled(req.Context(), cluster, features.VersionConversion) {
 		return false, nil, "", nil
 	}
 
+	_, span := tracing.Start(req.Context(), "cube.proxy.tryVersionConvert", attribute.String("cluster", cluster))
+	defer func() {
+		span.SetAttributes(attribute.Bool("convert.needed", needConvert))
+		tracing.End(span, err)
+	}()
+
 	_, isNamespaced, gvr, err := conversion.ParseURL(url)
 	if err != nil {
 		return false, nil, "", err
@@ -87,7 +95,7 @@ func (h *ProxyHandler) tryVersionConvert(cluster, url string, req *http.Request)
 	}
 
 	// convert url according to specified gvr at first
-	convertedUrl, err := conversion.ConvertURL(url, &schema.GroupVersionResource{Group: recommendVersion.Group, Version: recommendVersion.Version, Resource: gvr.Resource})
+	convertedUrl, err = conversion.ConvertURL(url, &schema.GroupVersionResource{Group: recommendVersion.Group, Version: recommendVersion.Version, Resource: gvr.Resource})
 	if err != nil {
 		return false, nil, "", err
 	}
@@ -115,7 +123,7 @@ func (h *ProxyHandler) tryVersionConvert(cluster, url string, req *http.Request)
 		return false, nil, "", err
 	}
 	// encode concerted object
-	convertedObj, err := converter.Encode(out, recommendVersion.GroupVersion())
+	convertedObj, err = converter.Encode(out, recommendVersion.GroupVersion())
 	if err != nil {
 		return false, nil, "", err
 	}
@@ -140,6 +148,19 @@ func (h *ProxyHandler) ProxyHandle(c *gin.Context) {
 	// http request params
 	cluster := c.Param("cluster")
 	proxyUrl := c.Param("url")
+
+	// join the trace of caller if any. Requests are proxied to kube-apiserver
+	// of member cluster rather than warden, so this hop ends the trace
+	ctx, span := tracing.Start(tracing.Extract(c.Request.Context(), c.Request.Header), "cube.proxy",
+		attribute.String("cluster", cluster),
+		attribute.String("http.method", c.Request.Method),
+		attribute.String("http.target", proxyUrl))
+	defer func() {
+		span.SetAttributes(attribute.Int("http.status_code", c.Writer.Status()))
+		span.End()
+	}()
+	c.Request = c.Request.WithContext(ctx)
+
 	username := c.GetString(constants.UserName)
 	if len(username) == 0 {
 		clog.Warn("username is empty")
@@ -264,6 +285,10 @@ func (h *ProxyHandler) ProxyHandle(c *gin.Context) {
 			}
 			req.URL.RawQuery = queryString
 		}
+
+		// kube-apiserver does not join the trace, the trace context is only
+		// injected for tracing proxies between KubeCube and kube-apiserver
+		tracing.Inject(req.Context(), req.Header)
 	}
 
 	errorHandler := func(resp http.ResponseWriter, req *http.Request, err error) {
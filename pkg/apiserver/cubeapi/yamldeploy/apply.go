@@ -0,0 +1,160 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamldeploy
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	requestutil "github.com/kubecube-io/kubecube/pkg/utils/request"
+)
+
+const (
+	// CreateMode creates objects and fails if object exists
+	CreateMode = "create"
+	// ApplyMode applies objects by server-side apply
+	ApplyMode = "apply"
+)
+
+// action of object deployed
+const (
+	actionCreated = "created"
+	actionApplied = "applied"
+	actionPruned  = "pruned"
+)
+
+// ApplyByRestClient applies object by server-side apply with the user as field manager,
+// the conflicts with other field managers will be overwritten if force is true.
+func ApplyByRestClient(restClient *rest.RESTClient, mapping *meta.RESTMapping, namespace string, dryRun string, obj runtime.Object, username string, force bool) (runtime.Object, error) {
+	fieldManager, err := requestutil.FieldManagerFor(username)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := metadataAccessor.Name(obj)
+	if err != nil || len(name) == 0 {
+		return nil, fmt.Errorf("miss name in .metadata.name")
+	}
+
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+	if dryRun == "true" {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+
+	return restClient.Patch(types.ApplyPatchType).
+		SetHeader(constants.ImpersonateUserKey, username).
+		NamespaceIfScoped(namespace, mapping.Scope.Name() == meta.RESTScopeNameNamespace).
+		Resource(mapping.Resource.Resource).
+		Name(name).
+		VersionedParams(options, metav1.ParameterCodec).
+		Body(data).
+		Do(context.TODO()).
+		Get()
+}
+
+// pruneObjects deletes objects selected by selector which are not in the applied objects.
+// Only the kinds and namespaces of applied objects are pruned to limit the scope.
+func pruneObjects(ctx context.Context, config *rest.Config, mapper meta.RESTMapper, applied []*unstructured.Unstructured, selector labels.Selector, dryRun bool, username string) ([]objectResult, error) {
+	if selector == nil || selector.Empty() {
+		return nil, fmt.Errorf("prune selector must not be empty")
+	}
+
+	impersonated := rest.CopyConfig(config)
+	impersonated.Impersonate = rest.ImpersonationConfig{UserName: username}
+	cli, err := dynamic.NewForConfig(impersonated)
+	if err != nil {
+		return nil, err
+	}
+
+	type scope struct {
+		mapping   *meta.RESTMapping
+		namespace string
+	}
+
+	keep := make(map[string]bool)
+	scopes := make(map[string]scope)
+	for _, obj := range applied {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			// the object failed to apply already
+			continue
+		}
+		namespace := ""
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace = obj.GetNamespace()
+		}
+		keep[objectKey(gvk.GroupKind().String(), namespace, obj.GetName())] = true
+		scopes[mapping.Resource.String()+"/"+namespace] = scope{mapping: mapping, namespace: namespace}
+	}
+
+	deleteOptions := metav1.DeleteOptions{}
+	if dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+	}
+	propagation := metav1.DeletePropagationBackground
+	deleteOptions.PropagationPolicy = &propagation
+
+	var results []objectResult
+	for _, s := range scopes {
+		list, err := cli.Resource(s.mapping.Resource).Namespace(s.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return results, err
+		}
+		for _, item := range list.Items {
+			if keep[objectKey(s.mapping.GroupVersionKind.GroupKind().String(), s.namespace, item.GetName())] {
+				continue
+			}
+			result := objectResult{
+				APIVersion: item.GetAPIVersion(),
+				Kind:       item.GetKind(),
+				Namespace:  item.GetNamespace(),
+				Name:       item.GetName(),
+				Action:     actionPruned,
+			}
+			err = cli.Resource(s.mapping.Resource).Namespace(s.namespace).Delete(ctx, item.GetName(), deleteOptions)
+			if err != nil && !errors.IsNotFound(err) {
+				result.Message = err.Error()
+			} else {
+				result.Success = true
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+func objectKey(groupKind, namespace, name string) string {
+	return groupKind + "/" + namespace + "/" + name
+}
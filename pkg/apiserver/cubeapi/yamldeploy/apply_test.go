@@ -0,0 +1,50 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamldeploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeployOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, err := newDeployOptions("", true, false, "")
+	assert.Nil(err)
+	assert.Equal(CreateMode, opts.mode)
+	assert.True(opts.dryRun)
+	assert.Nil(opts.pruneSelector)
+
+	opts, err = newDeployOptions(ApplyMode, false, true, "app=demo")
+	assert.Nil(err)
+	assert.Equal(ApplyMode, opts.mode)
+	assert.True(opts.force)
+	assert.Equal("app=demo", opts.pruneSelector.String())
+
+	// prune without apply
+	_, err = newDeployOptions(CreateMode, false, false, "app=demo")
+	assert.NotNil(err)
+
+	// invalid selector
+	_, err = newDeployOptions(ApplyMode, false, false, "app==,")
+	assert.NotNil(err)
+
+	_, err = newDeployOptions("replace", false, false, "")
+	assert.NotNil(err)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamldeploy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// kindOrder is the order of kinds to deploy, the kinds depended by others go first
+var kindOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PriorityClass",
+	"StorageClass",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"ResourceQuota",
+	"LimitRange",
+	"NetworkPolicy",
+	"Secret",
+	"ConfigMap",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"Service",
+	"DaemonSet",
+	"Deployment",
+	"StatefulSet",
+	"ReplicaSet",
+	"Pod",
+	"Job",
+	"CronJob",
+	"HorizontalPodAutoscaler",
+	"PodDisruptionBudget",
+	"Ingress",
+}
+
+// kindRanks maps kind to its rank of deploy order
+var kindRanks = func() map[string]int {
+	ranks := make(map[string]int, len(kindOrder))
+	for i, kind := range kindOrder {
+		ranks[kind] = i
+	}
+	return ranks
+}()
+
+// kindRank returns the deploy rank of kind, unknown kinds such as
+// custom resources go last
+func kindRank(kind string) int {
+	if rank, ok := kindRanks[kind]; ok {
+		return rank
+	}
+	return len(kindOrder)
+}
+
+// sortByDependency sorts objects by kind order and keeps the order in yaml
+// for objects with same kind
+func sortByDependency(objs []*unstructured.Unstructured) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		return kindRank(objs[i].GetKind()) < kindRank(objs[j].GetKind())
+	})
+}
+
+// decodeBundle decodes multi-document yaml separated by "---" into objects
+// ordered by dependency, the items of List are flatten and the empty
+// documents are skipped.
+func decodeBundle(data []byte) ([]*unstructured.Unstructured, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+
+	var objs []*unstructured.Unstructured
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read document %v failed: %v", i, err)
+		}
+
+		docJson, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %v is invalid: %v", i, err)
+		}
+		if len(bytes.TrimSpace(docJson)) == 0 || bytes.Equal(bytes.TrimSpace(docJson), []byte("null")) {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		_, _, err = unstructured.UnstructuredJSONScheme.Decode(docJson, nil, obj)
+		if err != nil {
+			return nil, fmt.Errorf("decode document %v failed: %v", i, err)
+		}
+
+		if !obj.IsList() {
+			objs = append(objs, obj)
+			continue
+		}
+
+		err = obj.EachListItem(func(item runtime.Object) error {
+			u, ok := item.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("unexpected item type %T", item)
+			}
+			objs = append(objs, u)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("decode list in document %v failed: %v", i, err)
+		}
+	}
+
+	sortByDependency(objs)
+
+	return objs, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamldeploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bundle = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm-1
+  namespace: ns-1
+---
+# comment only
+---
+{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "deploy-1", "namespace": "ns-1"}}
+---
+`
+
+func TestDecodeBundle(t *testing.T) {
+	assert := assert.New(t)
+
+	objs, err := decodeBundle([]byte(bundle))
+	assert.Nil(err)
+	assert.Len(objs, 2)
+	assert.Equal("ConfigMap", objs[0].GetKind())
+	assert.Equal("cm-1", objs[0].GetName())
+	assert.Equal("Deployment", objs[1].GetKind())
+	assert.Equal("apps", objs[1].GroupVersionKind().Group)
+
+	_, err = decodeBundle([]byte("metadata:\n  name: no-kind\n"))
+	assert.NotNil(err)
+}
+
+const listBundle = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deploy-1
+  namespace: ns-1
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: svc-1
+    namespace: ns-1
+- apiVersion: rbac.authorization.k8s.io/v1
+  kind: RoleBinding
+  metadata:
+    name: rb-1
+    namespace: ns-1
+---
+apiVersion: demo.kubecube.io/v1
+kind: Demo
+metadata:
+  name: demo-1
+  namespace: ns-1
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: demos.demo.kubecube.io
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ns-1
+`
+
+func TestDecodeBundleInOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	objs, err := decodeBundle([]byte(listBundle))
+	assert.Nil(err)
+
+	var kinds []string
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.Equal([]string{"Namespace", "CustomResourceDefinition", "RoleBinding", "Service", "Deployment", "Demo"}, kinds)
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package yamldeploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/audit"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/errcode"
+	"github.com/kubecube-io/kubecube/pkg/utils/response"
+)
+
+// maxConcurrentClusters is the max number of clusters deployed at same time
+const maxConcurrentClusters = 10
+
+// fanOutRequest is the yaml bundle deploy to multi clusters
+type fanOutRequest struct {
+	// ClusterSet is the target clusters given by names or label selector
+	multicluster.ClusterSet `json:",inline"`
+
+	// Yaml is multi-document yaml separated by "---"
+	Yaml string `json:"yaml"`
+
+	// DryRun means objects will not be persisted
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Mode is the way to deploy objects, create or apply, default is create
+	Mode string `json:"mode,omitempty"`
+
+	// Force overwrites the conflicts fields owned by others when apply
+	Force bool `json:"force,omitempty"`
+
+	// PruneSelector is the label selector of objects to prune after apply
+	PruneSelector string `json:"pruneSelector,omitempty"`
+}
+
+// objectResult is the deploy result of an object
+type objectResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Action     string `json:"action,omitempty"`
+	Success    bool   `json:"success"`
+	Message    string `json:"message,omitempty"`
+}
+
+// clusterResult is the deploy result of a cluster
+type clusterResult struct {
+	Cluster string         `json:"cluster"`
+	Success bool           `json:"success"`
+	Message string         `json:"message,omitempty"`
+	Objects []objectResult `json:"objects,omitempty"`
+}
+
+// fanOutResult is the deploy results of all target clusters
+type fanOutResult struct {
+	Total  int             `json:"total"`
+	Failed int             `json:"failed"`
+	DryRun bool            `json:"dryRun"`
+	Items  []clusterResult `json:"items"`
+}
+
+// DeployToClusters deploys yaml bundle to multi clusters concurrently
+// @Summary Deploy yaml to multi clusters
+// @Description deploy multi-document yaml to clusters given by names or label selector, results are given per cluster and per object
+// @Tags yamldeploy
+// @Param fanOutRequest body fanOutRequest true "yaml bundle and target clusters"
+// @Success 200 {object} fanOutResult
+// @Failure 400 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/extend/yaml/deploy  [post]
+func DeployToClusters(c *gin.Context) {
+	req := fanOutRequest{}
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailReturn(c, errcode.InvalidBodyFormat)
+		return
+	}
+
+	if req.ClusterSet.IsEmpty() {
+		response.FailReturn(c, errcode.MissTargetClusters)
+		return
+	}
+
+	clusterNames, err := req.ClusterSet.Names(c.Request.Context(), multicluster.Interface())
+	unavailable, ok := multicluster.UnavailableClustersOf(err)
+	if err != nil && !ok {
+		response.FailReturn(c, errcode.BadRequest(err))
+		return
+	}
+	if len(clusterNames) == 0 && len(unavailable) == 0 {
+		response.FailReturn(c, errcode.MissTargetClusters)
+		return
+	}
+
+	opts, err := newDeployOptions(req.Mode, req.DryRun, req.Force, req.PruneSelector)
+	if err != nil {
+		response.FailReturn(c, errcode.BadRequest(err))
+		return
+	}
+
+	objs, err := decodeBundle([]byte(req.Yaml))
+	if err != nil {
+		response.FailReturn(c, errcode.DeployYamlError(err.Error()))
+		return
+	}
+	if len(objs) == 0 {
+		response.FailReturn(c, errcode.EmptyYamlBundle)
+		return
+	}
+
+	c = audit.SetAuditInfo(c, audit.YamlDeploy, strings.Join(clusterNames, ","))
+
+	username := c.GetString(constants.UserName)
+	result := fanOut(c.Request.Context(), clusterNames, objs, opts, username)
+
+	// clusters selected but not available are failed without deploying
+	for _, name := range unavailable {
+		result.Items = append(result.Items, clusterResult{Cluster: name, Message: fmt.Sprintf("cluster %v is not available", name)})
+		result.Total++
+		result.Failed++
+	}
+
+	response.SuccessReturn(c, result)
+}
+
+// fanOut deploys objects to clusters concurrently, objects are
+// deployed one by one in order within a cluster.
+func fanOut(ctx context.Context, clusterNames []string, objs []*unstructured.Unstructured, opts deployOptions, username string) fanOutResult {
+	clusters := multicluster.Interface().FuzzyCopy()
+	pivotCli := clients.Interface().Kubernetes(constants.LocalCluster)
+
+	result := fanOutResult{
+		Total:  len(clusterNames),
+		DryRun: opts.dryRun,
+		Items:  make([]clusterResult, len(clusterNames)),
+	}
+
+	wg := sync.WaitGroup{}
+	limit := make(chan struct{}, maxConcurrentClusters)
+	for i, name := range clusterNames {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, name string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			cluster, ok := clusters[name]
+			if !ok {
+				result.Items[i] = clusterResult{Cluster: name, Message: fmt.Sprintf("cluster %v not found", name)}
+				return
+			}
+			inMaintenance, err := utils.IsClusterInMaintenance(ctx, pivotCli.Cache(), name)
+			if err != nil {
+				result.Items[i] = clusterResult{Cluster: name, Message: err.Error()}
+				return
+			}
+			if inMaintenance {
+				result.Items[i] = clusterResult{Cluster: name, Message: errcode.ClusterInMaintenanceError(name).Message}
+				return
+			}
+			result.Items[i] = deployToCluster(ctx, cluster, objs, opts, username)
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, item := range result.Items {
+		if !item.Success {
+			result.Failed++
+		}
+	}
+
+	return result
+}
+
+// deployOptions controls the way to deploy objects
+type deployOptions struct {
+	dryRun        bool
+	mode          string
+	force         bool
+	pruneSelector labels.Selector
+}
+
+// newDeployOptions validates and builds deploy options
+func newDeployOptions(mode string, dryRun, force bool, pruneSelector string) (deployOptions, error) {
+	opts := deployOptions{dryRun: dryRun, mode: mode, force: force}
+
+	switch mode {
+	case "":
+		opts.mode = CreateMode
+	case CreateMode, ApplyMode:
+	default:
+		return opts, fmt.Errorf("unsupported deploy mode %v", mode)
+	}
+
+	if len(pruneSelector) > 0 {
+		if opts.mode != ApplyMode {
+			return opts, fmt.Errorf("prune only supported in apply mode")
+		}
+		selector, err := labels.Parse(pruneSelector)
+		if err != nil {
+			return opts, fmt.Errorf("prune selector invalid: %v", err)
+		}
+		if selector.Empty() {
+			return opts, fmt.Errorf("prune selector must not be empty")
+		}
+		opts.pruneSelector = selector
+	}
+
+	return opts, nil
+}
+
+// deployToCluster deploys objects to given cluster, the cluster is regarded
+// as failed if any of objects deploy failed.
+func deployToCluster(ctx context.Context, cluster *multicluster.FuzzyCluster, objs []*unstructured.Unstructured, opts deployOptions, username string) clusterResult {
+	result := clusterResult{Cluster: cluster.Name}
+
+	mapper, err := NewRestMapper(cluster.Client)
+	if err != nil {
+		result.Message = fmt.Sprintf("discover api resources failed: %v", err)
+		return result
+	}
+
+	result.Success = true
+	crdDeployed := false
+	for _, obj := range objs {
+		// rediscover api resources for custom resources defined in the same yaml
+		if crdDeployed && isNoMatch(mapper, obj) {
+			if m, err := NewRestMapper(cluster.Client); err == nil {
+				mapper = m
+				crdDeployed = false
+			}
+		}
+
+		r := deployObject(cluster.Config, mapper, obj.DeepCopy(), opts, username)
+		if !r.Success {
+			result.Success = false
+		}
+		if r.Success && obj.GetKind() == "CustomResourceDefinition" {
+			crdDeployed = true
+		}
+		result.Objects = append(result.Objects, r)
+	}
+
+	// prune only when all objects applied to avoid deleting objects by mistake
+	if opts.pruneSelector != nil && result.Success {
+		pruned, err := pruneObjects(ctx, cluster.Config, mapper, objs, opts.pruneSelector, opts.dryRun, username)
+		result.Objects = append(result.Objects, pruned...)
+		if err != nil {
+			result.Success = false
+			result.Message = fmt.Sprintf("prune objects failed: %v", err)
+			return result
+		}
+		for _, r := range pruned {
+			if !r.Success {
+				result.Success = false
+			}
+		}
+	}
+
+	if !result.Success {
+		result.Message = "some objects deploy failed"
+	}
+
+	clog.Debug("user %v %v %v objects to cluster %v, success: %v", username, opts.mode, len(objs), cluster.Name, result.Success)
+
+	return result
+}
+
+// isNoMatch tells if the kind of object is unknown by mapper
+func isNoMatch(mapper meta.RESTMapper, obj *unstructured.Unstructured) bool {
+	gvk := obj.GroupVersionKind()
+	_, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	return meta.IsNoMatchError(err)
+}
+
+// deployObject creates or applies object in cluster with the identity of user
+func deployObject(config *rest.Config, mapper meta.RESTMapper, obj *unstructured.Unstructured, opts deployOptions, username string) objectResult {
+	gvk := obj.GroupVersionKind()
+	result := objectResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		result.Message = fmt.Sprintf("create mapping fail, %v", err)
+		return result
+	}
+
+	// rest config is modified when new rest client
+	restClient, err := NewRestClient(rest.CopyConfig(config), &gvk)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	dryRun := fmt.Sprint(opts.dryRun)
+	if opts.mode == ApplyMode {
+		result.Action = actionApplied
+		_, err = ApplyByRestClient(restClient, mapping, obj.GetNamespace(), dryRun, obj, username, opts.force)
+	} else {
+		result.Action = actionCreated
+		_, err = CreateByRestClient(restClient, mapping, obj.GetNamespace(), dryRun, obj, username)
+	}
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Success = true
+
+	return result
+}
@@ -20,19 +20,18 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/kubectl/pkg/scheme"
-	"sigs.k8s.io/yaml"
 
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
@@ -45,9 +44,28 @@ import (
 
 var metadataAccessor = meta.NewAccessor()
 
+// Deploy deploys multi-document yaml to the cluster
+// @Summary Deploy yaml to cluster
+// @Description deploy multi-document yaml or List to cluster, objects are deployed in order of dependency and results are given per object
+// @Tags yamldeploy
+// @Param cluster path string true "cluster name"
+// @Param dryRun query string false "objects will not be persisted if true"
+// @Param mode query string false "create or apply, default is create"
+// @Param force query string false "overwrite conflicts fields when apply if true"
+// @Param pruneSelector query string false "label selector of objects to prune after apply"
+// @Success 200 {object} clusterResult
+// @Failure 400 {object} errcode.ErrorInfo
+// @Router /api/v1/cube/extend/clusters/{cluster}/yaml/deploy  [post]
 func Deploy(c *gin.Context) {
 	dryRun := c.Query("dryRun")
 
+	// create by default, apply means server-side apply
+	opts, err := newDeployOptions(c.Query("mode"), dryRun == "true", c.Query("force") == "true", c.Query("pruneSelector"))
+	if err != nil {
+		response.FailReturn(c, errcode.BadRequest(err))
+		return
+	}
+
 	// get cluster info
 	clusterName := c.Param("cluster")
 	clusters := multicluster.Interface().FuzzyCopy()
@@ -63,48 +81,26 @@ func Deploy(c *gin.Context) {
 		response.FailReturn(c, errcode.InvalidBodyFormat)
 		return
 	}
-	bodyJson, err := yaml.YAMLToJSON(body)
-	if err != nil {
-		response.FailReturn(c, errcode.InvalidBodyFormat)
-		return
-	}
-	obj, gvk, err := unstructured.UnstructuredJSONScheme.Decode(bodyJson, nil, nil)
-	if err != nil {
-		response.FailReturn(c, errcode.InvalidBodyFormat)
-		return
-	}
-
-	// new RestClient
-	restClient, err := NewRestClient(cluster.Config, gvk)
+	objs, err := decodeBundle(body)
 	if err != nil {
-		response.FailReturn(c, errcode.InternalServerError)
+		response.FailReturn(c, errcode.DeployYamlError(err.Error()))
 		return
 	}
-
-	// init mapping
-	restMapping, err := InitRestMapper(cluster.Client, gvk)
-	if err != nil {
-		errInfo := err.Error()
-		response.FailReturn(c, errcode.CreateMappingError(errInfo))
+	if len(objs) == 0 {
+		response.FailReturn(c, errcode.EmptyYamlBundle)
 		return
 	}
 
-	// get namespace
-	namespace, err := metadataAccessor.Namespace(obj)
-	if err != nil {
-		response.FailReturn(c, errcode.MissNamespaceInObj)
-		return
+	resources := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		resources = append(resources, fmt.Sprintf("%s/%s/%s", obj.GetNamespace(), obj.GetKind(), obj.GetName()))
 	}
-
-	c = audit.SetAuditInfo(c, audit.YamlDeploy, fmt.Sprintf("%s/%s", namespace, restMapping.Resource.String()))
+	c = audit.SetAuditInfo(c, audit.YamlDeploy, strings.Join(resources, ","))
 
 	username := c.GetString(constants.UserName)
-	// create
-	result, err := CreateByRestClient(restClient, restMapping, namespace, dryRun, obj, username)
-	if err != nil {
-		response.FailReturn(c, errcode.DeployYamlError(err.Error()))
-		return
-	}
+
+	// objects failed to deploy do not stop the others
+	result := deployToCluster(c.Request.Context(), cluster, objs, opts, username)
 
 	response.SuccessReturn(c, result)
 }
@@ -147,13 +143,12 @@ func NewRestClient(config *rest.Config, gvk *schema.GroupVersionKind) (*rest.RES
 }
 
 func InitRestMapper(client client.Client, gvk *schema.GroupVersionKind) (*meta.RESTMapping, error) {
-
-	groupResources, err := restmapper.GetAPIGroupResources(client.ClientSet().Discovery())
+	mapper, err := NewRestMapper(client)
 	if err != nil {
-		clog.Warn("restmapper get api group resources fail, %v", err)
 		return nil, err
 	}
-	mapping, err := restmapper.NewDiscoveryRESTMapper(groupResources).RESTMapping(gvk.GroupKind(), gvk.Version)
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 	if err != nil {
 		clog.Warn("create rest mapping fail, %v", err)
 		return nil, err
@@ -161,3 +156,14 @@ func InitRestMapper(client client.Client, gvk *schema.GroupVersionKind) (*meta.R
 
 	return mapping, nil
 }
+
+// NewRestMapper discovers api resources of cluster to map gvk to resource
+func NewRestMapper(client client.Client) (meta.RESTMapper, error) {
+	groupResources, err := restmapper.GetAPIGroupResources(client.ClientSet().Discovery())
+	if err != nil {
+		clog.Warn("restmapper get api group resources fail, %v", err)
+		return nil, err
+	}
+
+	return restmapper.NewDiscoveryRESTMapper(groupResources), nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	cubemetrics "github.com/kubecube-io/kubecube/pkg/metrics"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+)
+
+const (
+	component = "cube-apiserver"
+
+	// unknownCluster is the label of cluster not managed by KubeCube
+	unknownCluster = "unknown"
+)
+
+// Metrics records requests count and latency by route and cluster
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		cluster := c.Param("cluster")
+		if len(cluster) == 0 {
+			cluster = c.Query("cluster")
+		}
+
+		// cluster is given by client, only the known clusters are kept as
+		// label to bound the cardinality of metrics. Known but abnormal
+		// clusters are returned with error, they are still kept.
+		if len(cluster) > 0 {
+			if c, _ := multicluster.Interface().Get(cluster); c == nil {
+				cluster = unknownCluster
+			}
+		}
+
+		cubemetrics.ObserveRequest(component, c.FullPath(), c.Request.Method, c.Writer.Status(), cluster, time.Since(start))
+	}
+}
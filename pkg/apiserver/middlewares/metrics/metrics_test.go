@@ -0,0 +1,62 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	cubemetrics "github.com/kubecube-io/kubecube/pkg/metrics"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func TestMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	multicluster.InitFakeMultiClusterMgrWithOpts(&fake.Options{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/clusters/:cluster/*url", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{
+		"/clusters/" + constants.LocalCluster + "/api/v1/pods",
+		"/clusters/random-1/api/v1/pods",
+		"/clusters/random-2/api/v1/pods",
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	cubemetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, cubemetrics.Path, nil))
+	body := rec.Body.String()
+
+	// clusters not managed are recorded as unknown
+	assert.True(strings.Contains(body, `cluster="`+constants.LocalCluster+`",code="200",component="cube-apiserver"`), body)
+	assert.True(strings.Contains(body, `kubecube_http_requests_total{cluster="unknown",code="200",component="cube-apiserver",method="GET",route="/clusters/:cluster/*url"} 2`), body)
+	assert.False(strings.Contains(body, "random-"), body)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package precheck
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/errcode"
+	"github.com/kubecube-io/kubecube/pkg/utils/response"
+)
+
+// Maintenance rejects mutating requests to cluster which is under maintenance
+func Maintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.IsMutatingMethod(c.Request.Method) {
+			return
+		}
+
+		cluster := fetchCluster(c)
+		if len(cluster) == 0 {
+			return
+		}
+
+		cli := clients.Interface().Kubernetes(constants.LocalCluster)
+		if cli == nil {
+			return
+		}
+
+		inMaintenance, err := utils.IsClusterInMaintenance(c.Request.Context(), cli.Cache(), cluster)
+		if err != nil {
+			clog.Warn("get maintenance of cluster %v failed: %v", cluster, err)
+			return
+		}
+
+		if inMaintenance {
+			clog.Debug("cluster %v is under maintenance, reject %v %v", cluster, c.Request.Method, c.Request.URL.Path)
+			response.FailReturn(c, errcode.ClusterInMaintenanceError(cluster))
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package precheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubecube-io/kubecube/pkg/apis"
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+)
+
+func TestMaintenance(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apis.AddToScheme(scheme)
+	multicluster.InitFakeMultiClusterMgrWithOpts(&fake.Options{
+		Scheme: scheme,
+		Objs: []client.Object{
+			&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "under-maintenance"}, Spec: clusterv1.ClusterSpec{Maintenance: true}},
+			&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "normal"}},
+		},
+	})
+	clients.InitCubeClientSetWithOpts(nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Any("/clusters/:cluster/*url", Maintenance(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/clusters/under-maintenance/api/v1/pods", http.StatusOK},
+		{http.MethodPost, "/clusters/under-maintenance/api/v1/namespaces/default/pods", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/clusters/under-maintenance/api/v1/namespaces/default/pods/a", http.StatusServiceUnavailable},
+		{http.MethodPost, "/clusters/normal/api/v1/namespaces/default/pods", http.StatusOK},
+		// cluster not found is not in maintenance
+		{http.MethodPost, "/clusters/unknown/api/v1/namespaces/default/pods", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		assert.Equal(test.code, w.Code, "%v %v", test.method, test.path)
+	}
+}
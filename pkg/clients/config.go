@@ -16,10 +16,37 @@ limitations under the License.
 
 package clients
 
+import (
+	"fmt"
+	"time"
+)
+
 type Config struct {
 	//cfg *rest.Config
+
+	// ClusterClientQPS, ClusterClientBurst, ClusterClientTimeout and ClusterCacheResync
+	// are the global default client settings for clusters, see multicluster.ClientSettings
+	ClusterClientQPS     float64
+	ClusterClientBurst   int
+	ClusterClientTimeout time.Duration
+	ClusterCacheResync   time.Duration
 }
 
 func (c *Config) Validate() []error {
-	return nil
+	var errs []error
+
+	if c.ClusterClientQPS < 0 {
+		errs = append(errs, fmt.Errorf("cluster-client-qps must not be negative"))
+	}
+	if c.ClusterClientBurst < 0 {
+		errs = append(errs, fmt.Errorf("cluster-client-burst must not be negative"))
+	}
+	if c.ClusterClientTimeout < 0 {
+		errs = append(errs, fmt.Errorf("cluster-client-timeout must not be negative"))
+	}
+	if c.ClusterCacheResync < 0 {
+		errs = append(errs, fmt.Errorf("cluster-cache-resync must not be negative"))
+	}
+
+	return errs
 }
@@ -36,6 +36,14 @@ type cubeClientSet struct {
 
 // InitCubeClientSetWithOpts initialize global clients with given config.
 func InitCubeClientSetWithOpts(opts *Config) {
+	if opts != nil {
+		multicluster.SetDefaultClientSettings(multicluster.ClientSettings{
+			QPS:          float32(opts.ClusterClientQPS),
+			Burst:        opts.ClusterClientBurst,
+			Timeout:      opts.ClusterClientTimeout,
+			ResyncPeriod: opts.ClusterCacheResync,
+		})
+	}
 	genericClientSet.k8s = multicluster.Interface()
 }
 
@@ -17,13 +17,19 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -35,7 +41,8 @@ import (
 	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
-	"github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
 	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
 )
 
@@ -45,7 +52,12 @@ var (
 	_ reconcile.Reconciler = &ClusterReconciler{}
 )
 
-const clusterFinalizer = "cluster.finalizers.kubecube.io"
+const (
+	clusterFinalizer = "cluster.finalizers.kubecube.io"
+
+	// kubeConfigSecretIndex indexes clusters by namespace/name of kubeconfig secret they reference
+	kubeConfigSecretIndex = "spec.kubeConfigSecretRef"
+)
 
 // ClusterReconciler deploy warden to member cluster
 // when create event trigger
@@ -67,8 +79,22 @@ type ClusterReconciler struct {
 func newReconciler(mgr manager.Manager) (*ClusterReconciler, error) {
 	log = clog.WithName("cluster")
 
+	// secrets are read directly instead of caching all secrets of pivot cluster
+	direct, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+	cli, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader:     mgr.GetCache(),
+		Client:          direct,
+		UncachedObjects: []client.Object{&corev1.Secret{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	r := &ClusterReconciler{
-		Client:   mgr.GetClient(),
+		Client:   cli,
 		Scheme:   mgr.GetScheme(),
 		Affected: make(chan event.GenericEvent),
 	}
@@ -78,6 +104,8 @@ func newReconciler(mgr manager.Manager) (*ClusterReconciler, error) {
 //+kubebuilder:rbac:groups=cluster.kubecube.io,resources=clusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cluster.kubecube.io,resources=clusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=cluster.kubecube.io,resources=clusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log.Info("Reconcile cluster %v", req.Name)
@@ -94,11 +122,6 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
-	// no need lock cause write by one at same time
-	if !cluster.Spec.IsMemberCluster {
-		r.pivotCluster = &cluster
-	}
-
 	// examine DeletionTimestamp to determine if object is under deletion
 	if cluster.ObjectMeta.DeletionTimestamp == nil {
 		// ensure finalizer
@@ -114,12 +137,44 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
-	return r.syncCluster(ctx, cluster)
+	// kubeconfig of cluster only lives in memory after resolved
+	resolved, err := kubeconfig.ResolveCluster(ctx, r.Client, &cluster)
+	if err != nil {
+		log.Error(err.Error())
+		_ = r.updateConditions(ctx, &cluster, newCondition(clusterv1.ClusterCredentialsValid, metav1.ConditionFalse, clusterv1.ReasonKubeConfigNotFound, err.Error()))
+		return ctrl.Result{}, err
+	}
+
+	// move inline kubeconfig out of cluster cr
+	if len(cluster.Spec.KubeConfig) > 0 {
+		if err := r.migrateKubeConfig(ctx, &cluster); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// no need lock cause write by one at same time
+	if !resolved.Spec.IsMemberCluster {
+		r.pivotCluster = resolved
+	}
+
+	// rebuild the connection with cluster in place when kubeconfig changed
+	if needRotateCredential(*resolved) {
+		return r.rotateCredential(ctx, *resolved)
+	}
+
+	// rebuild the clients of cluster in place when client settings changed
+	if needRebuildClients(*resolved) {
+		return r.rebuildClients(*resolved)
+	}
+
+	return r.syncCluster(ctx, *resolved)
 }
 
 func (r *ClusterReconciler) syncCluster(ctx context.Context, cluster clusterv1.Cluster) (ctrl.Result, error) {
 	// update cluster status to processing
-	err := utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterProcessing)
+	err := r.updateConditions(ctx, &cluster,
+		newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionUnknown, clusterv1.ReasonConnecting, "connecting to cluster"),
+		clusterv1.WritableCondition(cluster.Spec.IsWritable))
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -130,7 +185,7 @@ func (r *ClusterReconciler) syncCluster(ctx context.Context, cluster clusterv1.C
 	if err != nil {
 		// todo: what if kubeconfig is wrong
 		log.Error(err.Error())
-		_ = utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterInitFailed)
+		_ = r.updateConditions(ctx, &cluster, newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionFalse, clusterv1.ReasonConnectFailed, err.Error()))
 		r.enqueue(cluster)
 		return ctrl.Result{}, nil
 	}
@@ -140,7 +195,9 @@ func (r *ClusterReconciler) syncCluster(ctx context.Context, cluster clusterv1.C
 	err = deployResources(ctx, tempClient, &cluster, r.pivotCluster)
 	if err != nil {
 		log.Error("deploy resource failed: %v", err)
-		_ = utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterInitFailed)
+		_ = r.updateConditions(ctx, &cluster,
+			newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionTrue, clusterv1.ReasonConnected, "handshake with cluster success"),
+			newCondition(clusterv1.ClusterWardenDeployed, metav1.ConditionFalse, clusterv1.ReasonDeployFailed, err.Error()))
 		return ctrl.Result{}, err
 	}
 	log.Info("Ensure resources in cluster %v success", cluster.Name)
@@ -150,18 +207,25 @@ func (r *ClusterReconciler) syncCluster(ctx context.Context, cluster clusterv1.C
 	err = multicluster.AddInternalClusterWithScout(cluster)
 	if err != nil {
 		log.Error(err.Error())
-		_ = utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterInitFailed)
+		_ = r.updateConditions(ctx, &cluster, newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionFalse, clusterv1.ReasonConnectFailed, err.Error()))
 		r.enqueue(cluster)
 		return ctrl.Result{}, nil
 	}
 	log.Info("Ensure cluster %v in internal clusters success", cluster.Name)
 
+	err = r.updateConditions(ctx, &cluster,
+		newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionTrue, clusterv1.ReasonConnected, "handshake with cluster success"),
+		newCondition(clusterv1.ClusterWardenDeployed, metav1.ConditionTrue, clusterv1.ReasonDeployed, "warden deployed to cluster"))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// start to scout loop for memberCluster warden, non-block
 	// status convert to normal after receive birth cry from scout
 	err = multicluster.Interface().ScoutFor(context.Background(), cluster.Name)
 	if err != nil {
 		log.Error("start scout for cluster %v failed", cluster.Name)
-		_ = utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterInitFailed)
+		_ = r.updateConditions(ctx, &cluster, newCondition(clusterv1.ClusterHeartbeatHealthy, metav1.ConditionFalse, clusterv1.ReasonScoutFailed, err.Error()))
 		return ctrl.Result{}, err
 	}
 
@@ -177,7 +241,7 @@ func (r *ClusterReconciler) enqueue(cluster clusterv1.Cluster) {
 		retryTimeout  = 12 * time.Hour
 	)
 
-	config, _ := kubeconfig.LoadKubeConfigFromBytes(cluster.Spec.KubeConfig)
+	config, _ := multicluster.RestConfigOf(cluster)
 
 	// set retry timeout is 12 hours
 	ctx, cancel := context.WithTimeout(context.Background(), retryTimeout)
@@ -211,7 +275,8 @@ func (r *ClusterReconciler) enqueue(cluster clusterv1.Cluster) {
 				// retrying timeout need update status
 				// todo(weilaaa): to allow user reconnect cluster manually
 				if ctx.Err().Error() == "context deadline exceeded" {
-					_ = utils.UpdateClusterStatusByState(ctx, r.Client, &cluster, clusterv1.ClusterReconnectedFailed)
+					_ = r.updateConditions(context.Background(), &cluster,
+						newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionFalse, clusterv1.ReasonReconnectTimeout, "retry to connect cluster timeout"))
 				}
 
 				return
@@ -227,7 +292,7 @@ func SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	// filter update event
+	// filter update event of cluster
 	predicateFunc := predicate.Funcs{
 		CreateFunc: func(event event.CreateEvent) bool {
 			return true
@@ -236,20 +301,86 @@ func SetupWithManager(mgr ctrl.Manager) error {
 			if !updateEvent.ObjectNew.GetDeletionTimestamp().IsZero() {
 				return true
 			}
+			// kubeconfig changed means the credential of cluster rotated
+			oldCluster, ok1 := updateEvent.ObjectOld.(*clusterv1.Cluster)
+			newCluster, ok2 := updateEvent.ObjectNew.(*clusterv1.Cluster)
+			if ok1 && ok2 && clusterv1.IsKubeConfigChanged(oldCluster, newCluster) {
+				return true
+			}
+			// client settings changed need rebuild clients of cluster
+			if ok1 && ok2 && clusterv1.IsConnectionChanged(oldCluster, newCluster) {
+				return true
+			}
 			return false
 		},
 		DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
 			return false
 		},
 		GenericFunc: func(genericEvent event.GenericEvent) bool {
-			// we use generic event to process init failed cluster
+			return false
+		},
+	}
+
+	// only care about kubeconfig changed of secrets, secrets not referenced by
+	// any cluster are dropped by secretToClusters
+	secretPredicateFunc := predicate.Funcs{
+		CreateFunc: func(event event.CreateEvent) bool {
+			// cluster may be waiting for its kubeconfig secret
 			return true
 		},
+		UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+			oldSecret, ok1 := updateEvent.ObjectOld.(*corev1.Secret)
+			newSecret, ok2 := updateEvent.ObjectNew.(*corev1.Secret)
+			return ok1 && ok2 && !bytes.Equal(oldSecret.Data[constants.ClusterKubeConfigSecretKey], newSecret.Data[constants.ClusterKubeConfigSecretKey])
+		},
+		DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(genericEvent event.GenericEvent) bool {
+			return false
+		},
+	}
+
+	// clusters are found by the secret they reference, whoever created it
+	err = mgr.GetFieldIndexer().IndexField(context.Background(), &clusterv1.Cluster{}, kubeConfigSecretIndex, func(obj client.Object) []string {
+		ref := obj.(*clusterv1.Cluster).Spec.KubeConfigSecretRef
+		if ref == nil {
+			return nil
+		}
+		return []string{ref.Namespace + "/" + ref.Name}
+	})
+	if err != nil {
+		return err
+	}
+
+	secretToClusters := func(obj client.Object) []reconcile.Request {
+		clusters := &clusterv1.ClusterList{}
+		err := mgr.GetClient().List(context.Background(), clusters, client.MatchingFields{kubeConfigSecretIndex: obj.GetNamespace() + "/" + obj.GetName()})
+		if err != nil {
+			log.Warn("list clusters reference secret %v/%v failed: %v", obj.GetNamespace(), obj.GetName(), err)
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(clusters.Items))
+		for _, c := range clusters.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: c.Name}})
+		}
+		return requests
+	}
+
+	// kubeconfig secrets live in namespace of KubeCube, only secrets there are
+	// cached instead of all secrets of pivot cluster
+	secretCache, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper(), Namespace: env.CubeNamespace()})
+	if err != nil {
+		return err
+	}
+	if err = mgr.Add(secretCache); err != nil {
+		return err
 	}
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&clusterv1.Cluster{}).
+		For(&clusterv1.Cluster{}, builder.WithPredicates(predicateFunc)).
+		// we use generic event to process init failed cluster
 		Watches(&source.Channel{Source: r.Affected}, &handler.EnqueueRequestForObject{}).
-		WithEventFilter(predicateFunc).
+		Watches(source.NewKindWithCache(&corev1.Secret{}, secretCache), handler.EnqueueRequestsFromMapFunc(secretToClusters), builder.WithPredicates(secretPredicateFunc)).
 		Complete(r)
 }
@@ -34,10 +34,12 @@ import (
 	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/deletion"
 	"github.com/kubecube-io/kubecube/pkg/utils"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/env"
 	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
+	"github.com/kubecube-io/kubecube/pkg/warden/localmgr/controllers/hotplug"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -89,7 +91,7 @@ func waitForJobComplete(cli client.Client, namespacedName types.NamespacedName)
 }
 
 func tryConnectCluster(cluster clusterv1.Cluster) (client.Client, error) {
-	config, err := kubeconfig.LoadKubeConfigFromBytes(cluster.Spec.KubeConfig)
+	config, err := multicluster.RestConfigOf(cluster)
 	if err != nil {
 		return nil, err
 	}
@@ -153,19 +155,30 @@ func (r *ClusterReconciler) deleteExternalResources(cluster clusterv1.Cluster, c
 		return nil
 	}
 
-	if !env.RetainMemberClusterResource() {
-		mClient := internalCluster.Client
-		// delete kubecube-system of cluster
-		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: env.CubeNamespace()}}
-		err := mClient.Direct().Delete(ctx, &ns)
-		if err != nil {
-			if errors.IsNotFound(err) {
-				clog.Warn("namespace %v of cluster %v not found, delete skip", env.CubeNamespace(), cluster.Name)
-			}
-			// retry if delete resources in member cluster failed
-			clog.Error("delete namespace %v of cluster %v failed: %v", env.CubeNamespace(), cluster.Name, err)
-			return err
-		}
+	// retain or purge resources in member cluster by deletion policies of cluster
+	mClient := internalCluster.Client.Direct()
+	inventory, err := deletion.Collect(ctx, mClient, &cluster)
+	if err != nil {
+		clog.Error(err.Error())
+		return err
+	}
+
+	helm := hotplug.NewHelmForConfig(internalCluster.Config)
+	results, purgeErr := deletion.Execute(ctx, mClient, helm.Uninstall, &cluster, inventory)
+
+	// record the outcome before finalizer removed
+	err = utils.UpdateClusterStatus(ctx, r.Client, &cluster, func(obj *clusterv1.Cluster) {
+		obj.Status.DeletionResults = results
+	})
+	if err != nil {
+		clog.Error("update deletion results of cluster %v failed: %v", cluster.Name, err)
+		return err
+	}
+
+	if purgeErr != nil {
+		// retry if delete resources in member cluster failed
+		clog.Error("clean up cluster %v failed: %v", cluster.Name, purgeErr)
+		return purgeErr
 	}
 
 	// delete internal cluster and release goroutine inside
@@ -179,6 +192,47 @@ func (r *ClusterReconciler) deleteExternalResources(cluster clusterv1.Cluster, c
 	return nil
 }
 
+// migrateKubeConfig moves the deprecated inline kubeconfig of cluster into
+// secret and refers it by KubeConfigSecretRef
+func (r *ClusterReconciler) migrateKubeConfig(ctx context.Context, cluster *clusterv1.Cluster) error {
+	ref, err := kubeconfig.EnsureSecretForCluster(ctx, r.Client, cluster.Name, cluster.Spec.KubeConfig)
+	if err != nil {
+		log.Error("store kubeconfig of cluster %v into secret failed: %v", cluster.Name, err)
+		return err
+	}
+
+	cluster.Spec.KubeConfigSecretRef = ref
+	cluster.Spec.KubeConfig = nil
+
+	err = r.Update(ctx, cluster)
+	if err != nil {
+		log.Error("move kubeconfig of cluster %v out of cr failed: %v", cluster.Name, err)
+		return err
+	}
+
+	log.Info("kubeconfig of cluster %v moved to secret %v/%v", cluster.Name, ref.Namespace, ref.Name)
+
+	return nil
+}
+
+// deleteKubeConfigSecret deletes the kubeconfig secret of cluster managed by KubeCube,
+// the secret referred by user is kept.
+func (r *ClusterReconciler) deleteKubeConfigSecret(ctx context.Context, cluster *clusterv1.Cluster) error {
+	ref := kubeconfig.SecretRefForCluster(cluster.Name)
+	if cluster.Spec.KubeConfigSecretRef == nil || *cluster.Spec.KubeConfigSecretRef != *ref {
+		return nil
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: ref.Name, Namespace: ref.Namespace}}
+	err := r.Delete(ctx, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		clog.Error("delete kubeconfig secret of cluster %v failed: %v", cluster.Name, err)
+		return err
+	}
+
+	return nil
+}
+
 func (r *ClusterReconciler) ensureFinalizer(ctx context.Context, cluster *clusterv1.Cluster) error {
 	if !controllerutil.ContainsFinalizer(cluster, clusterFinalizer) {
 		controllerutil.AddFinalizer(cluster, clusterFinalizer)
@@ -205,6 +259,11 @@ func (r *ClusterReconciler) removeFinalizer(ctx context.Context, cluster *cluste
 			// so that it can be retried
 			return err
 		}
+
+		if err := r.deleteKubeConfigSecret(ctx, cluster); err != nil {
+			return err
+		}
+
 		// remove our finalizer from the list and update it.
 		controllerutil.RemoveFinalizer(cluster, clusterFinalizer)
 		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -231,6 +290,24 @@ func (r *ClusterReconciler) removeFinalizer(ctx context.Context, cluster *cluste
 	return nil
 }
 
+// updateConditions updates conditions of cluster and the state derived from them
+func (r *ClusterReconciler) updateConditions(ctx context.Context, cluster *clusterv1.Cluster, conditions ...metav1.Condition) error {
+	err := utils.UpdateClusterConditions(ctx, r.Client, cluster, conditions...)
+	if err != nil {
+		log.Warn("update conditions of cluster %v failed: %v", cluster.Name, err)
+	}
+	return err
+}
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+}
+
 func int32Ptr(i int32) *int32 { return &i }
 
 func boolPtr(b bool) *bool { return &b }
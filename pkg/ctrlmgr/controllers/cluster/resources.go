@@ -43,7 +43,7 @@ const (
 	configMapName        = "kubeconfig-pivot-cluster"
 	kubeConfigSecretName = "kubeconfigs"
 	tlsSecretName        = "cube-tls-secret"
-	webhookName          = "warden-validating-webhook-configuration"
+	webhookName          = constants.WardenWebhook
 	appKey               = "kubecube.io/app"
 	masterMark           = "node-role.kubernetes.io/master"
 	existsOp             = "Exists"
@@ -163,6 +163,7 @@ func makeDeployment(cluster string, isMemberCluster bool) *appsv1.Deployment {
 			"-local-cluster-kubeconfig=/etc/kubeconfigs/local-kubeconfig",
 			"-tls-cert=/etc/tls/tls.crt",
 			"-tls-key=/etc/tls/tls.key",
+			"-cube-ca-cert=/etc/tls/ca.crt",
 			fmt.Sprintf("-in-member-cluster=%v", isMemberCluster),
 			fmt.Sprintf("-cluster=%s", cluster),
 			fmt.Sprintf("-pivot-cube-host=%s", env.PivotCubeHost()),
@@ -261,6 +262,7 @@ func makeDeployment(cluster string, isMemberCluster bool) *appsv1.Deployment {
 			"-in-member-cluster=false",
 			"-tls-cert=/etc/tls/tls.crt",
 			"-tls-key=/etc/tls/tls.key",
+			"-cube-ca-cert=/etc/tls/ca.crt",
 			fmt.Sprintf("-cluster=%s", cluster),
 			fmt.Sprintf("-pivot-cube-host=%s", env.PivotCubeClusterIPSvc()),
 		}
@@ -554,7 +556,7 @@ func makeTLSSecret() *corev1.Secret {
 func makeClusterRole() *rbacv1.ClusterRole {
 	return &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "kubecube-role",
+			Name: constants.CubeClusterRole,
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -573,12 +575,12 @@ func makeClusterRole() *rbacv1.ClusterRole {
 func makeClusterRoleBinding() *rbacv1.ClusterRoleBinding {
 	return &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "kubecube-rolebinding",
+			Name: constants.CubeClusterRoleBinding,
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: constants.K8sGroupRBAC,
 			Kind:     constants.K8sKindClusterRole,
-			Name:     "kubecube-role",
+			Name:     constants.CubeClusterRole,
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -0,0 +1,151 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+// rotateRetryInterval is the interval to retry when credential rotated failed
+const rotateRetryInterval = time.Minute
+
+// needRotateCredential tells if the kubeconfig of cluster changed after
+// its internal cluster built
+func needRotateCredential(cluster clusterv1.Cluster) bool {
+	internalCluster, _ := multicluster.Interface().Get(cluster.Name)
+	if internalCluster == nil {
+		return false
+	}
+	return internalCluster.IsKubeConfigChanged(cluster)
+}
+
+// needRebuildClients tells if the client settings of cluster changed
+// after its internal cluster built
+func needRebuildClients(cluster clusterv1.Cluster) bool {
+	internalCluster, _ := multicluster.Interface().Get(cluster.Name)
+	if internalCluster == nil {
+		return false
+	}
+	return internalCluster.IsConnectionChanged(cluster)
+}
+
+// rebuildClients rebuilds the internal cluster with the new client settings
+func (r *ClusterReconciler) rebuildClients(cluster clusterv1.Cluster) (ctrl.Result, error) {
+	log.Info("client settings of cluster %v changed, try to rebuild clients", cluster.Name)
+
+	err := multicluster.Interface().Rebuild(cluster)
+	if err != nil {
+		log.Error("rebuild clients of cluster %v failed: %v", cluster.Name, err)
+		return ctrl.Result{RequeueAfter: rotateRetryInterval}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// rotateCredential rebuilds the internal cluster with the new kubeconfig
+// of cluster, and the kubeconfig used by warden will be refreshed as well.
+func (r *ClusterReconciler) rotateCredential(ctx context.Context, cluster clusterv1.Cluster) (ctrl.Result, error) {
+	log.Info("kubeconfig of cluster %v changed, try to rotate credential", cluster.Name)
+
+	if !cluster.Spec.IsMemberCluster {
+		r.pivotCluster = &cluster
+	}
+
+	rotateFailed := func(err error) (ctrl.Result, error) {
+		log.Error("rotate credential of cluster %v failed: %v", cluster.Name, err)
+		_ = r.updateConditions(ctx, &cluster, newCondition(clusterv1.ClusterCredentialsValid, metav1.ConditionFalse, clusterv1.ReasonRotateFailed, err.Error()))
+		return ctrl.Result{RequeueAfter: rotateRetryInterval}, nil
+	}
+
+	tempClient, err := tryConnectCluster(cluster)
+	if err != nil {
+		return rotateFailed(fmt.Errorf("connect to cluster with new kubeconfig failed: %v", err))
+	}
+
+	err = r.refreshKubeConfigSecret(ctx, tempClient, &cluster)
+	if err != nil {
+		return rotateFailed(err)
+	}
+
+	err = multicluster.Interface().Rebuild(cluster)
+	if err != nil {
+		return rotateFailed(err)
+	}
+
+	updateFn := func(obj *clusterv1.Cluster) {
+		now := metav1.Now()
+		obj.Status.LastCredentialRotation = &now
+		conditions := []metav1.Condition{
+			newCondition(clusterv1.ClusterAPIServerReachable, metav1.ConditionTrue, clusterv1.ReasonConnected, "handshake with cluster success"),
+			newCondition(clusterv1.ClusterCredentialsValid, metav1.ConditionTrue, clusterv1.ReasonCredentialRotated, "new kubeconfig of cluster is in use"),
+		}
+		for _, c := range conditions {
+			c.ObservedGeneration = obj.Generation
+			clusterv1.SetClusterCondition(&obj.Status, c)
+		}
+	}
+
+	err = utils.UpdateClusterStatus(ctx, r.Client, &cluster, updateFn)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("rotate credential of cluster %v success", cluster.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// refreshKubeConfigSecret updates the kubeconfig secret mounted by warden,
+// it does nothing if the secret not deployed by KubeCube
+func (r *ClusterReconciler) refreshKubeConfigSecret(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) error {
+	if r.pivotCluster == nil {
+		log.Warn("pivot cluster not ready, skip refresh kubeconfig secret of cluster %v", cluster.Name)
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, client.ObjectKey{Name: kubeConfigSecretName, Namespace: env.CubeNamespace()}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Debug("kubeconfig secret not found in cluster %v, skip refresh", cluster.Name)
+			return nil
+		}
+		return fmt.Errorf("get kubeconfig secret of cluster %v failed: %v", cluster.Name, err)
+	}
+
+	secret.Data = makeKubeConfigSecret(r.pivotCluster, cluster).Data
+
+	err = cli.Update(ctx, secret)
+	if err != nil {
+		return fmt.Errorf("refresh kubeconfig secret of cluster %v failed: %v", cluster.Name, err)
+	}
+
+	return nil
+}
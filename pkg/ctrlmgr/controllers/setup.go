@@ -25,6 +25,7 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/ctrlmgr/controllers/binding"
 	cluster "github.com/kubecube-io/kubecube/pkg/ctrlmgr/controllers/cluster"
 	"github.com/kubecube-io/kubecube/pkg/ctrlmgr/controllers/quota"
+	"github.com/kubecube-io/kubecube/pkg/ctrlmgr/controllers/template"
 	user "github.com/kubecube-io/kubecube/pkg/ctrlmgr/controllers/user"
 )
 
@@ -37,6 +38,7 @@ func init() {
 	setupFns = append(setupFns, cluster.SetupWithManager)
 	setupFns = append(setupFns, user.SetupWithManager)
 	setupFns = append(setupFns, quota.SetupWithManager)
+	setupFns = append(setupFns, template.SetupWithManager)
 	setupFns = append(setupFns, binding.SetupClusterRoleBindingReconcilerWithManager)
 	setupFns = append(setupFns, binding.SetupRoleBindingReconcilerWithManager)
 }
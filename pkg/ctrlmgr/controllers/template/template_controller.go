@@ -0,0 +1,91 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+var _ reconcile.Reconciler = &ProjectTemplateReconciler{}
+
+// ProjectTemplateReconciler annotates ProjectTemplate to be synced
+// to member clusters, where projects apply templates from
+type ProjectTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func newReconciler(mgr manager.Manager) (*ProjectTemplateReconciler, error) {
+	r := &ProjectTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}
+	return r, nil
+}
+
+//+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projecttemplates,verbs=get;list;watch;update;patch
+
+// Reconcile adds sync annotation to ProjectTemplate if absent
+func (r *ProjectTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	template := &tenantv1.ProjectTemplate{}
+	err := r.Get(ctx, req.NamespacedName, template)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if _, ok := template.Annotations[constants.SyncAnnotation]; ok {
+		return ctrl.Result{}, nil
+	}
+
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[constants.SyncAnnotation] = "1"
+	err = r.Update(ctx, template)
+	if err != nil {
+		clog.Warn("update project template %v .metadata.annotations fail, %v", template.Name, err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func SetupWithManager(mgr ctrl.Manager) error {
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&tenantv1.ProjectTemplate{}).
+		Complete(r)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func TestReconcile(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = tenantv1.AddToScheme(scheme)
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&tenantv1.ProjectTemplate{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	).Build()
+	r := &ProjectTemplateReconciler{Client: cli, Scheme: scheme}
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "default"}})
+	assert.Nil(err)
+
+	template := &tenantv1.ProjectTemplate{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "default"}, template))
+	assert.Equal("1", template.Annotations[constants.SyncAnnotation])
+
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "not-found"}})
+	assert.Nil(err)
+}
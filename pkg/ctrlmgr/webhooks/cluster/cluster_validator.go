@@ -28,8 +28,10 @@ import (
 
 	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/features"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/domain"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
 )
 
 var (
@@ -109,6 +111,16 @@ func generateValidate(cluster clusterv1.Cluster) error {
 		}
 	}
 
+	// feature gates of cluster must be known
+	if _, err := features.ForCluster(&cluster); err != nil {
+		return err
+	}
+
+	// kubeconfig secrets are only watched in namespace of KubeCube
+	if ref := cluster.Spec.KubeConfigSecretRef; ref != nil && ref.Namespace != env.CubeNamespace() {
+		return fmt.Errorf("kubeconfig secret of cluster must be in namespace %v, got %v", env.CubeNamespace(), ref.Namespace)
+	}
+
 	return nil
 }
 
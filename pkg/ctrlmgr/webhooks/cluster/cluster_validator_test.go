@@ -20,12 +20,14 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/kubecube-io/kubecube/pkg/apis"
 	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
 )
 
 func clusterTemplate(name string) clusterv1.Cluster {
@@ -102,3 +104,33 @@ func TestValidateUpdate(t *testing.T) {
 	err = clusterValidate.ValidateUpdate(nil)
 	assert.Nil(err)
 }
+
+func TestValidateFeatureGates(t *testing.T) {
+	assert := assert.New(t)
+
+	clusterValidate := NewClusterValidator(nil)
+	clusterValidate.Cluster = clusterTemplate("test-cluster")
+
+	clusterValidate.Spec.FeatureGates = map[string]bool{"VersionConversion": true}
+	assert.Nil(clusterValidate.ValidateCreate())
+
+	clusterValidate.Spec.FeatureGates = map[string]bool{"Unknown": true}
+	assert.NotNil(clusterValidate.ValidateCreate())
+
+	clusterValidate.Spec.FeatureGates = nil
+	clusterValidate.Annotations = map[string]string{"cluster.kubecube.io/feature-gates": "VersionConversion=maybe"}
+	assert.NotNil(clusterValidate.ValidateUpdate(nil))
+}
+
+func TestValidateKubeConfigSecretRef(t *testing.T) {
+	assert := assert.New(t)
+
+	clusterValidate := NewClusterValidator(nil)
+	clusterValidate.Cluster = clusterTemplate("test-cluster")
+
+	clusterValidate.Spec.KubeConfigSecretRef = &corev1.SecretReference{Name: "kubeconfig", Namespace: env.CubeNamespace()}
+	assert.Nil(clusterValidate.ValidateCreate())
+
+	clusterValidate.Spec.KubeConfigSecretRef = &corev1.SecretReference{Name: "kubeconfig", Namespace: "default"}
+	assert.NotNil(clusterValidate.ValidateCreate())
+}
@@ -27,6 +27,7 @@ import (
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	"github.com/kubecube-io/kubecube/pkg/utils/domain"
 )
@@ -49,11 +50,19 @@ func (r *Validator) ValidateCreate(project *tenantv1.Project) error {
 		return err
 	}
 
+	if err := r.validateTemplate(ctx, project); err != nil {
+		return err
+	}
+
+	if err := tenancy.ValidateNetworkPeers(project.Spec.NetworkExceptions); err != nil {
+		return err
+	}
+
 	clog.Debug("Create validate success, project info: %v", project)
 	return nil
 }
 
-func (r *Validator) ValidateUpdate(_ *tenantv1.Project, currentProject *tenantv1.Project) error {
+func (r *Validator) ValidateUpdate(oldProject *tenantv1.Project, currentProject *tenantv1.Project) error {
 
 	tenantName := currentProject.Labels[constants.TenantLabel]
 	if tenantName == "" {
@@ -72,6 +81,22 @@ func (r *Validator) ValidateUpdate(_ *tenantv1.Project, currentProject *tenantv1
 		return err
 	}
 
+	if err := r.validateTemplate(ctx, currentProject); err != nil {
+		return err
+	}
+
+	if err := tenancy.ValidateNetworkPeers(currentProject.Spec.NetworkExceptions); err != nil {
+		return err
+	}
+
+	// moving project to other tenant must not overload quota of that tenant
+	if oldProject.Labels[constants.TenantLabel] != tenantName {
+		if _, err := tenancy.PlanProjectMove(ctx, r.Client, oldProject, tenantName); err != nil {
+			clog.Info("move project %v to tenant %v denied: %v", currentProject.Name, tenantName, err)
+			return err
+		}
+	}
+
 	clog.Debug("Update validate success, project info: %v", currentProject)
 
 	return nil
@@ -96,3 +121,16 @@ func (r *Validator) ValidateDelete(project *tenantv1.Project) error {
 	}
 	return nil
 }
+
+// validateTemplate checks the project template referenced by project exists
+func (r *Validator) validateTemplate(ctx context.Context, project *tenantv1.Project) error {
+	if len(project.Spec.Template) == 0 {
+		return nil
+	}
+	template := tenantv1.ProjectTemplate{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: project.Spec.Template}, &template); err != nil {
+		clog.Info("The project template %s is not exist", project.Spec.Template)
+		return fmt.Errorf("the project template %s is not exist", project.Spec.Template)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features holds the feature gates of cube and warden. Features are
+// switched on or off globally by flag, and can be overridden per cluster by
+// annotation or spec of Cluster, so that a feature can be tried on one
+// cluster before rolling out everywhere.
+package features
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+)
+
+const (
+	// VersionConversion converts resources to the version served by member
+	// cluster when proxy requests to it
+	VersionConversion featuregate.Feature = "VersionConversion"
+
+	// ExtendAPI serves the extend apis of resources in cluster, such as
+	// external access of service and pods of workloads
+	ExtendAPI featuregate.Feature = "ExtendAPI"
+
+	// WardenAuthProxy lets warden proxy the requests of users to kube-apiserver
+	// of cluster it running in, such as requests of kubectl
+	WardenAuthProxy featuregate.Feature = "WardenAuthProxy"
+)
+
+// ClusterAnnotation is the annotation of Cluster overrides feature gates of
+// cluster, the value is like "VersionConversion=true,ExtendAPI=false". The
+// feature gates in spec of Cluster take precedence over the annotation.
+const ClusterAnnotation = "cluster.kubecube.io/feature-gates"
+
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	VersionConversion: {Default: false, PreRelease: featuregate.Alpha},
+	ExtendAPI:         {Default: true, PreRelease: featuregate.Beta},
+	WardenAuthProxy:   {Default: true, PreRelease: featuregate.Beta},
+}
+
+var (
+	// DefaultMutableFeatureGate is the global feature gates set by flag
+	DefaultMutableFeatureGate featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+	// DefaultFeatureGate is the read only view of DefaultMutableFeatureGate
+	DefaultFeatureGate featuregate.FeatureGate = DefaultMutableFeatureGate
+)
+
+func init() {
+	utilruntime.Must(DefaultMutableFeatureGate.Add(defaultFeatureGates))
+}
+
+// Config is the config of global feature gates
+type Config struct {
+	// FeatureGates is like "VersionConversion=true,ExtendAPI=false"
+	FeatureGates string
+}
+
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if err := DefaultFeatureGate.DeepCopy().Set(c.FeatureGates); err != nil {
+		errs = append(errs, fmt.Errorf("invalid feature gates: %v", err))
+	}
+
+	return errs
+}
+
+// InitFeatureGatesWithOpts sets the global feature gates by config
+func InitFeatureGatesWithOpts(c *Config) error {
+	if c == nil {
+		return nil
+	}
+	return DefaultMutableFeatureGate.Set(c.FeatureGates)
+}
+
+// ForCluster returns the feature gates of cluster, that are the global feature
+// gates overridden by annotation and then by spec of cluster
+func ForCluster(cluster *clusterv1.Cluster) (featuregate.FeatureGate, error) {
+	annotation := strings.TrimSpace(cluster.Annotations[ClusterAnnotation])
+	if len(annotation) == 0 && len(cluster.Spec.FeatureGates) == 0 {
+		return DefaultFeatureGate, nil
+	}
+
+	gates := DefaultFeatureGate.DeepCopy()
+	if err := gates.Set(annotation); err != nil {
+		return nil, fmt.Errorf("invalid feature gates in annotation of cluster %v: %v", cluster.Name, err)
+	}
+	if err := gates.SetFromMap(cluster.Spec.FeatureGates); err != nil {
+		return nil, fmt.Errorf("invalid feature gates in spec of cluster %v: %v", cluster.Name, err)
+	}
+
+	return gates, nil
+}
+
+// EnabledForCluster tells if feature is enabled for cluster. The global feature
+// gates are used if cluster not found or its overrides are invalid.
+func EnabledForCluster(ctx context.Context, cli client.Reader, cluster string, feature featuregate.Feature) bool {
+	c := &clusterv1.Cluster{}
+	err := cli.Get(ctx, types.NamespacedName{Name: cluster}, c)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			clog.Warn("get cluster %v failed, use global feature gates: %v", cluster, err)
+		}
+		return DefaultFeatureGate.Enabled(feature)
+	}
+
+	gates, err := ForCluster(c)
+	if err != nil {
+		clog.Warn("%v, use global feature gates", err)
+		return DefaultFeatureGate.Enabled(feature)
+	}
+
+	return gates.Enabled(feature)
+}
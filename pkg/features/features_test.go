@@ -0,0 +1,85 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+func TestForCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+	gates, err := ForCluster(cluster)
+	assert.Nil(err)
+	assert.False(gates.Enabled(VersionConversion))
+	assert.True(gates.Enabled(ExtendAPI))
+
+	// spec takes precedence over annotation
+	cluster.Annotations = map[string]string{ClusterAnnotation: "VersionConversion=true,ExtendAPI=false"}
+	cluster.Spec.FeatureGates = map[string]bool{string(ExtendAPI): true}
+	gates, err = ForCluster(cluster)
+	assert.Nil(err)
+	assert.True(gates.Enabled(VersionConversion))
+	assert.True(gates.Enabled(ExtendAPI))
+
+	// global gates are not changed by overrides of cluster
+	assert.False(DefaultFeatureGate.Enabled(VersionConversion))
+
+	cluster.Spec.FeatureGates = map[string]bool{"Unknown": true}
+	_, err = ForCluster(cluster)
+	assert.NotNil(err)
+}
+
+func TestEnabledForCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary"},
+			Spec:       clusterv1.ClusterSpec{FeatureGates: map[string]bool{string(VersionConversion): true}},
+		},
+		&clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Annotations: map[string]string{ClusterAnnotation: "Unknown=true"}},
+		},
+	).Build()
+
+	ctx := context.Background()
+	assert.True(EnabledForCluster(ctx, cli, "canary", VersionConversion))
+	assert.False(EnabledForCluster(ctx, cli, "invalid", VersionConversion))
+	assert.False(EnabledForCluster(ctx, cli, "not-found", VersionConversion))
+	assert.True(EnabledForCluster(ctx, cli, "not-found", WardenAuthProxy))
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Empty((&Config{}).Validate())
+	assert.Empty((&Config{FeatureGates: "VersionConversion=true"}).Validate())
+	assert.NotEmpty((&Config{FeatureGates: "Unknown=true"}).Validate())
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the prometheus metrics of cube and warden. Metrics are
+// registered into the registry of controller-runtime, so that the metrics of
+// controllers, work queues and rest clients are exported together.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "kubecube"
+
+	// Path is the path metrics served on
+	Path = "/metrics"
+
+	// unknownRoute is the route label of requests match no route
+	unknownRoute = "unknown"
+)
+
+// Registry is the registry of metrics exported by Handler
+var Registry = ctrlmetrics.Registry
+
+var (
+	// RequestsTotal counts http requests by route and cluster
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of http requests by component, route, method, code and cluster.",
+	}, []string{"component", "route", "method", "code", "cluster"})
+
+	// RequestDuration observes latencies of http requests by route and cluster
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by component, route, method and cluster.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"component", "route", "method", "cluster"})
+
+	// SyncReconcileTotal counts the reconciles of sync manager by resource kind
+	SyncReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_reconcile_total",
+		Help:      "Number of reconciles of sync manager by resource kind.",
+	}, []string{"kind"})
+
+	// SyncReconcileErrors counts the failed reconciles of sync manager by resource kind
+	SyncReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_reconcile_errors_total",
+		Help:      "Number of failed reconciles of sync manager by resource kind.",
+	}, []string{"kind"})
+
+	// HotplugResultsTotal counts the deploy results of hotplug components
+	HotplugResultsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "hotplug_results_total",
+		Help:      "Number of deploy results of hotplug components by cluster, component and result.",
+	}, []string{"cluster", "component", "result"})
+
+	// HotplugSucceeded tells if the last deploy of hotplug component succeeded
+	HotplugSucceeded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "hotplug_succeeded",
+		Help:      "Whether the last deploy of hotplug component succeeded, 1 for success and 0 for fail.",
+	}, []string{"cluster", "component"})
+)
+
+func init() {
+	Registry.MustRegister(
+		RequestsTotal,
+		RequestDuration,
+		SyncReconcileTotal,
+		SyncReconcileErrors,
+		HotplugResultsTotal,
+		HotplugSucceeded,
+	)
+}
+
+// Handler serves the metrics in Registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a finished http request
+func ObserveRequest(component, route, method string, code int, cluster string, elapsed time.Duration) {
+	if len(route) == 0 {
+		route = unknownRoute
+	}
+	RequestsTotal.WithLabelValues(component, route, method, strconv.Itoa(code), cluster).Inc()
+	RequestDuration.WithLabelValues(component, route, method, cluster).Observe(elapsed.Seconds())
+}
+
+// InstrumentHandler records requests served by handler with a fixed route and cluster
+func InstrumentHandler(component, route, cluster string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+		handler.ServeHTTP(rw, r)
+
+		ObserveRequest(component, route, r.Method, rw.code, cluster, time.Since(start))
+	})
+}
+
+// ObserveSyncReconcile records a reconcile of sync manager
+func ObserveSyncReconcile(kind string, err error) {
+	SyncReconcileTotal.WithLabelValues(kind).Inc()
+	if err != nil {
+		SyncReconcileErrors.WithLabelValues(kind).Inc()
+	}
+}
+
+// ObserveHotplugResult records the deploy result of hotplug component
+func ObserveHotplugResult(cluster, component string, succeeded bool) {
+	result, value := "fail", 0.0
+	if succeeded {
+		result, value = "success", 1.0
+	}
+	HotplugResultsTotal.WithLabelValues(cluster, component, result).Inc()
+	HotplugSucceeded.WithLabelValues(cluster, component).Set(value)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := InstrumentHandler("test", "/", "member-1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hijack" {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/not-found")
+	assert.Nil(err)
+	_ = resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	// hijacked connection is recorded as switching protocols
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.Nil(err)
+	_, _ = conn.Write([]byte("GET /hijack HTTP/1.1\r\nHost: test\r\n\r\n"))
+	_, _ = bufio.NewReader(conn).ReadString('\n')
+	_ = conn.Close()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	body := rec.Body.String()
+
+	assert.True(strings.Contains(body, `kubecube_http_requests_total{cluster="member-1",code="404",component="test",method="GET",route="/"} 1`), body)
+	assert.True(strings.Contains(body, `kubecube_http_requests_total{cluster="member-1",code="101",component="test",method="GET",route="/"} 1`), body)
+	assert.True(strings.Contains(body, `kubecube_http_request_duration_seconds_count{cluster="member-1",component="test",method="GET",route="/"} 2`), body)
+}
+
+func TestObserve(t *testing.T) {
+	assert := assert.New(t)
+
+	ObserveSyncReconcile("Tenant", nil)
+	ObserveSyncReconcile("Tenant", fmt.Errorf("conflict"))
+	ObserveHotplugResult("member-1", "logseer", true)
+	ObserveHotplugResult("member-1", "logseer", false)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	body := rec.Body.String()
+
+	for _, line := range []string{
+		`kubecube_sync_reconcile_total{kind="Tenant"} 2`,
+		`kubecube_sync_reconcile_errors_total{kind="Tenant"} 1`,
+		`kubecube_hotplug_results_total{cluster="member-1",component="logseer",result="fail"} 1`,
+		`kubecube_hotplug_results_total{cluster="member-1",component="logseer",result="success"} 1`,
+		`kubecube_hotplug_succeeded{cluster="member-1",component="logseer"} 0`,
+	} {
+		assert.True(strings.Contains(body, line), line)
+	}
+}
@@ -0,0 +1,430 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package catalog keeps the discovery results of every cluster in multi cluster
+// manager. Discovery is cached per cluster and refreshed periodically or when
+// custom resource definitions of cluster changed, so that the version converter
+// and apis read the cache instead of discovering cluster on each request.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+const (
+	defaultRefreshInterval = 10 * time.Minute
+
+	// crdChangedDelay merges the refreshes triggered by a burst of crd changes
+	crdChangedDelay = 2 * time.Second
+
+	// watchTimeout bounds the wait for crd informer of cluster synced
+	watchTimeout = 30 * time.Second
+)
+
+// Config is the config of api catalog
+type Config struct {
+	// RefreshInterval is the interval to refresh discovery of all clusters,
+	// cluster is refreshed in time when its crds changed
+	RefreshInterval time.Duration
+}
+
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if c.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("catalog refresh interval must not be negative"))
+	}
+
+	return errs
+}
+
+// APIGroup is a group served by cluster
+type APIGroup struct {
+	Name             string   `json:"name"`
+	Versions         []string `json:"versions"`
+	PreferredVersion string   `json:"preferredVersion"`
+}
+
+// APIResource is a resource served by cluster, subresources are not included
+type APIResource struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Resource   string   `json:"resource"`
+	Kind       string   `json:"kind"`
+	Namespaced bool     `json:"namespaced"`
+	Verbs      []string `json:"verbs"`
+	ShortNames []string `json:"shortNames,omitempty"`
+
+	// Preferred tells if version is the preferred version of group
+	Preferred bool `json:"preferred"`
+}
+
+// GroupVersionResource returns the gvr of resource
+func (r APIResource) GroupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+// ClusterAPIs is the discovery snapshot of a cluster
+type ClusterAPIs struct {
+	Cluster           string        `json:"cluster"`
+	KubernetesVersion string        `json:"kubernetesVersion"`
+	Groups            []APIGroup    `json:"groups"`
+	Resources         []APIResource `json:"resources"`
+
+	// RefreshTime is the time of last successful refresh
+	RefreshTime time.Time `json:"refreshTime"`
+
+	// Error is the error of last refresh, the snapshot is stale or partial if not empty
+	Error string `json:"error,omitempty"`
+}
+
+// clusterEntry is the cached discovery of a cluster
+type clusterEntry struct {
+	// cluster is the client set which discovery belongs to, entry
+	// is renewed once the cluster reconnected with new client set
+	cluster   *multicluster.InternalCluster
+	discovery discovery.CachedDiscoveryInterface
+	apis      *ClusterAPIs
+	watched   bool
+}
+
+// Catalog caches the discovery of clusters
+type Catalog struct {
+	sync.RWMutex
+
+	interval time.Duration
+	manager  multicluster.Manager
+	entries  map[string]*clusterEntry
+
+	// queue holds the clusters whose crds changed
+	queue workqueue.DelayingInterface
+}
+
+// catalog is the global api catalog
+var catalog = newCatalog(defaultRefreshInterval, nil)
+
+func newCatalog(interval time.Duration, manager multicluster.Manager) *Catalog {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &Catalog{
+		interval: interval,
+		manager:  manager,
+		entries:  make(map[string]*clusterEntry),
+		queue:    workqueue.NewNamedDelayingQueue("api-catalog"),
+	}
+}
+
+// NewCatalogWithOpts initializes the global catalog with given config
+func NewCatalogWithOpts(opts *Config) *Catalog {
+	if opts != nil && opts.RefreshInterval > 0 {
+		catalog.interval = opts.RefreshInterval
+	}
+	return catalog
+}
+
+// Interface the entry for api catalog of clusters
+func Interface() *Catalog {
+	return catalog
+}
+
+func (c *Catalog) Initialize() error {
+	return nil
+}
+
+// Run refreshes the catalog periodically and on crd changes util received stop signal
+func (c *Catalog) Run(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		c.queue.ShutDown()
+	}()
+
+	go wait.Until(c.processNextCluster, time.Second, stop)
+
+	wait.Until(func() {
+		c.Refresh(context.Background())
+	}, c.interval, stop)
+}
+
+func (c *Catalog) processNextCluster() {
+	for {
+		item, shutdown := c.queue.Get()
+		if shutdown {
+			return
+		}
+
+		cluster := item.(string)
+		clog.Debug("crds of cluster %v changed, refresh api catalog", cluster)
+		c.refreshCluster(context.Background(), c.managerOrDefault(), cluster)
+
+		c.queue.Done(item)
+	}
+}
+
+func (c *Catalog) managerOrDefault() multicluster.Manager {
+	if c.manager == nil {
+		return multicluster.Interface()
+	}
+	return c.manager
+}
+
+// List returns the api catalog of all clusters sorted by name
+func (c *Catalog) List() []ClusterAPIs {
+	c.RLock()
+	defer c.RUnlock()
+
+	items := make([]ClusterAPIs, 0, len(c.entries))
+	for _, e := range c.entries {
+		if e.apis != nil {
+			items = append(items, *e.apis)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Cluster < items[j].Cluster
+	})
+
+	return items
+}
+
+// Get returns the api catalog of cluster, false if not discovered yet
+func (c *Catalog) Get(cluster string) (ClusterAPIs, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	e, ok := c.entries[cluster]
+	if !ok || e.apis == nil {
+		return ClusterAPIs{}, false
+	}
+
+	return *e.apis, true
+}
+
+// Discovery returns the cached discovery of cluster, it is invalidated
+// when the catalog of cluster refreshed
+func (c *Catalog) Discovery(cluster string) (discovery.DiscoveryInterface, error) {
+	internalCluster, err := c.managerOrDefault().Get(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.entryOf(cluster, internalCluster).discovery, nil
+}
+
+// entryOf returns the entry of cluster, a new entry is made if cluster
+// not cached or reconnected with new client set
+func (c *Catalog) entryOf(cluster string, internalCluster *multicluster.InternalCluster) *clusterEntry {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[cluster]
+	if ok && e.cluster == internalCluster {
+		return e
+	}
+
+	e = &clusterEntry{
+		cluster:   internalCluster,
+		discovery: memory.NewMemCacheClient(internalCluster.Client.Discovery()),
+	}
+	c.entries[cluster] = e
+
+	return e
+}
+
+// Refresh discovers all clusters concurrently
+func (c *Catalog) Refresh(ctx context.Context) {
+	m := c.managerOrDefault()
+
+	clusters := m.FuzzyCopy()
+	delete(clusters, constants.LocalCluster)
+
+	wg := sync.WaitGroup{}
+	for name := range clusters {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			c.refreshCluster(ctx, m, name)
+		}(name)
+	}
+	wg.Wait()
+
+	// drop the catalog of clusters removed
+	c.Lock()
+	for name := range c.entries {
+		if _, ok := clusters[name]; !ok {
+			delete(c.entries, name)
+		}
+	}
+	c.Unlock()
+}
+
+// RefreshCluster discovers the cluster at once
+func (c *Catalog) RefreshCluster(ctx context.Context, cluster string) {
+	c.refreshCluster(ctx, c.managerOrDefault(), cluster)
+}
+
+func (c *Catalog) refreshCluster(ctx context.Context, m multicluster.Manager, cluster string) {
+	internalCluster, err := m.Get(cluster)
+	if err != nil {
+		clog.Warn("refresh api catalog of cluster %v failed: %v", cluster, err)
+		return
+	}
+
+	e := c.entryOf(cluster, internalCluster)
+	c.watchCRDs(ctx, cluster, e)
+
+	e.discovery.Invalidate()
+	apis, err := Discover(e.discovery, cluster)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err != nil {
+		clog.Warn("refresh api catalog of cluster %v failed: %v", cluster, err)
+		if apis == nil {
+			// keep the last snapshot and mark it stale
+			apis = e.apis
+			if apis == nil {
+				apis = &ClusterAPIs{Cluster: cluster}
+			}
+		}
+		apis.Error = err.Error()
+	}
+
+	e.apis = apis
+}
+
+// watchCRDs refreshes the catalog of cluster once its crds changed
+func (c *Catalog) watchCRDs(ctx context.Context, cluster string, e *clusterEntry) {
+	c.RLock()
+	watched := e.watched
+	c.RUnlock()
+	if watched {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, watchTimeout)
+	defer cancel()
+
+	informer, err := e.cluster.Client.Cache().GetInformer(ctx, &apiextensionsv1.CustomResourceDefinition{})
+	if err != nil {
+		clog.Warn("watch crds of cluster %v failed: %v", cluster, err)
+		return
+	}
+
+	enqueue := func() { c.queue.AddAfter(cluster, crdChangedDelay) }
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { enqueue() },
+		UpdateFunc: func(oldObj, newObj interface{}) { enqueue() },
+		DeleteFunc: func(obj interface{}) { enqueue() },
+	})
+
+	c.Lock()
+	e.watched = true
+	c.Unlock()
+}
+
+// Discover makes the api catalog of cluster by given discovery. The partial
+// result is returned with error if some groups failed to be discovered.
+func Discover(d discovery.DiscoveryInterface, cluster string) (*ClusterAPIs, error) {
+	apis := &ClusterAPIs{
+		Cluster:   cluster,
+		Groups:    []APIGroup{},
+		Resources: []APIResource{},
+	}
+
+	v, err := d.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("discover version failed: %v", err)
+	}
+	apis.KubernetesVersion = v.GitVersion
+
+	groups, resourceLists, err := d.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("discover resources failed: %v", err)
+	}
+
+	preferred := make(map[string]string, len(groups))
+	for _, g := range groups {
+		group := APIGroup{Name: g.Name, PreferredVersion: g.PreferredVersion.Version, Versions: []string{}}
+		for _, v := range g.Versions {
+			group.Versions = append(group.Versions, v.Version)
+		}
+		preferred[g.Name] = g.PreferredVersion.Version
+		apis.Groups = append(apis.Groups, group)
+	}
+
+	for _, list := range resourceLists {
+		gv, parseErr := schema.ParseGroupVersion(list.GroupVersion)
+		if parseErr != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			// subresources such as pods/log go with their parents
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			apis.Resources = append(apis.Resources, APIResource{
+				Group:      gv.Group,
+				Version:    gv.Version,
+				Resource:   r.Name,
+				Kind:       r.Kind,
+				Namespaced: r.Namespaced,
+				Verbs:      sets.NewString(r.Verbs...).List(),
+				ShortNames: r.ShortNames,
+				Preferred:  preferred[gv.Group] == gv.Version,
+			})
+		}
+	}
+
+	sort.Slice(apis.Groups, func(i, j int) bool {
+		return apis.Groups[i].Name < apis.Groups[j].Name
+	})
+	sort.Slice(apis.Resources, func(i, j int) bool {
+		return lessResource(apis.Resources[i], apis.Resources[j])
+	})
+	apis.RefreshTime = time.Now()
+
+	return apis, err
+}
+
+func lessResource(a, b APIResource) bool {
+	if a.Group != b.Group {
+		return a.Group < b.Group
+	}
+	if a.Version != b.Version {
+		return a.Version < b.Version
+	}
+	return a.Resource < b.Resource
+}
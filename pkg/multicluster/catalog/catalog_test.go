@@ -0,0 +1,148 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+var (
+	deployments = metav1.APIResource{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"list", "get", "create"}}
+	scale       = metav1.APIResource{Name: "deployments/scale", Kind: "Scale", Namespaced: true, Verbs: []string{"get"}}
+	pods        = metav1.APIResource{Name: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"get", "list"}}
+	foos        = metav1.APIResource{Name: "foos", Kind: "Foo", Namespaced: false, Verbs: []string{"get"}}
+)
+
+func newClusterClient(resources ...*metav1.APIResourceList) *multicluster.InternalCluster {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	cli := fake.NewFakeClients(&fake.Options{Scheme: scheme})
+	cli.ClientSet().Discovery().(*fakediscovery.FakeDiscovery).Resources = resources
+
+	return &multicluster.InternalCluster{Client: cli}
+}
+
+func TestDiscover(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClusterClient(
+		&metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{pods}},
+		&metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{deployments, scale}},
+	)
+
+	apis, err := Discover(c.Client.Discovery(), "member-1")
+	assert.Nil(err)
+	assert.Equal("member-1", apis.Cluster)
+	assert.Len(apis.Groups, 2)
+
+	// subresources are omitted and verbs are sorted
+	assert.Equal([]APIResource{
+		{Group: "", Version: "v1", Resource: "pods", Kind: "Pod", Namespaced: true, Verbs: []string{"get", "list"}, Preferred: true},
+		{Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", Namespaced: true, Verbs: []string{"create", "get", "list"}, Preferred: true},
+	}, apis.Resources)
+}
+
+func TestRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	member1 := newClusterClient(&metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{deployments}})
+	member2 := newClusterClient(
+		&metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{deployments}},
+		&metav1.APIResourceList{GroupVersion: "example.io/v1", APIResources: []metav1.APIResource{foos}},
+	)
+
+	m := &multicluster.FakerManagerImpl{Clusters: make(map[string]*multicluster.InternalCluster)}
+	_ = m.Add(constants.LocalCluster, newClusterClient())
+	_ = m.Add("member-1", member1)
+	_ = m.Add("member-2", member2)
+
+	c := newCatalog(0, m)
+	c.Refresh(context.Background())
+
+	items := c.List()
+	assert.Len(items, 2)
+	assert.Len(items[0].Resources, 1)
+	assert.Len(items[1].Resources, 2)
+
+	// cached discovery is refreshed together with catalog
+	d, err := c.Discovery("member-1")
+	assert.Nil(err)
+	member1.Client.ClientSet().Discovery().(*fakediscovery.FakeDiscovery).Resources = append(
+		member1.Client.ClientSet().Discovery().(*fakediscovery.FakeDiscovery).Resources,
+		&metav1.APIResourceList{GroupVersion: "example.io/v1", APIResources: []metav1.APIResource{foos}},
+	)
+	_, resources, err := d.ServerGroupsAndResources()
+	assert.Nil(err)
+	assert.Len(resources, 1)
+
+	c.RefreshCluster(context.Background(), "member-1")
+	_, resources, err = d.ServerGroupsAndResources()
+	assert.Nil(err)
+	assert.Len(resources, 2)
+	apis, ok := c.Get("member-1")
+	assert.True(ok)
+	assert.Len(apis.Resources, 2)
+
+	// catalog of removed cluster is dropped
+	_ = m.Del("member-2")
+	c.Refresh(context.Background())
+	_, ok = c.Get("member-2")
+	assert.False(ok)
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	deploymentsOf := func(verbs ...string) APIResource {
+		return APIResource{Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", Namespaced: true, Verbs: verbs}
+	}
+	foo := APIResource{Group: "example.io", Version: "v1", Resource: "foos", Kind: "Foo", Verbs: []string{"get"}}
+
+	items := []ClusterAPIs{
+		{Cluster: "member-1", Resources: []APIResource{deploymentsOf("get", "list"), foo}},
+		{Cluster: "member-2", Resources: []APIResource{deploymentsOf("get", "list")}},
+		{Cluster: "member-3", Resources: []APIResource{deploymentsOf("get")}},
+	}
+
+	diffs := Diff(items)
+	assert.Len(diffs, 2)
+
+	assert.Equal("deployments", diffs[0].Resource)
+	assert.Empty(diffs[0].MissingClusters)
+	assert.Equal([]string{"get"}, diffs[0].Verbs["member-3"])
+
+	assert.Equal("foos", diffs[1].Resource)
+	assert.Equal([]string{"member-1"}, diffs[1].Clusters)
+	assert.Equal([]string{"member-2", "member-3"}, diffs[1].MissingClusters)
+	assert.Nil(diffs[1].Verbs)
+
+	// clusters serve the same apis make no diff
+	assert.Empty(Diff(Filter(items, []string{"member-1"})))
+	assert.Empty(Diff(Filter(items, []string{"member-2"})))
+}
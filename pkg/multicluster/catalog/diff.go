@@ -0,0 +1,137 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package catalog
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// APIDiff is a resource which is not served the same way by all clusters
+type APIDiff struct {
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+	Kind     string `json:"kind"`
+
+	// Clusters serve the resource
+	Clusters []string `json:"clusters"`
+
+	// MissingClusters do not serve the resource
+	MissingClusters []string `json:"missingClusters"`
+
+	// Verbs of resource in each cluster, given only if verbs or
+	// scope of resource differ between clusters
+	Verbs map[string][]string `json:"verbs,omitempty"`
+
+	// Namespaced of resource in each cluster, given only if verbs
+	// or scope of resource differ between clusters
+	Namespaced map[string]bool `json:"namespaced,omitempty"`
+}
+
+// Diff compares the resources served by clusters, resources served the
+// same way by all clusters are omitted
+func Diff(items []ClusterAPIs) []APIDiff {
+	clusters := make([]string, 0, len(items))
+	served := make(map[schema.GroupVersionResource]map[string]APIResource)
+	for _, item := range items {
+		clusters = append(clusters, item.Cluster)
+		for _, r := range item.Resources {
+			key := r.GroupVersionResource()
+			if served[key] == nil {
+				served[key] = make(map[string]APIResource)
+			}
+			served[key][item.Cluster] = r
+		}
+	}
+
+	diffs := []APIDiff{}
+	for key, byCluster := range served {
+		d := APIDiff{
+			Group:           key.Group,
+			Version:         key.Version,
+			Resource:        key.Resource,
+			Clusters:        []string{},
+			MissingClusters: []string{},
+		}
+
+		var first *APIResource
+		consistent := true
+		for _, cluster := range clusters {
+			r, ok := byCluster[cluster]
+			if !ok {
+				d.MissingClusters = append(d.MissingClusters, cluster)
+				continue
+			}
+			d.Clusters = append(d.Clusters, cluster)
+			d.Kind = r.Kind
+			if first == nil {
+				first = &r
+				continue
+			}
+			if first.Namespaced != r.Namespaced || !reflect.DeepEqual(first.Verbs, r.Verbs) {
+				consistent = false
+			}
+		}
+
+		if len(d.MissingClusters) == 0 && consistent {
+			continue
+		}
+
+		if !consistent {
+			d.Verbs = make(map[string][]string, len(d.Clusters))
+			d.Namespaced = make(map[string]bool, len(d.Clusters))
+			for _, cluster := range d.Clusters {
+				d.Verbs[cluster] = byCluster[cluster].Verbs
+				d.Namespaced[cluster] = byCluster[cluster].Namespaced
+			}
+		}
+
+		sort.Strings(d.Clusters)
+		sort.Strings(d.MissingClusters)
+		diffs = append(diffs, d)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return lessResource(
+			APIResource{Group: diffs[i].Group, Version: diffs[i].Version, Resource: diffs[i].Resource},
+			APIResource{Group: diffs[j].Group, Version: diffs[j].Version, Resource: diffs[j].Resource},
+		)
+	})
+
+	return diffs
+}
+
+// Filter returns the items of clusters given, all items are returned if clusters is empty
+func Filter(items []ClusterAPIs, clusters []string) []ClusterAPIs {
+	if len(clusters) == 0 {
+		return items
+	}
+
+	wanted := sets.NewString(clusters...)
+	filtered := make([]ClusterAPIs, 0, len(clusters))
+	for _, item := range items {
+		if wanted.Has(item.Cluster) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
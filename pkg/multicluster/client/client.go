@@ -19,6 +19,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"time"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -76,9 +77,22 @@ type InternalClient struct {
 	restMapper meta.RESTMapper
 }
 
+// Options tunes the client built by NewClientForWithOptions
+type Options struct {
+	// ResyncPeriod is the resync period of informers in cache,
+	// the default of controller-runtime is used if zero
+	ResyncPeriod time.Duration
+}
+
 // NewClientFor generate client by config
-// todo: with options
 func NewClientFor(ctx context.Context, cfg *rest.Config) (Client, error) {
+	return NewClientForWithOptions(ctx, cfg, Options{})
+}
+
+// NewClientForWithOptions generate client by config and options. QPS, burst and
+// timeout of config are honored by all clients except that the cache ignores
+// timeout, cause watch of informers is long-running.
+func NewClientForWithOptions(ctx context.Context, cfg *rest.Config, opts Options) (Client, error) {
 	var err error
 	c := new(InternalClient)
 
@@ -88,7 +102,14 @@ func NewClientFor(ctx context.Context, cfg *rest.Config) (Client, error) {
 		return nil, fmt.Errorf("new k8s client failed: %v", err)
 	}
 
-	c.cache, err = cache.New(cfg, cache.Options{Scheme: scheme})
+	cacheCfg := rest.CopyConfig(cfg)
+	cacheCfg.Timeout = 0
+	cacheOpts := cache.Options{Scheme: scheme}
+	if opts.ResyncPeriod > 0 {
+		cacheOpts.Resync = &opts.ResyncPeriod
+	}
+
+	c.cache, err = cache.New(cacheCfg, cacheOpts)
 	if err != nil {
 		return nil, fmt.Errorf("new k8s cache failed: %v", err)
 	}
@@ -72,6 +72,7 @@ func NewFakeClients(opts *Options) mgrclient.Client {
 	cli := clientFake.NewClientBuilder().WithScheme(opts.Scheme).WithObjects(opts.Objs...).WithRuntimeObjects(opts.ClientRuntimeObjs...).WithLists(opts.Lists...).Build()
 	c.client = cli
 	c.rawClientSet = clientSetFake.NewSimpleClientset(opts.ClientSetRuntimeObjs...)
+	c.discovery = c.rawClientSet.Discovery()
 	c.metrics = metricsFake.NewSimpleClientset(opts.MetricsRuntimeObjs...)
 	c.cache = &cacheFake.FakeClient{
 		Client: cli,
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// ClusterSet is a group of clusters given by names or label selector
+// over cluster objects, such as all prod clusters in region east. It is
+// used by yamldeploy and the /clusters api, hotplug selects clusters by
+// its own ClusterSelector. Quota is not assigned by cluster set: each
+// CubeResourceQuota is bound to exactly one cluster by ClusterLabel and
+// checked against the capacity of that cluster, so quotas of clusters in
+// a set are still created one by one.
+type ClusterSet struct {
+	// Clusters are names of clusters in set
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+
+	// Selector selects clusters by labels of cluster
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// UnavailableClustersError means some clusters matched but they are not
+// available, such as clusters not connected yet or abnormal. The available
+// clusters are returned along with it.
+type UnavailableClustersError struct {
+	// Clusters are names of clusters matched but not available
+	Clusters []string
+}
+
+func (e *UnavailableClustersError) Error() string {
+	return fmt.Sprintf("clusters matched but not available: %v", strings.Join(e.Clusters, ", "))
+}
+
+// UnavailableClustersOf returns the names of clusters matched but not
+// available, false if err is not caused by unavailable clusters
+func UnavailableClustersOf(err error) ([]string, bool) {
+	e, ok := err.(*UnavailableClustersError)
+	if !ok {
+		return nil, false
+	}
+	return e.Clusters, true
+}
+
+// IsEmpty tells if the cluster set selects nothing
+func (s ClusterSet) IsEmpty() bool {
+	return len(s.Clusters) == 0 && s.Selector == nil
+}
+
+// Names resolves the cluster set to sorted names of active clusters, error
+// returned if any cluster given by name not found. Clusters selected but not
+// available are reported by UnavailableClustersError with the active ones.
+func (s ClusterSet) Names(ctx context.Context, m Manager) ([]string, error) {
+	set := make(map[string]struct{})
+	var unavailable []string
+
+	for _, name := range s.Clusters {
+		if _, err := m.Get(name); err != nil {
+			return nil, err
+		}
+		set[name] = struct{}{}
+	}
+
+	if s.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(s.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("cluster selector invalid: %v", err)
+		}
+		names, err := m.ListClustersNameBySelector(ctx, selector)
+		if err != nil {
+			clusters, ok := UnavailableClustersOf(err)
+			if !ok {
+				return nil, err
+			}
+			unavailable = clusters
+		}
+		for _, name := range names {
+			set[name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(unavailable) > 0 {
+		return names, &UnavailableClustersError{Clusters: unavailable}
+	}
+	return names, nil
+}
+
+// listClustersBySelector lists clusters from local cluster with the
+// latest labels, cause the raw cluster of internal cluster may stale.
+// Clusters matched but not available are reported by UnavailableClustersError.
+func listClustersBySelector(ctx context.Context, m Manager, selector labels.Selector) ([]*InternalCluster, error) {
+	localCluster, err := m.Get(constants.LocalCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterList := clusterv1.ClusterList{}
+	err = localCluster.Client.Cache().List(ctx, &clusterList, &client.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		clusters    []*InternalCluster
+		unavailable []string
+	)
+	for _, cluster := range clusterList.Items {
+		c, err := m.Get(cluster.Name)
+		if err != nil {
+			// cluster not ready yet or abnormal
+			unavailable = append(unavailable, cluster.Name)
+			continue
+		}
+		clusters = append(clusters, c)
+	}
+
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return clusters, &UnavailableClustersError{Clusters: unavailable}
+	}
+	return clusters, nil
+}
+
+// listClustersNameBySelector lists names of clusters by selector
+func listClustersNameBySelector(ctx context.Context, m Manager, selector labels.Selector) ([]string, error) {
+	clusters, err := listClustersBySelector(ctx, m, selector)
+	if _, ok := UnavailableClustersOf(err); err != nil && !ok {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+
+	return names, err
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubecube-io/kubecube/pkg/apis"
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+)
+
+var _ = Describe("Cluster set", func() {
+	newCluster := func(name string, l map[string]string) *clusterv1.Cluster {
+		return &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: l}}
+	}
+
+	BeforeEach(func(done Done) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(apis.AddToScheme(scheme)).To(Succeed())
+		opts := &fake.Options{
+			Scheme: scheme,
+			Objs: []client.Object{
+				newCluster("east-prod", map[string]string{"env": "prod", "region": "east"}),
+				newCluster("west-prod", map[string]string{"env": "prod", "region": "west"}),
+				newCluster("east-dev", map[string]string{"env": "dev", "region": "east"}),
+				newCluster("east-prod-2", map[string]string{"env": "prod", "region": "east"}),
+			},
+		}
+		multicluster.InitFakeMultiClusterMgrWithOpts(opts)
+
+		// east-prod-2 is not ready yet
+		for _, name := range []string{"east-prod", "west-prod", "east-dev"} {
+			c := new(multicluster.InternalCluster)
+			c.Name = name
+			c.Client = fake.NewFakeClients(opts)
+			Expect(multicluster.Interface().Add(name, c)).To(Succeed())
+		}
+		close(done)
+	})
+
+	It("should list ready clusters by selector", func() {
+		selector, err := labels.Parse("env=prod,region=east")
+		Expect(err).To(BeNil())
+		names, err := multicluster.Interface().ListClustersNameBySelector(context.Background(), selector)
+		Expect(names).To(ConsistOf("east-prod"))
+
+		// matched but not ready cluster is reported
+		unavailable, ok := multicluster.UnavailableClustersOf(err)
+		Expect(ok).To(BeTrue())
+		Expect(unavailable).To(Equal([]string{"east-prod-2"}))
+
+		selector, err = labels.Parse("env=dev")
+		Expect(err).To(BeNil())
+		names, err = multicluster.Interface().ListClustersNameBySelector(context.Background(), selector)
+		Expect(err).To(BeNil())
+		Expect(names).To(ConsistOf("east-dev"))
+	})
+
+	It("should resolve names of cluster set", func() {
+		set := multicluster.ClusterSet{
+			Clusters: []string{"east-dev"},
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+		}
+		names, err := set.Names(context.Background(), multicluster.Interface())
+		Expect(names).To(Equal([]string{"east-dev", "east-prod", "west-prod"}))
+		unavailable, ok := multicluster.UnavailableClustersOf(err)
+		Expect(ok).To(BeTrue())
+		Expect(unavailable).To(Equal([]string{"east-prod-2"}))
+
+		set = multicluster.ClusterSet{Clusters: []string{"not-exist"}}
+		_, err = set.Names(context.Background(), multicluster.Interface())
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -19,15 +19,24 @@ package multicluster
 import (
 	"sync"
 
+	"k8s.io/client-go/discovery"
+
 	"github.com/kubecube-io/kubecube/pkg/conversion"
 )
 
 var _ conversion.MultiVersionConverter = &DefaultMultiVersionConverter{}
 
+// DiscoveryFunc returns the discovery of cluster
+type DiscoveryFunc func(cluster string) (discovery.DiscoveryInterface, error)
+
 type DefaultMultiVersionConverter struct {
 	rw              sync.RWMutex
 	versionConverts map[string]*conversion.VersionConverter
 	multiClusterMgr Manager
+
+	// discoveryFor returns the discovery used by version converter of
+	// cluster, the discovery client of cluster is used if nil
+	discoveryFor DiscoveryFunc
 }
 
 func NewDefaultMultiVersionConverter(m Manager) conversion.MultiVersionConverter {
@@ -37,6 +46,16 @@ func NewDefaultMultiVersionConverter(m Manager) conversion.MultiVersionConverter
 	}
 }
 
+// NewMultiVersionConverterWithDiscovery makes version converters of clusters
+// with discovery returned by discoveryFor, such as the cached discovery
+func NewMultiVersionConverterWithDiscovery(m Manager, discoveryFor DiscoveryFunc) conversion.MultiVersionConverter {
+	return &DefaultMultiVersionConverter{
+		versionConverts: map[string]*conversion.VersionConverter{},
+		multiClusterMgr: m,
+		discoveryFor:    discoveryFor,
+	}
+}
+
 func (m *DefaultMultiVersionConverter) GetVersionConvert(cluster string) (*conversion.VersionConverter, error) {
 	m.rw.RLock()
 	c, find := m.versionConverts[cluster]
@@ -51,7 +70,15 @@ func (m *DefaultMultiVersionConverter) GetVersionConvert(cluster string) (*conve
 		return nil, err
 	}
 
-	newc, err := conversion.NewVersionConvertor(ic.Client.Discovery(), nil)
+	var d discovery.DiscoveryInterface = ic.Client.Discovery()
+	if m.discoveryFor != nil {
+		d, err = m.discoveryFor(cluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newc, err := conversion.NewVersionConvertor(d, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,439 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	extensionv1 "github.com/kubecube-io/kubecube/pkg/apis/extension/v1"
+	hotplugv1 "github.com/kubecube-io/kubecube/pkg/apis/hotplug/v1"
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	userv1 "github.com/kubecube-io/kubecube/pkg/apis/user/v1"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+const (
+	// releaseKind is the kind of helm release in inventory
+	releaseKind = "HelmRelease"
+
+	// commonHotplug is the hotplug shared by all clusters
+	commonHotplug = "common"
+
+	enabled = "enabled"
+)
+
+// Resource is a resource created by KubeCube in member cluster
+type Resource struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// obj is used to delete the resource, nil for helm release
+	obj client.Object
+}
+
+func (r Resource) String() string {
+	if len(r.Namespace) > 0 {
+		return fmt.Sprintf("%v %v/%v", r.Kind, r.Namespace, r.Name)
+	}
+	return fmt.Sprintf("%v %v", r.Kind, r.Name)
+}
+
+// Inventory is the resources created by KubeCube in member cluster by category
+type Inventory map[clusterv1.ResourceCategory][]Resource
+
+// ReleaseUninstaller uninstalls helm release in member cluster
+type ReleaseUninstaller func(namespace, name string) error
+
+// PlanItem is what to do with a category of resources when cluster deleted
+type PlanItem struct {
+	Category  clusterv1.ResourceCategory `json:"category"`
+	Policy    clusterv1.DeletionPolicy   `json:"policy"`
+	Resources []string                   `json:"resources"`
+}
+
+// Plan is the dry-run of cleaning up member cluster
+type Plan struct {
+	Cluster string     `json:"cluster"`
+	Items   []PlanItem `json:"items"`
+}
+
+// policiesOf returns the effective deletion policies of cluster, resources
+// of pivot cluster are always retained cause KubeCube itself running there.
+func policiesOf(cluster *clusterv1.Cluster) []clusterv1.CategoryDeletionPolicy {
+	if !cluster.Spec.IsMemberCluster {
+		return clusterv1.EffectiveDeletionPolicies(nil, true)
+	}
+	return clusterv1.EffectiveDeletionPolicies(cluster.Spec.DeletionPolicies, env.RetainMemberClusterResource())
+}
+
+// NewPlan makes the deletion plan of cluster with its inventory
+func NewPlan(cluster *clusterv1.Cluster, inventory Inventory) *Plan {
+	plan := &Plan{Cluster: cluster.Name}
+
+	for _, p := range policiesOf(cluster) {
+		plan.Items = append(plan.Items, PlanItem{
+			Category:  p.Category,
+			Policy:    p.Policy,
+			Resources: resourceNames(inventory[p.Category]),
+		})
+	}
+
+	return plan
+}
+
+// Collect lists resources created by KubeCube in member cluster
+func Collect(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) (Inventory, error) {
+	inventory := make(Inventory)
+
+	collectors := map[clusterv1.ResourceCategory]func(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) ([]Resource, error){
+		clusterv1.ResourceCategoryWarden:          collectWarden,
+		clusterv1.ResourceCategoryWebhooks:        collectWebhooks,
+		clusterv1.ResourceCategoryCRDs:            collectCRDs,
+		clusterv1.ResourceCategorySyncedResources: collectSyncedResources,
+		clusterv1.ResourceCategoryHotplugReleases: collectHotplugReleases,
+	}
+
+	for _, category := range clusterv1.ResourceCategories {
+		resources, err := collectors[category](ctx, cli, cluster)
+		if err != nil {
+			return nil, fmt.Errorf("collect %v of cluster %v failed: %v", category, cluster.Name, err)
+		}
+		inventory[category] = resources
+	}
+
+	return inventory, nil
+}
+
+// Execute cleans up member cluster by the deletion policies of cluster, results
+// of every category are returned in order even though some of them failed.
+func Execute(ctx context.Context, cli client.Client, uninstall ReleaseUninstaller, cluster *clusterv1.Cluster, inventory Inventory) ([]clusterv1.DeletionResult, error) {
+	var (
+		results []clusterv1.DeletionResult
+		errs    []error
+	)
+
+	for _, p := range policiesOf(cluster) {
+		resources := inventory[p.Category]
+		result := clusterv1.DeletionResult{
+			Category:  p.Category,
+			Policy:    p.Policy,
+			Resources: resourceNames(resources),
+			Succeeded: true,
+		}
+
+		if tenants := archivedTenants(resources); p.Policy == clusterv1.DeletionPurge && len(tenants) > 0 {
+			// purging would lose archives of tenants that can not be restored anymore
+			result.Succeeded = false
+			result.Message = fmt.Sprintf("archives of tenants %v are stored in namespace %v, restore the tenants or retain warden resources", tenants, env.CubeNamespace())
+			errs = append(errs, fmt.Errorf("purge %v refused: %v", p.Category, result.Message))
+		} else if p.Policy == clusterv1.DeletionPurge {
+			err := purge(ctx, cli, uninstall, resources)
+			if err != nil {
+				result.Succeeded = false
+				result.Message = err.Error()
+				errs = append(errs, fmt.Errorf("purge %v failed: %v", p.Category, err))
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, utilerrors.NewAggregate(errs)
+}
+
+func purge(ctx context.Context, cli client.Client, uninstall ReleaseUninstaller, resources []Resource) error {
+	var errs []error
+	for _, r := range resources {
+		var err error
+		if r.obj == nil {
+			err = uninstall(r.Namespace, r.Name)
+		} else {
+			err = deleteObject(ctx, cli, r.obj)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", r, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func deleteObject(ctx context.Context, cli client.Client, obj client.Object) error {
+	// tenant and project are protected by webhook of warden
+	switch obj.(type) {
+	case *tenantv1.Tenant, *tenantv1.Project:
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		if annotations[constants.ForceDeleteAnnotation] != "true" {
+			annotations[constants.ForceDeleteAnnotation] = "true"
+			obj.SetAnnotations(annotations)
+			err := cli.Update(ctx, obj)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	err := cli.Delete(ctx, obj, client.PropagationPolicy("Background"))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// objectKey is an object expected in member cluster with its key
+type objectKey struct {
+	obj client.Object
+	key types.NamespacedName
+}
+
+func collectWarden(ctx context.Context, cli client.Client, _ *clusterv1.Cluster) ([]Resource, error) {
+	resources, err := getObjects(ctx, cli,
+		objectKey{obj: &corev1.Namespace{}, key: types.NamespacedName{Name: env.CubeNamespace()}},
+		objectKey{obj: &rbacv1.ClusterRoleBinding{}, key: types.NamespacedName{Name: constants.CubeClusterRoleBinding}},
+		objectKey{obj: &rbacv1.ClusterRole{}, key: types.NamespacedName{Name: constants.CubeClusterRole}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// archives of tenants are stored in cube namespace and gone with it
+	archives, err := tenancy.ListArchives(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+	for i := range archives {
+		resources = append(resources, newResource(cli, &archives[i]))
+	}
+
+	return resources, nil
+}
+
+// archivedTenants returns tenants whose archive bundle is in resources
+func archivedTenants(resources []Resource) []string {
+	var tenants []string
+	for _, r := range resources {
+		if r.obj != nil && tenancy.IsArchive(r.obj) {
+			tenants = append(tenants, r.obj.GetLabels()[constants.TenantLabel])
+		}
+	}
+	return tenants
+}
+
+func collectWebhooks(ctx context.Context, cli client.Client, _ *clusterv1.Cluster) ([]Resource, error) {
+	return getObjects(ctx, cli,
+		objectKey{obj: &admissionregistrationv1.ValidatingWebhookConfiguration{}, key: types.NamespacedName{Name: constants.WardenWebhook}},
+	)
+}
+
+// getObjects gets the expected objects, not found object is ignored
+func getObjects(ctx context.Context, cli client.Client, objs ...objectKey) ([]Resource, error) {
+	var resources []Resource
+	for _, o := range objs {
+		err := cli.Get(ctx, o.key, o.obj)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		resources = append(resources, newResource(cli, o.obj))
+	}
+	return resources, nil
+}
+
+func collectCRDs(ctx context.Context, cli client.Client, _ *clusterv1.Cluster) ([]Resource, error) {
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	err := cli.List(ctx, crds, client.MatchingLabels{constants.CrdLabel: "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for i := range crds.Items {
+		resources = append(resources, newResource(cli, &crds.Items[i]))
+	}
+
+	return resources, nil
+}
+
+// syncedLists are the lists of resources synced from pivot cluster by warden
+func syncedLists() []client.ObjectList {
+	return []client.ObjectList{
+		&rbacv1.RoleBindingList{},
+		&rbacv1.ClusterRoleBindingList{},
+		&rbacv1.RoleList{},
+		&rbacv1.ClusterRoleList{},
+		&hotplugv1.HotplugList{},
+		&tenantv1.TenantList{},
+		&tenantv1.ProjectList{},
+		&tenantv1.ProjectTemplateList{},
+		&userv1.UserList{},
+		&extensionv1.ExternalResourceList{},
+		&quotav1.CubeResourceQuotaList{},
+	}
+}
+
+func collectSyncedResources(ctx context.Context, cli client.Client, _ *clusterv1.Cluster) ([]Resource, error) {
+	var resources []Resource
+	for _, list := range syncedLists() {
+		err := cli.List(ctx, list)
+		if err != nil {
+			// crds of KubeCube may be not installed
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range objs {
+			obj, ok := o.(client.Object)
+			if !ok {
+				continue
+			}
+			if _, ok := obj.GetAnnotations()[constants.PivotResourceVersionAnnotation]; !ok {
+				continue
+			}
+			resources = append(resources, newResource(cli, obj))
+		}
+	}
+
+	return resources, nil
+}
+
+// collectHotplugReleases finds the helm releases enabled by hotplug of cluster,
+// the hotplug of cluster overrides the common one as the same as warden does.
+func collectHotplugReleases(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) ([]Resource, error) {
+	hotplugs, err := hotplugsOf(ctx, cli, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make(map[string]hotplugv1.ComponentConfig)
+	for _, hotplug := range hotplugs {
+		for _, c := range hotplug.Spec.Component {
+			components[c.Name] = c
+		}
+	}
+
+	var resources []Resource
+	for _, c := range components {
+		// audit is not installed by helm
+		if c.Status != enabled || c.Name == "audit" {
+			continue
+		}
+		resources = append(resources, Resource{Kind: releaseKind, Namespace: c.Namespace, Name: c.Name})
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].String() < resources[j].String()
+	})
+
+	return resources, nil
+}
+
+// hotplugsOf returns hotplugs applied to cluster in the order warden merges
+// them: the common one, the ones select cluster by name, the one named after
+// cluster
+func hotplugsOf(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster) ([]hotplugv1.Hotplug, error) {
+	list := &hotplugv1.HotplugList{}
+	err := cli.List(ctx, list)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var common, own *hotplugv1.Hotplug
+	var selected []hotplugv1.Hotplug
+	for i, hotplug := range list.Items {
+		switch {
+		case hotplug.Name == commonHotplug:
+			common = &list.Items[i]
+		case hotplug.Name == cluster.Name:
+			own = &list.Items[i]
+		case hotplug.Spec.ClusterSelector != nil:
+			selector, err := metav1.LabelSelectorAsSelector(hotplug.Spec.ClusterSelector)
+			if err != nil {
+				continue
+			}
+			if selector.Matches(labels.Set(cluster.Labels)) {
+				selected = append(selected, hotplug)
+			}
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Name < selected[j].Name
+	})
+
+	var hotplugs []hotplugv1.Hotplug
+	if common != nil {
+		hotplugs = append(hotplugs, *common)
+	}
+	hotplugs = append(hotplugs, selected...)
+	if own != nil {
+		hotplugs = append(hotplugs, *own)
+	}
+	return hotplugs, nil
+}
+
+func newResource(cli client.Client, obj client.Object) Resource {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if len(kind) == 0 {
+		gvk, err := apiutil.GVKForObject(obj, cli.Scheme())
+		if err == nil {
+			kind = gvk.Kind
+		}
+	}
+	return Resource{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName(), obj: obj}
+}
+
+func resourceNames(resources []Resource) []string {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, r.String())
+	}
+	return names
+}
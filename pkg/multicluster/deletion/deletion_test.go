@@ -0,0 +1,230 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deletion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubecube-io/kubecube/pkg/apis"
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	hotplugv1 "github.com/kubecube-io/kubecube/pkg/apis/hotplug/v1"
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+func newMemberClient() client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apis.AddToScheme(scheme)
+	_ = apiextensionsv1.AddToScheme(scheme)
+
+	synced := map[string]string{constants.PivotResourceVersionAnnotation: "1"}
+
+	objs := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: env.CubeNamespace()}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: constants.CubeClusterRole}},
+		&admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: constants.WardenWebhook}},
+		&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "tenants.tenant.kubecube.io", Labels: map[string]string{constants.CrdLabel: "true"}}},
+		&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "others.example.io"}},
+		&tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "tenant-1", Annotations: synced}},
+		&tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "tenant-local"}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "rb-1", Namespace: "ns-1", Annotations: synced}},
+		&hotplugv1.Hotplug{
+			ObjectMeta: metav1.ObjectMeta{Name: "common"},
+			Spec: hotplugv1.HotplugSpec{Component: []hotplugv1.ComponentConfig{
+				{Name: "logseer", Namespace: "logseer", Status: "enabled"},
+				{Name: "audit", Status: "enabled"},
+				{Name: "elasticsearch", Namespace: "elasticsearch", Status: "enabled"},
+			}},
+		},
+		&hotplugv1.Hotplug{
+			ObjectMeta: metav1.ObjectMeta{Name: "member-1"},
+			Spec: hotplugv1.HotplugSpec{Component: []hotplugv1.ComponentConfig{
+				{Name: "elasticsearch", Namespace: "elasticsearch", Status: "disabled"},
+			}},
+		},
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+}
+
+func TestCollect(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+	inventory, err := Collect(context.Background(), newMemberClient(), cluster)
+	assert.Nil(err)
+
+	assert.Equal([]string{"Namespace " + env.CubeNamespace(), "ClusterRole " + constants.CubeClusterRole}, resourceNames(inventory[clusterv1.ResourceCategoryWarden]))
+	assert.Equal([]string{"ValidatingWebhookConfiguration " + constants.WardenWebhook}, resourceNames(inventory[clusterv1.ResourceCategoryWebhooks]))
+	assert.Equal([]string{"CustomResourceDefinition tenants.tenant.kubecube.io"}, resourceNames(inventory[clusterv1.ResourceCategoryCRDs]))
+	assert.ElementsMatch([]string{"RoleBinding ns-1/rb-1", "Tenant tenant-1"}, resourceNames(inventory[clusterv1.ResourceCategorySyncedResources]))
+	assert.Equal([]string{"HelmRelease logseer/logseer"}, resourceNames(inventory[clusterv1.ResourceCategoryHotplugReleases]))
+}
+
+func TestCollectSelectedHotplugs(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMemberClient()
+	assert.Nil(cli.Create(ctx, &hotplugv1.Hotplug{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge"},
+		Spec: hotplugv1.HotplugSpec{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "edge"}},
+			Component: []hotplugv1.ComponentConfig{
+				{Name: "logseer", Namespace: "logseer", Status: "disabled"},
+				{Name: "elasticsearch", Namespace: "elasticsearch", Status: "enabled"},
+				{Name: "ingress", Namespace: "ingress", Status: "enabled"},
+			},
+		},
+	}))
+
+	// hotplug selecting cluster overrides common one and is overridden by the one of cluster
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1", Labels: map[string]string{"zone": "edge"}}}
+	inventory, err := Collect(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Equal([]string{"HelmRelease ingress/ingress"}, resourceNames(inventory[clusterv1.ResourceCategoryHotplugReleases]))
+
+	cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+	inventory, err = Collect(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Equal([]string{"HelmRelease logseer/logseer"}, resourceNames(inventory[clusterv1.ResourceCategoryHotplugReleases]))
+}
+
+func TestExecute(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMemberClient()
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member-1"},
+		Spec: clusterv1.ClusterSpec{
+			IsMemberCluster: true,
+			DeletionPolicies: []clusterv1.CategoryDeletionPolicy{
+				{Category: clusterv1.ResourceCategoryWarden, Policy: clusterv1.DeletionPurge},
+				{Category: clusterv1.ResourceCategorySyncedResources, Policy: clusterv1.DeletionPurge},
+				{Category: clusterv1.ResourceCategoryHotplugReleases, Policy: clusterv1.DeletionPurge},
+			},
+		},
+	}
+
+	inventory, err := Collect(ctx, cli, cluster)
+	assert.Nil(err)
+
+	var uninstalled []string
+	uninstall := func(namespace, name string) error {
+		uninstalled = append(uninstalled, namespace+"/"+name)
+		return nil
+	}
+
+	results, err := Execute(ctx, cli, uninstall, cluster, inventory)
+	assert.Nil(err)
+	assert.Len(results, len(clusterv1.ResourceCategories))
+	for _, r := range results {
+		assert.True(r.Succeeded, r.Category)
+	}
+	assert.Equal([]string{"logseer/logseer"}, uninstalled)
+
+	// purged
+	err = cli.Get(ctx, types.NamespacedName{Name: env.CubeNamespace()}, &corev1.Namespace{})
+	assert.True(errors.IsNotFound(err))
+	err = cli.Get(ctx, types.NamespacedName{Name: constants.WardenWebhook}, &admissionregistrationv1.ValidatingWebhookConfiguration{})
+	assert.True(errors.IsNotFound(err))
+	err = cli.Get(ctx, types.NamespacedName{Name: "tenant-1"}, &tenantv1.Tenant{})
+	assert.True(errors.IsNotFound(err))
+
+	// retained
+	err = cli.Get(ctx, types.NamespacedName{Name: "tenant-local"}, &tenantv1.Tenant{})
+	assert.Nil(err)
+	err = cli.Get(ctx, types.NamespacedName{Name: "tenants.tenant.kubecube.io"}, &apiextensionsv1.CustomResourceDefinition{})
+	assert.Nil(err)
+}
+
+func TestExecuteWithArchives(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMemberClient()
+	archive := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      tenancy.ArchiveName("tenant-1"),
+		Namespace: env.CubeNamespace(),
+		Labels:    map[string]string{constants.TenantLabel: "tenant-1"},
+	}}
+	assert.Nil(cli.Create(ctx, archive))
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "member-1"},
+		Spec: clusterv1.ClusterSpec{
+			IsMemberCluster: true,
+			DeletionPolicies: []clusterv1.CategoryDeletionPolicy{
+				{Category: clusterv1.ResourceCategoryWarden, Policy: clusterv1.DeletionPurge},
+			},
+		},
+	}
+
+	inventory, err := Collect(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Contains(resourceNames(inventory[clusterv1.ResourceCategoryWarden]), "Secret "+env.CubeNamespace()+"/"+archive.Name)
+
+	results, err := Execute(ctx, cli, func(string, string) error { return nil }, cluster, inventory)
+	assert.NotNil(err)
+	for _, r := range results {
+		if r.Category == clusterv1.ResourceCategoryWarden {
+			assert.False(r.Succeeded)
+			assert.Contains(r.Message, "tenant-1")
+		}
+	}
+
+	// archive is kept
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: env.CubeNamespace()}, &corev1.Namespace{}))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: archive.Name, Namespace: env.CubeNamespace()}, &corev1.Secret{}))
+}
+
+func TestPlanOfPivotCluster(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "pivot-cluster"},
+		Spec: clusterv1.ClusterSpec{
+			DeletionPolicies: []clusterv1.CategoryDeletionPolicy{
+				{Category: clusterv1.ResourceCategoryWarden, Policy: clusterv1.DeletionPurge},
+			},
+		},
+	}
+
+	plan := NewPlan(cluster, Inventory{})
+	assert.Equal("pivot-cluster", plan.Cluster)
+	for _, item := range plan.Items {
+		assert.Equal(clusterv1.DeletionRetain, item.Policy)
+	}
+}
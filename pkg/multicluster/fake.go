@@ -23,10 +23,12 @@ import (
 	"sync"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster/client"
 	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
@@ -113,6 +115,21 @@ func (m *FakerManagerImpl) Del(cluster string) error {
 	return nil
 }
 
+// Rebuild only refresh raw cluster in testing
+func (m *FakerManagerImpl) Rebuild(cluster clusterv1.Cluster) error {
+	m.Lock()
+	defer m.Unlock()
+
+	c, ok := m.Clusters[cluster.Name]
+	if !ok {
+		return fmt.Errorf("rebuild: internal cluster %s not found", cluster.Name)
+	}
+
+	c.RawCluster = cluster.DeepCopy()
+
+	return nil
+}
+
 func (m *FakerManagerImpl) FuzzyCopy() map[string]*FuzzyCluster {
 	m.RLock()
 	defer m.RUnlock()
@@ -178,6 +195,16 @@ func (m *FakerManagerImpl) ListClustersNameByType(t clusterType) []string {
 	return clusterNames
 }
 
+// ListClustersBySelector list clusters whose labels match given selector
+func (m *FakerManagerImpl) ListClustersBySelector(ctx context.Context, selector labels.Selector) ([]*InternalCluster, error) {
+	return listClustersBySelector(ctx, m, selector)
+}
+
+// ListClustersNameBySelector list cluster names whose labels match given selector
+func (m *FakerManagerImpl) ListClustersNameBySelector(ctx context.Context, selector labels.Selector) ([]string, error) {
+	return listClustersNameBySelector(ctx, m, selector)
+}
+
 func (m *FakerManagerImpl) Version(cluster string) (*version.Info, error) {
 	return nil, nil
 }
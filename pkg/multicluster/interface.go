@@ -20,7 +20,9 @@ import (
 	"context"
 	"net/http"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/version"
 )
 
@@ -31,6 +33,9 @@ type Manager interface {
 	Get(cluster string) (*InternalCluster, error)
 	Del(cluster string) error
 
+	// Rebuild replaces internal cluster with one of rebuilt client, cache and transport
+	Rebuild(cluster clusterv1.Cluster) error
+
 	// Version the k8s version about cluster
 	Version(cluster string) (*version.Info, error)
 
@@ -50,6 +55,14 @@ type Manager interface {
 
 	// ListClustersNameByType list cluster names by given type
 	ListClustersNameByType(t clusterType) []string
+
+	// ListClustersBySelector list clusters whose labels match given selector,
+	// clusters matched but not available are reported by UnavailableClustersError
+	ListClustersBySelector(ctx context.Context, selector labels.Selector) ([]*InternalCluster, error)
+
+	// ListClustersNameBySelector list cluster names whose labels match given selector,
+	// clusters matched but not available are reported by UnavailableClustersError
+	ListClustersNameBySelector(ctx context.Context, selector labels.Selector) ([]string, error)
 }
 
 // Interface the way to be used outside for multi cluster manager
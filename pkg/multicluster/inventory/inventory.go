@@ -0,0 +1,418 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory keeps a periodically refreshed inventory of every cluster
+// in multi cluster manager, so that callers read the snapshot in memory instead
+// of listing nodes and pods of all clusters on each request.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+const (
+	defaultRefreshInterval = time.Minute
+
+	// collectTimeout bounds the collection of one cluster, the first list
+	// of a kind waits for the informer synced
+	collectTimeout = 30 * time.Second
+)
+
+// Config is the config of inventory aggregator
+type Config struct {
+	// RefreshInterval is the interval to refresh inventory of clusters
+	RefreshInterval time.Duration
+}
+
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if c.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("inventory refresh interval must not be negative"))
+	}
+
+	return errs
+}
+
+// Workloads counts the workloads of a project in cluster
+type Workloads struct {
+	Tenant       string `json:"tenant"`
+	Project      string `json:"project"`
+	Namespaces   int    `json:"namespaces"`
+	Deployments  int    `json:"deployments"`
+	StatefulSets int    `json:"statefulSets"`
+	DaemonSets   int    `json:"daemonSets"`
+	Jobs         int    `json:"jobs"`
+	Pods         int    `json:"pods"`
+}
+
+// ClusterInventory is the snapshot of a cluster
+type ClusterInventory struct {
+	Cluster        string `json:"cluster"`
+	NodeCount      int    `json:"nodeCount"`
+	ReadyNodeCount int    `json:"readyNodeCount"`
+	NamespaceCount int    `json:"namespaceCount"`
+
+	Capacity    corev1.ResourceList `json:"capacity"`
+	Allocatable corev1.ResourceList `json:"allocatable"`
+
+	// Requests and Limits are the sum of non-terminated pods on nodes
+	Requests corev1.ResourceList `json:"requests"`
+	Limits   corev1.ResourceList `json:"limits"`
+
+	// Workloads is grouped by tenant and project, namespaces
+	// not belong to any project are not counted
+	Workloads []Workloads `json:"workloads"`
+
+	// RefreshTime is the time of last successful refresh
+	RefreshTime time.Time `json:"refreshTime"`
+
+	// Error is the error of last refresh, the snapshot is stale if not empty
+	Error string `json:"error,omitempty"`
+}
+
+// Aggregator refreshes the inventory of clusters periodically
+type Aggregator struct {
+	sync.RWMutex
+
+	interval    time.Duration
+	manager     multicluster.Manager
+	inventories map[string]*ClusterInventory
+}
+
+// aggregator is the global inventory aggregator
+var aggregator = newAggregator(defaultRefreshInterval, nil)
+
+func newAggregator(interval time.Duration, manager multicluster.Manager) *Aggregator {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	return &Aggregator{
+		interval:    interval,
+		manager:     manager,
+		inventories: make(map[string]*ClusterInventory),
+	}
+}
+
+// NewAggregatorWithOpts initializes the global aggregator with given config
+func NewAggregatorWithOpts(opts *Config) *Aggregator {
+	if opts != nil && opts.RefreshInterval > 0 {
+		aggregator.interval = opts.RefreshInterval
+	}
+	return aggregator
+}
+
+// Interface the entry for inventory of clusters
+func Interface() *Aggregator {
+	return aggregator
+}
+
+func (a *Aggregator) Initialize() error {
+	return nil
+}
+
+// Run refreshes the inventory util received stop signal
+func (a *Aggregator) Run(stop <-chan struct{}) {
+	wait.Until(func() {
+		a.Refresh(context.Background())
+	}, a.interval, stop)
+}
+
+// List returns the inventories of all clusters sorted by name
+func (a *Aggregator) List() []ClusterInventory {
+	a.RLock()
+	defer a.RUnlock()
+
+	items := make([]ClusterInventory, 0, len(a.inventories))
+	for _, v := range a.inventories {
+		items = append(items, *v)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Cluster < items[j].Cluster
+	})
+
+	return items
+}
+
+// Get returns the inventory of cluster, false if not collected yet
+func (a *Aggregator) Get(cluster string) (ClusterInventory, bool) {
+	a.RLock()
+	defer a.RUnlock()
+
+	v, ok := a.inventories[cluster]
+	if !ok {
+		return ClusterInventory{}, false
+	}
+
+	return *v, true
+}
+
+// Refresh collects the inventory of all clusters concurrently
+func (a *Aggregator) Refresh(ctx context.Context) {
+	m := a.manager
+	if m == nil {
+		m = multicluster.Interface()
+	}
+
+	clusters := m.FuzzyCopy()
+	delete(clusters, constants.LocalCluster)
+
+	wg := sync.WaitGroup{}
+	for name := range clusters {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			a.refreshCluster(ctx, m, name)
+		}(name)
+	}
+	wg.Wait()
+
+	// drop the inventory of clusters removed
+	a.Lock()
+	for name := range a.inventories {
+		if _, ok := clusters[name]; !ok {
+			delete(a.inventories, name)
+		}
+	}
+	a.Unlock()
+}
+
+func (a *Aggregator) refreshCluster(ctx context.Context, m multicluster.Manager, cluster string) {
+	var inventory *ClusterInventory
+
+	internalCluster, err := m.Get(cluster)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+		inventory, err = Collect(ctx, internalCluster.Client.Cache(), cluster)
+		cancel()
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	if err != nil {
+		clog.Warn("refresh inventory of cluster %v failed: %v", cluster, err)
+		// keep the last snapshot and mark it stale
+		stale, ok := a.inventories[cluster]
+		if !ok {
+			stale = &ClusterInventory{Cluster: cluster}
+		}
+		stale.Error = err.Error()
+		a.inventories[cluster] = stale
+		return
+	}
+
+	a.inventories[cluster] = inventory
+}
+
+// Collect makes the inventory of cluster by given reader, which should be
+// the cache of cluster to avoid listing through kube-apiserver
+func Collect(ctx context.Context, cli client.Reader, cluster string) (*ClusterInventory, error) {
+	inventory := &ClusterInventory{
+		Cluster:     cluster,
+		Capacity:    corev1.ResourceList{},
+		Allocatable: corev1.ResourceList{},
+		Requests:    corev1.ResourceList{},
+		Limits:      corev1.ResourceList{},
+		Workloads:   []Workloads{},
+	}
+
+	nodes := corev1.NodeList{}
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes failed: %v", err)
+	}
+
+	nodeNames := sets.NewString()
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		nodeNames.Insert(n.Name)
+		addResourceList(inventory.Capacity, n.Status.Capacity)
+		addResourceList(inventory.Allocatable, n.Status.Allocatable)
+		if isNodeReady(n) {
+			inventory.ReadyNodeCount++
+		}
+	}
+	inventory.NodeCount = len(nodes.Items)
+
+	namespaces := corev1.NamespaceList{}
+	if err := cli.List(ctx, &namespaces); err != nil {
+		return nil, fmt.Errorf("list namespaces failed: %v", err)
+	}
+	inventory.NamespaceCount = len(namespaces.Items)
+
+	// workloads of namespaces are counted into project they belong to
+	projects := make(map[string]*Workloads)
+	projectOf := make(map[string]*Workloads)
+	for _, ns := range namespaces.Items {
+		tenant, ok1 := ns.Labels[constants.HncTenantLabel]
+		project, ok2 := ns.Labels[constants.HncProjectLabel]
+		if !ok1 || !ok2 {
+			continue
+		}
+		key := tenant + "/" + project
+		w, ok := projects[key]
+		if !ok {
+			w = &Workloads{Tenant: tenant, Project: project}
+			projects[key] = w
+		}
+		w.Namespaces++
+		projectOf[ns.Name] = w
+	}
+
+	pods := corev1.PodList{}
+	if err := cli.List(ctx, &pods); err != nil {
+		return nil, fmt.Errorf("list pods failed: %v", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if w, ok := projectOf[pod.Namespace]; ok {
+			w.Pods++
+		}
+		phase := pod.Status.Phase
+		if nodeNames.Has(pod.Spec.NodeName) && phase != corev1.PodSucceeded && phase != corev1.PodFailed {
+			reqs, limits := PodRequestsAndLimits(pod)
+			addResourceList(inventory.Requests, reqs)
+			addResourceList(inventory.Limits, limits)
+		}
+	}
+
+	deployments := appsv1.DeploymentList{}
+	if err := cli.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("list deployments failed: %v", err)
+	}
+	for _, d := range deployments.Items {
+		if w, ok := projectOf[d.Namespace]; ok {
+			w.Deployments++
+		}
+	}
+
+	statefulSets := appsv1.StatefulSetList{}
+	if err := cli.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("list statefulsets failed: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		if w, ok := projectOf[s.Namespace]; ok {
+			w.StatefulSets++
+		}
+	}
+
+	daemonSets := appsv1.DaemonSetList{}
+	if err := cli.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("list daemonsets failed: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		if w, ok := projectOf[d.Namespace]; ok {
+			w.DaemonSets++
+		}
+	}
+
+	jobs := batchv1.JobList{}
+	if err := cli.List(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("list jobs failed: %v", err)
+	}
+	for _, j := range jobs.Items {
+		if w, ok := projectOf[j.Namespace]; ok {
+			w.Jobs++
+		}
+	}
+
+	for _, w := range projects {
+		inventory.Workloads = append(inventory.Workloads, *w)
+	}
+	sort.Slice(inventory.Workloads, func(i, j int) bool {
+		if inventory.Workloads[i].Tenant != inventory.Workloads[j].Tenant {
+			return inventory.Workloads[i].Tenant < inventory.Workloads[j].Tenant
+		}
+		return inventory.Workloads[i].Project < inventory.Workloads[j].Project
+	})
+
+	inventory.RefreshTime = time.Now()
+
+	return inventory, nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// PodRequestsAndLimits returns the effective requests and limits of pod
+func PodRequestsAndLimits(pod *corev1.Pod) (reqs, limits corev1.ResourceList) {
+	reqs, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResourceList(reqs, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+	// init containers define the minimum of any resource
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(reqs, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+
+	// add overhead for running a pod to the sum of requests and to non-zero limits
+	if pod.Spec.Overhead != nil {
+		addResourceList(reqs, pod.Spec.Overhead)
+
+		for name, quantity := range pod.Spec.Overhead {
+			if value, ok := limits[name]; ok && !value.IsZero() {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+	return
+}
+
+// addResourceList adds the resources in new to list
+func addResourceList(list, new corev1.ResourceList) {
+	for name, quantity := range new {
+		if value, ok := list[name]; !ok {
+			list[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			list[name] = value
+		}
+	}
+}
+
+// maxResourceList sets list to the greater of list and new for every resource
+func maxResourceList(list, new corev1.ResourceList) {
+	for name, quantity := range new {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubecube-io/kubecube/pkg/multicluster"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func newNode(name string, cpu, mem string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(mem),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Capacity:    resources,
+			Allocatable: resources,
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func newPod(namespace, name, node string, phase corev1.PodPhase, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name: "c",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func newProjectNamespace(name, tenant, project string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{constants.HncTenantLabel: tenant, constants.HncProjectLabel: project},
+	}}
+}
+
+func newClusterClient(objs ...client.Object) *multicluster.InternalCluster {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	return &multicluster.InternalCluster{Client: fake.NewFakeClients(&fake.Options{Scheme: scheme, Objs: objs})}
+}
+
+func TestCollect(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClusterClient(
+		newNode("node-1", "4", "8Gi", true),
+		newNode("node-2", "4", "8Gi", false),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		newProjectNamespace("ns-1", "tenant-1", "project-1"),
+		newProjectNamespace("ns-2", "tenant-1", "project-1"),
+		newProjectNamespace("ns-3", "tenant-2", "project-2"),
+		newPod("ns-1", "running", "node-1", corev1.PodRunning, "500m"),
+		newPod("ns-1", "succeeded", "node-1", corev1.PodSucceeded, "1"),
+		newPod("ns-3", "pending", "", corev1.PodPending, "1"),
+		newPod("kube-system", "system", "node-2", corev1.PodRunning, "250m"),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: "ns-1"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: "ns-2"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "s", Namespace: "ns-3"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds", Namespace: "kube-system"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "j", Namespace: "ns-3"}},
+	)
+
+	inventory, err := Collect(context.Background(), c.Client.Cache(), "member-1")
+	assert.Nil(err)
+
+	assert.Equal("member-1", inventory.Cluster)
+	assert.Equal(2, inventory.NodeCount)
+	assert.Equal(1, inventory.ReadyNodeCount)
+	assert.Equal(4, inventory.NamespaceCount)
+
+	cpu := inventory.Capacity[corev1.ResourceCPU]
+	assert.Equal("8", cpu.String())
+	mem := inventory.Allocatable[corev1.ResourceMemory]
+	assert.Equal("16Gi", mem.String())
+
+	// terminated pods and pods not scheduled are not allocated
+	req := inventory.Requests[corev1.ResourceCPU]
+	assert.Equal("750m", req.String())
+	limit := inventory.Limits[corev1.ResourceCPU]
+	assert.Equal("750m", limit.String())
+
+	assert.Equal([]Workloads{
+		{Tenant: "tenant-1", Project: "project-1", Namespaces: 2, Deployments: 2, Pods: 2},
+		{Tenant: "tenant-2", Project: "project-2", Namespaces: 1, StatefulSets: 1, Jobs: 1, Pods: 1},
+	}, inventory.Workloads)
+	assert.False(inventory.RefreshTime.IsZero())
+}
+
+func TestRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &multicluster.FakerManagerImpl{Clusters: make(map[string]*multicluster.InternalCluster)}
+	_ = m.Add(constants.LocalCluster, newClusterClient())
+	_ = m.Add("member-1", newClusterClient(newNode("node-1", "2", "4Gi", true)))
+	_ = m.Add("member-2", newClusterClient(newNode("node-1", "2", "4Gi", true), newNode("node-2", "2", "4Gi", true)))
+
+	a := newAggregator(0, m)
+	a.Refresh(context.Background())
+
+	items := a.List()
+	assert.Len(items, 2)
+	assert.Equal("member-1", items[0].Cluster)
+	assert.Equal(1, items[0].NodeCount)
+	assert.Equal("member-2", items[1].Cluster)
+	assert.Equal(2, items[1].NodeCount)
+
+	// inventory of removed cluster is dropped
+	_ = m.Del("member-2")
+	a.Refresh(context.Background())
+
+	_, ok := a.Get("member-2")
+	assert.False(ok)
+	item, ok := a.Get("member-1")
+	assert.True(ok)
+	assert.Empty(item.Error)
+}
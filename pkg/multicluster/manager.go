@@ -17,12 +17,14 @@ limitations under the License.
 package multicluster
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -119,17 +121,13 @@ type InternalCluster struct {
 	// StopCh for closing channel when delete cluster, goroutine
 	// of cache and scout will exit gracefully.
 	StopCh chan struct{}
+
+	// cancelClient stops the cache of client only, it is used
+	// when client of cluster rebuilt
+	cancelClient context.CancelFunc
 }
 
 func NewInternalCluster(cluster clusterv1.Cluster) (*InternalCluster, error) {
-	config, err := kubeconfig.LoadKubeConfigFromBytes(cluster.Spec.KubeConfig)
-	if err != nil {
-		return nil, fmt.Errorf("load kubeconfig failed: %v", err)
-	}
-	ts, err := rest.TransportFor(config)
-	if err != nil {
-		return nil, fmt.Errorf("load RoundTripper failed: %v", err)
-	}
 	// allocate mem address to avoid nil
 	cluster.Status.State = new(clusterv1.ClusterState)
 
@@ -143,20 +141,108 @@ func NewInternalCluster(cluster clusterv1.Cluster) (*InternalCluster, error) {
 	c := new(InternalCluster)
 	c.Name = cluster.Name
 	c.StopCh = make(chan struct{})
-	c.Config = config
-	c.transport = ts
 	c.Type = clusterType
-	c.RawCluster = cluster.DeepCopy()
-	c.Client, err = client.NewClientFor(exit.SetupCtxWithStop(context.Background(), c.StopCh), config)
+
+	conn, err := newConnection(cluster, c.StopCh)
 	if err != nil {
 		return nil, err
 	}
-	c.Version, err = c.Client.Discovery().ServerVersion()
+	conn.applyTo(c)
+
+	return c, nil
+}
+
+// RestConfigOf builds rest config by kubeconfig of cluster with client
+// settings applied, the config dials through tunnel if cluster is in
+// tunnel mode. The kubeconfig of cluster should be resolved already.
+func RestConfigOf(cluster clusterv1.Cluster) (*rest.Config, error) {
+	config, err := kubeconfig.LoadKubeConfigFromBytes(cluster.Spec.KubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig failed: %v", err)
+	}
+
+	ClientSettingsOf(cluster).applyTo(config)
+
+	if cluster.Spec.ConnectionMode == clusterv1.ConnectionTunnel {
+		config.Dial = TunnelServer.Dialer(cluster.Name)
+	}
+
+	return config, nil
+}
+
+// connection holds all things used to connect with a real cluster
+type connection struct {
+	config       *rest.Config
+	transport    http.RoundTripper
+	client       client.Client
+	version      *version.Info
+	rawCluster   *clusterv1.Cluster
+	cancelClient context.CancelFunc
+}
+
+// newConnection builds config, transport and client by kubeconfig of cluster,
+// the cache of client will be stopped when stopCh closed or connection canceled.
+func newConnection(cluster clusterv1.Cluster, stopCh chan struct{}) (*connection, error) {
+	config, err := RestConfigOf(cluster)
 	if err != nil {
 		return nil, err
 	}
+	settings := ClientSettingsOf(cluster)
 
-	return c, nil
+	ts, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("load RoundTripper failed: %v", err)
+	}
+	ts = settings.wrapTransport(ts)
+
+	ctx, cancel := context.WithCancel(exit.SetupCtxWithStop(context.Background(), stopCh))
+
+	cli, err := client.NewClientForWithOptions(ctx, config, client.Options{ResyncPeriod: settings.ResyncPeriod})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	v, err := cli.Discovery().ServerVersion()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &connection{
+		config:       config,
+		transport:    ts,
+		client:       cli,
+		version:      v,
+		rawCluster:   cluster.DeepCopy(),
+		cancelClient: cancel,
+	}, nil
+}
+
+func (conn *connection) applyTo(c *InternalCluster) {
+	c.Config = conn.config
+	c.transport = conn.transport
+	c.Client = conn.client
+	c.Version = conn.version
+	c.RawCluster = conn.rawCluster
+	c.cancelClient = conn.cancelClient
+}
+
+// IsKubeConfigChanged tells if the kubeconfig of cluster differs from the one
+// that internal cluster built with
+func (c *InternalCluster) IsKubeConfigChanged(cluster clusterv1.Cluster) bool {
+	if c.RawCluster == nil {
+		return false
+	}
+	return !bytes.Equal(c.RawCluster.Spec.KubeConfig, cluster.Spec.KubeConfig)
+}
+
+// IsConnectionChanged tells if the connection mode or client settings of
+// cluster differs from the one that internal cluster built with
+func (c *InternalCluster) IsConnectionChanged(cluster clusterv1.Cluster) bool {
+	if c.RawCluster == nil {
+		return false
+	}
+	return clusterv1.IsConnectionChanged(c.RawCluster, &cluster)
 }
 
 // MultiClustersMgr a memory cache for runtime cluster.
@@ -245,6 +331,52 @@ func (m *MultiClustersMgr) Version(cluster string) (*version.Info, error) {
 	return c.Version, nil
 }
 
+// Rebuild rebuilds client, cache and transport of internal cluster with the
+// kubeconfig and client settings of given cluster, the scout of internal
+// cluster is kept. A new internal cluster replaces the stale one, so that
+// holders of the stale one never see a half updated cluster.
+// The internal cluster stays unchanged if rebuild failed. The kubeconfig of
+// given cluster should be resolved already, see ResolveCluster.
+func (m *MultiClustersMgr) Rebuild(cluster clusterv1.Cluster) error {
+	m.RLock()
+	stale, ok := m.Clusters[cluster.Name]
+	m.RUnlock()
+	if !ok {
+		return fmt.Errorf("rebuild: internal cluster %s not found", cluster.Name)
+	}
+
+	conn, err := newConnection(cluster, stale.StopCh)
+	if err != nil {
+		return fmt.Errorf("rebuild: connect to cluster %s failed: %v", cluster.Name, err)
+	}
+
+	c := &InternalCluster{
+		Name:   stale.Name,
+		Type:   stale.Type,
+		Scout:  stale.Scout,
+		StopCh: stale.StopCh,
+	}
+	conn.applyTo(c)
+
+	m.Lock()
+	if m.Clusters[cluster.Name] != stale {
+		m.Unlock()
+		conn.cancelClient()
+		return fmt.Errorf("rebuild: internal cluster %s changed while rebuilding", cluster.Name)
+	}
+	m.Clusters[cluster.Name] = c
+	m.Unlock()
+
+	// stop cache of stale client after nobody could get it
+	if stale.cancelClient != nil {
+		stale.cancelClient()
+	}
+
+	clog.Info("rebuild internal cluster %v", cluster.Name)
+
+	return nil
+}
+
 // ScoutFor starts watch for warden intelligence
 func (m *MultiClustersMgr) ScoutFor(ctx context.Context, cluster string) error {
 	c, err := m.Get(cluster)
@@ -302,6 +434,16 @@ func (m *MultiClustersMgr) ListClustersNameByType(t clusterType) []string {
 	return clusterNames
 }
 
+// ListClustersBySelector list clusters whose labels match given selector
+func (m *MultiClustersMgr) ListClustersBySelector(ctx context.Context, selector labels.Selector) ([]*InternalCluster, error) {
+	return listClustersBySelector(ctx, m, selector)
+}
+
+// ListClustersNameBySelector list cluster names whose labels match given selector
+func (m *MultiClustersMgr) ListClustersNameBySelector(ctx context.Context, selector labels.Selector) ([]string, error) {
+	return listClustersNameBySelector(ctx, m, selector)
+}
+
 // FuzzyCluster be exported for test
 type FuzzyCluster struct {
 	Name       string
@@ -321,7 +463,7 @@ func (m *MultiClustersMgr) FuzzyCopy() map[string]*FuzzyCluster {
 			continue
 		}
 		// we must new *rest.Config just like deep copy
-		cfg, _ := kubeconfig.LoadKubeConfigFromBytes(v.RawCluster.Spec.KubeConfig)
+		cfg, _ := RestConfigOf(*v.RawCluster)
 		clusters[name] = &FuzzyCluster{
 			Name:       name,
 			Config:     cfg,
@@ -345,6 +487,26 @@ func AddInternalCluster(cluster clusterv1.Cluster) error {
 	return addInternalCluster(cluster, false)
 }
 
+// ResolveCluster returns cluster with kubeconfig loaded from the secret
+// in local cluster, the result must not be written back to apiserver.
+func ResolveCluster(cluster clusterv1.Cluster) (clusterv1.Cluster, error) {
+	if len(cluster.Spec.KubeConfig) > 0 {
+		return cluster, nil
+	}
+
+	localCluster, err := Interface().Get(constants.LocalCluster)
+	if err != nil {
+		return cluster, err
+	}
+
+	resolved, err := kubeconfig.ResolveCluster(context.Background(), localCluster.Client.Direct(), &cluster)
+	if err != nil {
+		return cluster, err
+	}
+
+	return *resolved, nil
+}
+
 func addInternalCluster(cluster clusterv1.Cluster, withScout bool) error {
 	_, err := ManagerImpl.Get(cluster.Name)
 	if err == nil {
@@ -352,6 +514,11 @@ func addInternalCluster(cluster clusterv1.Cluster, withScout bool) error {
 		return nil
 	}
 
+	cluster, err = ResolveCluster(cluster)
+	if err != nil {
+		return err
+	}
+
 	c, err := NewInternalCluster(cluster)
 	if err != nil {
 		return err
@@ -363,6 +530,7 @@ func addInternalCluster(cluster clusterv1.Cluster, withScout bool) error {
 			return err
 		}
 		c.Scout = scout.NewScout(cluster.Name, 0, 0, localCluster.Client.Direct(), c.StopCh)
+		c.Scout.MaintenanceReader = localCluster.Client.Cache()
 	}
 
 	err = ManagerImpl.Add(cluster.Name, c)
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var heartbeatAgeDesc = prometheus.NewDesc("kubecube_scout_heartbeat_age_seconds",
+	"Seconds since the last heartbeat of warden received by scout of cluster.", []string{"cluster"}, nil)
+
+// scoutCollector exports the heartbeat age of clusters at scrape time
+type scoutCollector struct {
+	m *MultiClustersMgr
+}
+
+// NewScoutCollector returns the collector of heartbeat of clusters in multi cluster manager
+func NewScoutCollector() prometheus.Collector {
+	return &scoutCollector{m: ManagerImpl}
+}
+
+func (c *scoutCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- heartbeatAgeDesc
+}
+
+func (c *scoutCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.m == nil {
+		return
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	for name, cluster := range c.m.Clusters {
+		// local cluster has no scout, heartbeat not received yet is skipped
+		if cluster.Scout == nil {
+			continue
+		}
+		lastHeartbeat := cluster.Scout.LastHeartbeat()
+		if lastHeartbeat.IsZero() {
+			continue
+		}
+		age := time.Since(lastHeartbeat).Seconds()
+		ch <- prometheus.MustNewConstMetric(heartbeatAgeDesc, prometheus.GaugeValue, age, name)
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/conversion"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
+)
+
+const (
+	// MinKubernetesVersion is the minimum version of member cluster,
+	// the crds of KubeCube are apiextensions.k8s.io/v1 which need 1.16+
+	MinKubernetesVersion = "v1.16"
+
+	// connectTimeout is the timeout of every request to member cluster
+	connectTimeout = 10 * time.Second
+
+	// dialTimeout is the timeout of dialing pivot cube host
+	dialTimeout = 3 * time.Second
+)
+
+const (
+	CheckConnectivity      = "Connectivity"
+	CheckKubernetesVersion = "KubernetesVersion"
+	CheckRequiredAPIs      = "RequiredAPIs"
+	CheckRBAC              = "RBAC"
+	CheckPivotReachability = "PivotReachability"
+)
+
+// CheckStatus is the result status of a pre-flight check
+type CheckStatus string
+
+const (
+	// CheckPassed means cluster satisfies the check
+	CheckPassed CheckStatus = "Passed"
+	// CheckWarning means cluster can be imported but some features may not work
+	CheckWarning CheckStatus = "Warning"
+	// CheckFailed means cluster can not be imported
+	CheckFailed CheckStatus = "Failed"
+	// CheckSkipped means the check can not run from pivot cluster
+	CheckSkipped CheckStatus = "Skipped"
+)
+
+// CheckResult is the result of a single pre-flight check
+type CheckResult struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+// Report is the structured result of pre-flight checks
+type Report struct {
+	// Passed is true when none of checks failed
+	Passed            bool          `json:"passed"`
+	KubernetesVersion string        `json:"kubernetesVersion,omitempty"`
+	Checks            []CheckResult `json:"checks"`
+}
+
+func (r *Report) add(result CheckResult) {
+	if result.Status == CheckFailed {
+		r.Passed = false
+	}
+	r.Checks = append(r.Checks, result)
+}
+
+// apiRequirement describes a group version member cluster should serve
+type apiRequirement struct {
+	groupVersion string
+	// optional api only results warning when missing
+	optional bool
+	reason   string
+}
+
+var requiredAPIs = []apiRequirement{
+	{groupVersion: "apps/v1", reason: "warden deployment"},
+	{groupVersion: "batch/v1", reason: "dependence job"},
+	{groupVersion: "rbac.authorization.k8s.io/v1", reason: "cluster roles of KubeCube"},
+	{groupVersion: "apiextensions.k8s.io/v1", reason: "crds of KubeCube"},
+	{groupVersion: "hnc.x-k8s.io/v1alpha2", optional: true, reason: "hnc will be installed by dependence job"},
+	{groupVersion: "metrics.k8s.io/v1beta1", optional: true, reason: "metrics-server is needed by cluster monitor"},
+}
+
+// requiredPermissions are the permissions used when deploying warden to member cluster
+func requiredPermissions() []authorizationv1.ResourceAttributes {
+	return []authorizationv1.ResourceAttributes{
+		{Verb: "create", Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+		{Verb: "create", Resource: "namespaces"},
+		{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+		{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+		{Verb: "bind", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+		{Verb: "create", Resource: "secrets", Namespace: env.CubeNamespace()},
+		{Verb: "create", Resource: "configmaps", Namespace: env.CubeNamespace()},
+		{Verb: "create", Group: "batch", Resource: "jobs", Namespace: env.CubeNamespace()},
+		{Verb: "create", Group: "apps", Resource: "deployments", Namespace: env.CubeNamespace()},
+	}
+}
+
+// Run connects to cluster with given kubeconfig and checks if it can be
+// imported into KubeCube. Nothing will be created in the cluster. The
+// kube-apiserver of cluster connected by tunnel is not reachable from
+// pivot cluster, so only the pivot reachability is checked for it.
+func Run(ctx context.Context, kubeConfig []byte, mode clusterv1.ConnectionMode) (*Report, error) {
+	config, err := kubeconfig.LoadKubeConfigFromBytes(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Passed: true}
+
+	if mode == clusterv1.ConnectionTunnel {
+		skipDirectChecks(report)
+		report.add(checkPivotReachability(env.PivotCubeHost()))
+		return report, nil
+	}
+
+	cli, err := connectForPreflight(config)
+	if err != nil {
+		report.add(CheckResult{Name: CheckConnectivity, Status: CheckFailed, Message: err.Error()})
+		return report, nil
+	}
+
+	check(ctx, cli, report)
+
+	report.add(checkPivotReachability(env.PivotCubeHost()))
+
+	return report, nil
+}
+
+// connectForPreflight makes a client for cluster and ensures the
+// api server is reachable before any check
+func connectForPreflight(config *rest.Config) (kubernetes.Interface, error) {
+	config = rest.CopyConfig(config)
+	config.Timeout = connectTimeout
+
+	cli, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = cli.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("connect to %v failed: %v", config.Host, err)
+	}
+
+	return cli, nil
+}
+
+// check runs the checks against member cluster
+func check(ctx context.Context, cli kubernetes.Interface, report *Report) {
+	report.add(CheckResult{Name: CheckConnectivity, Status: CheckPassed})
+
+	version, result := checkKubernetesVersion(cli)
+	report.KubernetesVersion = version
+	report.add(result)
+
+	report.add(checkRequiredAPIs(cli))
+	report.add(checkRBAC(ctx, cli))
+}
+
+// skipDirectChecks records the checks against kube-apiserver of cluster as skipped
+func skipDirectChecks(report *Report) {
+	for _, name := range []string{CheckConnectivity, CheckKubernetesVersion, CheckRequiredAPIs, CheckRBAC} {
+		report.add(CheckResult{
+			Name:    name,
+			Status:  CheckSkipped,
+			Message: "kube-apiserver of cluster connected by tunnel is not reachable from pivot cluster",
+		})
+	}
+}
+
+func checkKubernetesVersion(cli kubernetes.Interface) (string, CheckResult) {
+	result := CheckResult{Name: CheckKubernetesVersion}
+
+	info, err := cli.Discovery().ServerVersion()
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = err.Error()
+		return "", result
+	}
+
+	// minor version may have suffix such as 20+ in some vendors
+	version := fmt.Sprintf("v%s.%s", info.Major, strings.TrimRight(info.Minor, "+"))
+
+	r, err := conversion.VersionCompare(version, MinKubernetesVersion)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("unrecognized kubernetes version %v", info.GitVersion)
+		return info.GitVersion, result
+	}
+
+	if r < 0 {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("kubernetes version %v is lower than %v", info.GitVersion, MinKubernetesVersion)
+		return info.GitVersion, result
+	}
+
+	result.Status = CheckPassed
+
+	return info.GitVersion, result
+}
+
+func checkRequiredAPIs(cli kubernetes.Interface) CheckResult {
+	result := CheckResult{Name: CheckRequiredAPIs, Status: CheckPassed}
+
+	groups, err := cli.Discovery().ServerGroups()
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	served := sets.NewString(metav1.ExtractGroupVersions(groups)...)
+
+	var missing, missingOptional []string
+	for _, api := range requiredAPIs {
+		if served.Has(api.groupVersion) {
+			continue
+		}
+		msg := fmt.Sprintf("%v (%v)", api.groupVersion, api.reason)
+		if api.optional {
+			missingOptional = append(missingOptional, msg)
+		} else {
+			missing = append(missing, msg)
+		}
+	}
+
+	switch {
+	case len(missing) > 0:
+		result.Status = CheckFailed
+		result.Message = "missing apis: " + strings.Join(append(missing, missingOptional...), ", ")
+	case len(missingOptional) > 0:
+		result.Status = CheckWarning
+		result.Message = "missing apis: " + strings.Join(missingOptional, ", ")
+	}
+
+	return result
+}
+
+func checkRBAC(ctx context.Context, cli kubernetes.Interface) CheckResult {
+	result := CheckResult{Name: CheckRBAC, Status: CheckPassed}
+
+	var denied []string
+	for _, attr := range requiredPermissions() {
+		attr := attr
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attr},
+		}
+		r, err := cli.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			result.Status = CheckFailed
+			result.Message = err.Error()
+			return result
+		}
+		if !r.Status.Allowed {
+			denied = append(denied, describePermission(attr))
+		}
+	}
+
+	if len(denied) > 0 {
+		result.Status = CheckFailed
+		result.Message = "permission denied: " + strings.Join(denied, ", ")
+	}
+
+	return result
+}
+
+// checkPivotReachability dials the pivot cube host which warden reports to.
+// It is dialed from pivot cluster, so it only tells the address is valid
+// and listening, member cluster should be able to reach the same address.
+func checkPivotReachability(host string) CheckResult {
+	result := CheckResult{Name: CheckPivotReachability, Status: CheckPassed}
+
+	if len(host) == 0 {
+		result.Status = CheckWarning
+		result.Message = "pivot cube host is not set"
+		return result
+	}
+
+	addr, err := hostAddress(host)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("pivot cube host %v unreachable: %v", host, err)
+		return result
+	}
+	_ = conn.Close()
+
+	result.Message = fmt.Sprintf("member cluster should be able to reach %v", addr)
+
+	return result
+}
+
+// hostAddress converts pivot cube host to host:port, https is
+// the default scheme as the same as warden reporter does.
+func hostAddress(host string) (string, error) {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid pivot cube host %v: %v", host, err)
+	}
+
+	port := u.Port()
+	if len(port) == 0 {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+func describePermission(attr authorizationv1.ResourceAttributes) string {
+	resource := attr.Resource
+	if len(attr.Group) > 0 {
+		resource = resource + "." + attr.Group
+	}
+	if len(attr.Namespace) > 0 {
+		return fmt.Sprintf("%v %v in %v", attr.Verb, resource, attr.Namespace)
+	}
+	return fmt.Sprintf("%v %v", attr.Verb, resource)
+}
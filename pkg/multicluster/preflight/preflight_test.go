@@ -0,0 +1,167 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+func newFakeClient(minor string, groupVersions []string, denied string) *fake.Clientset {
+	cli := fake.NewSimpleClientset()
+
+	discovery := cli.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{Major: "1", Minor: minor, GitVersion: "v1." + minor}
+	for _, gv := range groupVersions {
+		discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: gv})
+	}
+
+	cli.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != denied
+		return true, review, nil
+	})
+
+	return cli
+}
+
+func findCheck(report *Report, name string) CheckResult {
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return CheckResult{}
+}
+
+func TestCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	allAPIs := []string{"v1", "apps/v1", "batch/v1", "rbac.authorization.k8s.io/v1", "apiextensions.k8s.io/v1", "hnc.x-k8s.io/v1alpha2", "metrics.k8s.io/v1beta1"}
+
+	// all checks passed
+	report := &Report{Passed: true}
+	check(context.Background(), newFakeClient("20+", allAPIs, ""), report)
+	assert.True(report.Passed)
+	assert.Equal("v1.20+", report.KubernetesVersion)
+	for _, c := range report.Checks {
+		assert.Equal(CheckPassed, c.Status, c.Name)
+	}
+
+	// optional api missing only warns
+	report = &Report{Passed: true}
+	check(context.Background(), newFakeClient("20", allAPIs[:5], ""), report)
+	assert.True(report.Passed)
+	apis := findCheck(report, CheckRequiredAPIs)
+	assert.Equal(CheckWarning, apis.Status)
+	assert.Contains(apis.Message, "metrics.k8s.io/v1beta1")
+
+	// low version and rbac denied fail the report
+	report = &Report{Passed: true}
+	check(context.Background(), newFakeClient("15", allAPIs, "clusterrolebindings"), report)
+	assert.False(report.Passed)
+	assert.Equal(CheckFailed, findCheck(report, CheckKubernetesVersion).Status)
+	rbac := findCheck(report, CheckRBAC)
+	assert.Equal(CheckFailed, rbac.Status)
+	assert.Contains(rbac.Message, "create clusterrolebindings.rbac.authorization.k8s.io")
+
+	// required api missing
+	report = &Report{Passed: true}
+	check(context.Background(), newFakeClient("20", []string{"v1", "apps/v1"}, ""), report)
+	assert.False(report.Passed)
+	assert.Contains(findCheck(report, CheckRequiredAPIs).Message, "apiextensions.k8s.io/v1")
+}
+
+func TestRunTunnel(t *testing.T) {
+	assert := assert.New(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer l.Close()
+	os.Setenv("PIVOT_CUBE_HOST", "http://"+l.Addr().String())
+	defer os.Unsetenv("PIVOT_CUBE_HOST")
+
+	// kube-apiserver of tunnel cluster is never dialed
+	kubeConfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://10.255.255.1:6443
+  name: member
+contexts:
+- context:
+    cluster: member
+    user: admin
+  name: member
+current-context: member
+users:
+- name: admin
+  user:
+    token: token
+`)
+	report, err := Run(context.Background(), kubeConfig, clusterv1.ConnectionTunnel)
+	assert.Nil(err)
+	assert.True(report.Passed)
+	assert.Equal(CheckSkipped, findCheck(report, CheckConnectivity).Status)
+	assert.Equal(CheckSkipped, findCheck(report, CheckRBAC).Status)
+	assert.Equal(CheckPassed, findCheck(report, CheckPivotReachability).Status)
+}
+
+func TestCheckPivotReachability(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(CheckWarning, checkPivotReachability("").Status)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(err)
+	defer l.Close()
+
+	assert.Equal(CheckPassed, checkPivotReachability("http://"+l.Addr().String()).Status)
+
+	addr := l.Addr().String()
+	l.Close()
+	assert.Equal(CheckFailed, checkPivotReachability(addr).Status)
+}
+
+func TestHostAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]string{
+		"10.0.0.1:30443":        "10.0.0.1:30443",
+		"cube.kubecube.io":      "cube.kubecube.io:443",
+		"http://cube.io":        "cube.io:80",
+		"https://cube.io:8443/": "cube.io:8443",
+	}
+	for host, want := range tests {
+		got, err := hostAddress(host)
+		assert.Nil(err)
+		assert.Equal(want, got)
+	}
+}
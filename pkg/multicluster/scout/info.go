@@ -0,0 +1,127 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scout
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+// needSyncStatus determines whether cluster status should be updated by warden info.
+// Cluster status is synced at once when cluster state, versions or components changed,
+// the other info like latency and counts are synced at most once per statusSyncInterval.
+func (s *Scout) needSyncStatus(info WardenInfo, now time.Time) bool {
+	if s.clusterState != v1.ClusterNormal || s.syncedInfo == nil {
+		return true
+	}
+
+	last := s.syncedInfo
+	if last.WardenVersion != info.WardenVersion || last.KubernetesVersion != info.KubernetesVersion {
+		return true
+	}
+
+	if !reflect.DeepEqual(last.Components, info.Components) {
+		return true
+	}
+
+	return now.Sub(s.lastStatusSync) >= statusSyncInterval
+}
+
+// setWardenInfo populates status and conditions of cluster with warden info
+func setWardenInfo(cluster *v1.Cluster, info WardenInfo) {
+	status := &cluster.Status
+	status.WardenVersion = info.WardenVersion
+	status.KubernetesVersion = info.KubernetesVersion
+	status.Latency = &metav1.Duration{Duration: info.Latency}
+	status.NodeCount = info.NodeCount
+	status.PodCount = info.PodCount
+	status.Components = info.Components
+
+	conditions := []metav1.Condition{
+		{
+			Type:    v1.ClusterHeartbeatHealthy,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ReasonHeartbeatReceived,
+			Message: fmt.Sprintf("receive heartbeat from cluster %s", cluster.Name),
+		},
+		v1.WritableCondition(cluster.Spec.IsWritable),
+	}
+
+	// warden of early version does not report components and metrics
+	if len(info.Components) > 0 {
+		conditions = append(conditions, componentsCondition(info.Components))
+	}
+	if info.MetricsAvailable != nil {
+		conditions = append(conditions, metricsCondition(*info.MetricsAvailable))
+	}
+
+	for _, condition := range conditions {
+		condition.ObservedGeneration = cluster.Generation
+		v1.SetClusterCondition(status, condition)
+	}
+}
+
+// metricsCondition makes condition by availability of metrics api
+func metricsCondition(available bool) metav1.Condition {
+	if available {
+		return metav1.Condition{
+			Type:    v1.ClusterMetricsAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ReasonMetricsAvailable,
+			Message: "metrics api is available",
+		}
+	}
+
+	return metav1.Condition{
+		Type:    v1.ClusterMetricsAvailable,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1.ReasonMetricsUnavailable,
+		Message: "metrics api is unavailable",
+	}
+}
+
+// componentsCondition makes condition by readiness of warden components
+func componentsCondition(components []v1.ComponentStatus) metav1.Condition {
+	var notReady []string
+	for _, c := range components {
+		if !c.Ready {
+			notReady = append(notReady, c.Name)
+		}
+	}
+
+	if len(notReady) > 0 {
+		return metav1.Condition{
+			Type:    v1.ClusterComponentsReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1.ReasonComponentsNotReady,
+			Message: fmt.Sprintf("components of warden not ready: %v", strings.Join(notReady, ", ")),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    v1.ClusterComponentsReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ReasonComponentsReady,
+		Message: "all components of warden are ready",
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scout
+
+import (
+	"context"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+const (
+	// leasePrefix is the name prefix of the lease which holds heartbeat of cluster
+	leasePrefix = "cluster-heartbeat-"
+
+	// renewIntervalFraction is the fraction of lease duration to renew the lease,
+	// as the same as kubelet does for node lease.
+	renewIntervalFraction = 0.25
+)
+
+// LeaseName returns the name of lease that records heartbeat of given cluster
+func LeaseName(cluster string) string {
+	return leasePrefix + cluster
+}
+
+// leaseDuration is the duration that warden heartbeat keeps alive
+func (s *Scout) leaseDuration() time.Duration {
+	return time.Duration(s.WaitTimeoutSeconds) * time.Second
+}
+
+// renewInterval is the minimum interval between two renewals of lease,
+// heartbeats received within it will not write to apiserver.
+func (s *Scout) renewInterval() time.Duration {
+	return time.Duration(float64(s.leaseDuration()) * renewIntervalFraction)
+}
+
+// getLease fetches the latest lease of cluster and caches it
+func (s *Scout) getLease(ctx context.Context) (*coordinationv1.Lease, error) {
+	lease := &coordinationv1.Lease{}
+	key := types.NamespacedName{Name: LeaseName(s.Cluster), Namespace: env.CubeNamespace()}
+	err := s.client.Get(ctx, key, lease)
+	if err != nil {
+		s.lease = nil
+		return nil, err
+	}
+
+	s.lease = lease
+
+	return lease, nil
+}
+
+// renewLease renews the lease of cluster with given time, the lease
+// will be created if not exist.
+func (s *Scout) renewLease(ctx context.Context, now time.Time) error {
+	lease := s.lease
+	if lease == nil {
+		var err error
+		lease, err = s.getLease(ctx)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return s.createLease(ctx, now)
+			}
+			return err
+		}
+	}
+
+	// skip renewal if lease was renewed recently
+	if lease.Spec.RenewTime != nil && now.Sub(lease.Spec.RenewTime.Time) < s.renewInterval() {
+		return nil
+	}
+
+	newLease := lease.DeepCopy()
+	newLease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	newLease.Spec.LeaseDurationSeconds = int32Ptr(int32(s.WaitTimeoutSeconds))
+
+	err := s.client.Update(ctx, newLease)
+	if err != nil {
+		// lease may be renewed by other KubeCube instances, we
+		// fetch the latest one for next renewal.
+		s.lease = nil
+		if errors.IsConflict(err) {
+			return nil
+		}
+		return err
+	}
+
+	s.lease = newLease
+
+	return nil
+}
+
+// createLease creates the lease of cluster owned by the cluster
+// so that the lease will be gc when cluster deleted.
+func (s *Scout) createLease(ctx context.Context, now time.Time) error {
+	cluster := &v1.Cluster{}
+	err := s.client.Get(ctx, types.NamespacedName{Name: s.Cluster}, cluster)
+	if err != nil {
+		return err
+	}
+
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LeaseName(s.Cluster),
+			Namespace: env.CubeNamespace(),
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       cluster.Name,
+					UID:        cluster.UID,
+				},
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &s.Cluster,
+			LeaseDurationSeconds: int32Ptr(int32(s.WaitTimeoutSeconds)),
+			RenewTime:            &metav1.MicroTime{Time: now},
+		},
+	}
+
+	err = s.client.Create(ctx, lease)
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			// created by other KubeCube instances
+			return nil
+		}
+		return err
+	}
+
+	s.lease = lease
+
+	return nil
+}
+
+// isLeaseExpired determines the health of the cluster by lease
+func isLeaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	duration := time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+
+	return now.Sub(lease.Spec.RenewTime.Time) >= duration
+}
+
+func int32Ptr(i int32) *int32 { return &i }
@@ -22,8 +22,9 @@ import (
 	"sync"
 	"time"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
@@ -34,12 +35,19 @@ import (
 const (
 	defaultInitialDelaySeconds = 10
 	defaultWaitTimeoutSeconds  = 10
+
+	// statusSyncInterval is the minimum interval to sync the frequently
+	// changed warden info such as latency into cluster status
+	statusSyncInterval = time.Minute
 )
 
 // Scout collects information from warden
 type Scout struct {
-	// LastHeartbeat record last heartbeat form warden reporter
-	LastHeartbeat time.Time
+	// heartbeatMu guards lastHeartbeat which is read by metrics collector
+	heartbeatMu sync.RWMutex
+
+	// lastHeartbeat record last heartbeat form warden reporter
+	lastHeartbeat time.Time
 
 	// WaitTimeoutSeconds that heartbeat not receive timeout
 	WaitTimeoutSeconds int
@@ -62,8 +70,21 @@ type Scout struct {
 	// client k8s client
 	client client.Client
 
+	// MaintenanceReader reads maintenance of cluster on ill tick, it should
+	// be the cache of pivot cluster as the same as other maintenance checks
+	MaintenanceReader client.Reader
+
 	// clusterState shows the real-time status for cluster
 	clusterState v1.ClusterState
+
+	// lease is the latest lease of cluster known by scout
+	lease *coordinationv1.Lease
+
+	// syncedInfo is the warden info last synced to cluster status
+	syncedInfo *WardenInfo
+
+	// lastStatusSync is the time of last status sync
+	lastStatusSync time.Time
 }
 
 // WardenInfo contains intelligence within communication
@@ -73,6 +94,27 @@ type WardenInfo struct {
 
 	// ReportTime the time warden start to report
 	ReportTime time.Time `json:"reportTime"`
+
+	// Latency is the round-trip time of last heartbeat
+	Latency time.Duration `json:"latency,omitempty"`
+
+	// WardenVersion is the build version of warden
+	WardenVersion string `json:"wardenVersion,omitempty"`
+
+	// KubernetesVersion is the version of cluster kube-apiserver
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
+	// NodeCount is the number of nodes in cluster
+	NodeCount *int32 `json:"nodeCount,omitempty"`
+
+	// PodCount is the number of pods in cluster
+	PodCount *int32 `json:"podCount,omitempty"`
+
+	// Components is the readiness of components of warden
+	Components []v1.ComponentStatus `json:"components,omitempty"`
+
+	// MetricsAvailable indicates if metrics api of cluster is available
+	MetricsAvailable *bool `json:"metricsAvailable,omitempty"`
 }
 
 func NewScout(cluster string, initialDelay, waitTimeoutSeconds int, cli client.Client, stopCh chan struct{}) *Scout {
@@ -89,6 +131,7 @@ func NewScout(cluster string, initialDelay, waitTimeoutSeconds int, cli client.C
 		InitialDelaySeconds: initialDelay,
 		WaitTimeoutSeconds:  waitTimeoutSeconds,
 		client:              cli,
+		MaintenanceReader:   cli,
 		StopCh:              stopCh,
 		Once:                &sync.Once{},
 	}
@@ -103,6 +146,19 @@ func (s *Scout) ClusterHealth() v1.ClusterState {
 	return s.clusterState
 }
 
+// LastHeartbeat returns the time of last heartbeat form warden reporter
+func (s *Scout) LastHeartbeat() time.Time {
+	s.heartbeatMu.RLock()
+	defer s.heartbeatMu.RUnlock()
+	return s.lastHeartbeat
+}
+
+func (s *Scout) setLastHeartbeat(t time.Time) {
+	s.heartbeatMu.Lock()
+	s.lastHeartbeat = t
+	s.heartbeatMu.Unlock()
+}
+
 // Collect will scout a specified warden of cluster
 func (s *Scout) Collect(ctx context.Context) {
 	ticker := time.NewTicker(time.Duration(s.WaitTimeoutSeconds) * time.Second)
@@ -122,29 +178,33 @@ func (s *Scout) Collect(ctx context.Context) {
 	}
 }
 
-// healthWarden do callback when receive heartbeat first
-// todo(weilaaa): populate network delay with watden info
+// healthWarden do callback when receive heartbeat, the heartbeat is recorded
+// by lease and cluster status only be updated when cluster state or warden
+// info changed.
 func (s *Scout) healthWarden(ctx context.Context, info WardenInfo) {
-	cluster := &v1.Cluster{}
-	err := s.client.Get(ctx, types.NamespacedName{Name: s.Cluster}, cluster)
+	now := time.Now()
+	s.setLastHeartbeat(now)
+
+	err := s.renewLease(ctx, now)
 	if err != nil {
-		clog.Error(err.Error())
+		clog.Error("renew lease of cluster %v failed: %v", s.Cluster, err)
 		return
 	}
 
-	if s.clusterState != v1.ClusterNormal {
-		clog.Info("cluster %v connected", cluster.Name)
+	if !s.needSyncStatus(info, now) {
+		return
 	}
 
-	s.LastHeartbeat = time.Now()
+	if s.clusterState != v1.ClusterNormal {
+		clog.Info("cluster %v connected", s.Cluster)
+	}
 
 	updateFn := func(obj *v1.Cluster) {
-		state := v1.ClusterNormal
-		obj.Status.State = &state
-		obj.Status.Reason = fmt.Sprintf("receive heartbeat from cluster %s", s.Cluster)
-		obj.Status.LastHeartbeat = &metav1.Time{Time: s.LastHeartbeat}
+		obj.Status.LastHeartbeat = &metav1.Time{Time: now}
+		setWardenInfo(obj, info)
 	}
 
+	cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: s.Cluster}}
 	err = utils.UpdateClusterStatus(ctx, s.client, cluster, updateFn)
 	if err != nil {
 		clog.Error(err.Error())
@@ -152,65 +212,67 @@ func (s *Scout) healthWarden(ctx context.Context, info WardenInfo) {
 	}
 
 	s.clusterState = v1.ClusterNormal
+	s.syncedInfo = &info
+	s.lastStatusSync = now
 }
 
 // illWarden do callback when warden ill
 func (s *Scout) illWarden(ctx context.Context) {
-	cluster := &v1.Cluster{}
-	err := s.client.Get(ctx, types.NamespacedName{Name: s.Cluster}, cluster)
-	if err != nil {
-		clog.Error(err.Error())
+	lease, err := s.getLease(ctx)
+	if err != nil && !errors.IsNotFound(err) {
+		clog.Error("get lease of cluster %v failed: %v", s.Cluster, err)
 		return
 	}
 
-	if !isDisconnected(cluster, s.WaitTimeoutSeconds) {
-		// going here means cluster heartbeat is normal
+	if !isLeaseExpired(lease, time.Now()) {
+		// going here means cluster heartbeat is normal, the lease
+		// may be renewed by other KubeCube instances
 
 		if s.clusterState != v1.ClusterNormal {
-			clog.Info("cluster %v connected", cluster.Name)
+			clog.Info("cluster %v connected", s.Cluster)
 		}
 
-		s.LastHeartbeat = cluster.Status.LastHeartbeat.Time
+		s.setLastHeartbeat(lease.Spec.RenewTime.Time)
 		s.clusterState = v1.ClusterNormal
 		return
 	}
 
 	if s.clusterState == v1.ClusterNormal {
+		lastHeartbeat := s.LastHeartbeat()
+
+		// cluster under maintenance may be disconnected as expected, keep
+		// it normal until maintenance cleared to suppress alert
+		inMaintenance, err := utils.IsClusterInMaintenance(ctx, s.MaintenanceReader, s.Cluster)
+		if err != nil {
+			clog.Warn("get maintenance of cluster %v failed: %v", s.Cluster, err)
+		}
+		if inMaintenance {
+			clog.Debug("cluster %v under maintenance disconnected, last heartbeat: %v", s.Cluster, lastHeartbeat)
+			return
+		}
+
 		reason := fmt.Sprintf("cluster %s disconnected", s.Cluster)
 
+		clog.Warn("%v, last heartbeat: %v", reason, lastHeartbeat)
+
 		updateFn := func(obj *v1.Cluster) {
-			state := v1.ClusterAbnormal
-			obj.Status.State = &state
-			obj.Status.Reason = reason
-			obj.Status.LastHeartbeat = &metav1.Time{Time: s.LastHeartbeat}
+			obj.Status.LastHeartbeat = &metav1.Time{Time: lastHeartbeat}
+			v1.SetClusterCondition(&obj.Status, metav1.Condition{
+				Type:               v1.ClusterHeartbeatHealthy,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: obj.Generation,
+				Reason:             v1.ReasonHeartbeatTimeout,
+				Message:            reason,
+			})
 		}
 
-		clog.Warn("%v, last heartbeat: %v", reason, s.LastHeartbeat)
-
-		err := utils.UpdateClusterStatus(ctx, s.client, cluster, updateFn)
+		cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: s.Cluster}}
+		err = utils.UpdateClusterStatus(ctx, s.client, cluster, updateFn)
 		if err != nil {
 			clog.Error(err.Error())
+			return
 		}
 	}
 
 	s.clusterState = v1.ClusterAbnormal
 }
-
-// isDisconnected determines the health of the cluster
-// todo: consider use release do that
-func isDisconnected(cluster *v1.Cluster, waitTimeoutSecond int) bool {
-	clog.Debug("cluster %v, status: %v, time now: %v", cluster.Name, *cluster.Status.State, time.Now().String())
-
-	// has no LastHeartbeat return directly
-	if cluster.Status.LastHeartbeat == nil {
-		return true
-	}
-
-	// if sub time less than timeout setting, we consider the cluster is healthy
-	v := time.Now().Sub(cluster.Status.LastHeartbeat.Time)
-	if v.Milliseconds() < (time.Duration(waitTimeoutSecond) * time.Second).Milliseconds() {
-		return false
-	}
-
-	return true
-}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubecube-io/kubecube/pkg/apis"
+	v1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+func newTestScout(objs ...runtime.Object) (*Scout, client.Client) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apis.AddToScheme(scheme)
+
+	cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1", UID: "uid-1"}}
+	objs = append(objs, cluster)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+
+	return NewScout("member-1", 0, 0, cli, make(chan struct{})), cli
+}
+
+func getState(t *testing.T, cli client.Client) *v1.ClusterState {
+	cluster := &v1.Cluster{}
+	err := cli.Get(context.Background(), types.NamespacedName{Name: "member-1"}, cluster)
+	assert.Nil(t, err)
+	return cluster.Status.State
+}
+
+func TestHealthWarden(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s, cli := newTestScout()
+
+	s.healthWarden(ctx, WardenInfo{Cluster: "member-1", ReportTime: time.Now()})
+
+	// lease created and owned by cluster
+	lease := &coordinationv1.Lease{}
+	err := cli.Get(ctx, types.NamespacedName{Name: LeaseName("member-1"), Namespace: env.CubeNamespace()}, lease)
+	assert.Nil(err)
+	assert.Equal("member-1", *lease.Spec.HolderIdentity)
+	assert.Equal(int32(defaultWaitTimeoutSeconds), *lease.Spec.LeaseDurationSeconds)
+	assert.Len(lease.OwnerReferences, 1)
+	assert.Equal("uid-1", string(lease.OwnerReferences[0].UID))
+
+	// cluster status converts to normal
+	assert.Equal(v1.ClusterNormal, s.ClusterHealth())
+	state := getState(t, cli)
+	assert.NotNil(state)
+	assert.Equal(v1.ClusterNormal, *state)
+
+	// heartbeat within renew interval should not write lease
+	rv := lease.ResourceVersion
+	s.healthWarden(ctx, WardenInfo{Cluster: "member-1", ReportTime: time.Now()})
+	err = cli.Get(ctx, types.NamespacedName{Name: LeaseName("member-1"), Namespace: env.CubeNamespace()}, lease)
+	assert.Nil(err)
+	assert.Equal(rv, lease.ResourceVersion)
+}
+
+func TestIllWarden(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	renewTime := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: LeaseName("member-1"), Namespace: env.CubeNamespace()},
+		Spec: coordinationv1.LeaseSpec{
+			LeaseDurationSeconds: int32Ptr(defaultWaitTimeoutSeconds),
+			RenewTime:            &renewTime,
+		},
+	}
+
+	s, cli := newTestScout(lease)
+
+	// lease renewed by other instance keeps cluster normal without writing status
+	s.illWarden(ctx)
+	assert.Equal(v1.ClusterNormal, s.ClusterHealth())
+	assert.Nil(getState(t, cli))
+
+	// lease expired converts cluster to abnormal
+	expired := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	s.lease.Spec.RenewTime = &expired
+	err := cli.Update(ctx, s.lease)
+	assert.Nil(err)
+
+	s.illWarden(ctx)
+	assert.Equal(v1.ClusterAbnormal, s.ClusterHealth())
+	state := getState(t, cli)
+	assert.NotNil(state)
+	assert.Equal(v1.ClusterAbnormal, *state)
+
+	cluster := &v1.Cluster{}
+	err = cli.Get(ctx, types.NamespacedName{Name: "member-1"}, cluster)
+	assert.Nil(err)
+	assert.True(meta.IsStatusConditionFalse(cluster.Status.Conditions, v1.ClusterHeartbeatHealthy))
+}
+
+func TestIsLeaseExpired(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	renewTime := metav1.NewMicroTime(now.Add(-5 * time.Second))
+	lease := &coordinationv1.Lease{
+		Spec: coordinationv1.LeaseSpec{
+			LeaseDurationSeconds: int32Ptr(10),
+			RenewTime:            &renewTime,
+		},
+	}
+
+	assert.True(isLeaseExpired(nil, now))
+	assert.False(isLeaseExpired(lease, now))
+	assert.True(isLeaseExpired(lease, now.Add(5*time.Second)))
+
+	lease.Spec.RenewTime = nil
+	assert.True(isLeaseExpired(lease, now))
+}
+
+func TestNeedSyncStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	s, _ := newTestScout()
+	now := time.Now()
+	info := WardenInfo{
+		Cluster:           "member-1",
+		WardenVersion:     "v1.0.0",
+		KubernetesVersion: "v1.20.6",
+		Components:        []v1.ComponentStatus{{Name: "syncmgr", Ready: true}},
+	}
+
+	// cluster state not normal
+	assert.True(s.needSyncStatus(info, now))
+
+	s.clusterState = v1.ClusterNormal
+	s.syncedInfo = &info
+	s.lastStatusSync = now
+
+	// nothing changed
+	latencyChanged := info
+	latencyChanged.Latency = time.Second
+	assert.False(s.needSyncStatus(latencyChanged, now.Add(time.Second)))
+	assert.True(s.needSyncStatus(latencyChanged, now.Add(statusSyncInterval)))
+
+	// components changed
+	componentChanged := info
+	componentChanged.Components = []v1.ComponentStatus{{Name: "syncmgr", Ready: false}}
+	assert.True(s.needSyncStatus(componentChanged, now.Add(time.Second)))
+
+	// version changed
+	versionChanged := info
+	versionChanged.KubernetesVersion = "v1.22.0"
+	assert.True(s.needSyncStatus(versionChanged, now.Add(time.Second)))
+}
+
+func TestSetWardenInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := int32(3)
+	info := WardenInfo{
+		Latency:           20 * time.Millisecond,
+		WardenVersion:     "v1.0.0",
+		KubernetesVersion: "v1.20.6",
+		NodeCount:         &nodes,
+		Components: []v1.ComponentStatus{
+			{Name: "apiserver", Ready: true},
+			{Name: "syncmgr", Ready: false},
+		},
+	}
+
+	cluster := &v1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+	status := &cluster.Status
+	setWardenInfo(cluster, info)
+
+	assert.Equal("v1.0.0", status.WardenVersion)
+	assert.Equal("v1.20.6", status.KubernetesVersion)
+	assert.Equal(20*time.Millisecond, status.Latency.Duration)
+	assert.Equal(int32(3), *status.NodeCount)
+	assert.Nil(status.PodCount)
+	assert.Equal(v1.ClusterNormal, *status.State)
+
+	// metrics not reported by warden
+	assert.Nil(meta.FindStatusCondition(status.Conditions, v1.ClusterMetricsAvailable))
+	assert.True(meta.IsStatusConditionTrue(status.Conditions, v1.ClusterHeartbeatHealthy))
+	assert.True(meta.IsStatusConditionFalse(status.Conditions, v1.ClusterWritable))
+
+	components := meta.FindStatusCondition(status.Conditions, v1.ClusterComponentsReady)
+	assert.NotNil(components)
+	assert.Equal(metav1.ConditionFalse, components.Status)
+	assert.Contains(components.Message, "syncmgr")
+
+	metricsAvailable := true
+	info.MetricsAvailable = &metricsAvailable
+	info.Components[1].Ready = true
+	setWardenInfo(cluster, info)
+	assert.True(meta.IsStatusConditionTrue(status.Conditions, v1.ClusterComponentsReady))
+	assert.True(meta.IsStatusConditionTrue(status.Conditions, v1.ClusterMetricsAvailable))
+}
+
+func TestIllWardenInMaintenance(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	s, cli := newTestScout()
+	s.clusterState = v1.ClusterNormal
+
+	cluster := &v1.Cluster{}
+	err := cli.Get(ctx, types.NamespacedName{Name: "member-1"}, cluster)
+	assert.Nil(err)
+	cluster.Spec.Maintenance = true
+	err = cli.Update(ctx, cluster)
+	assert.Nil(err)
+
+	// lease not found under maintenance does not alert
+	s.illWarden(ctx)
+	assert.Equal(v1.ClusterNormal, s.ClusterHealth())
+	assert.Nil(getState(t, cli))
+
+	// alert once maintenance cleared
+	err = cli.Get(ctx, types.NamespacedName{Name: "member-1"}, cluster)
+	assert.Nil(err)
+	cluster.Spec.Maintenance = false
+	err = cli.Update(ctx, cluster)
+	assert.Nil(err)
+
+	s.illWarden(ctx)
+	assert.Equal(v1.ClusterAbnormal, s.ClusterHealth())
+}
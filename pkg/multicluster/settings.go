@@ -0,0 +1,183 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+// ClientSettings tunes the clients connect to cluster, zero value
+// of any setting means the default of client-go is used
+type ClientSettings struct {
+	QPS          float32
+	Burst        int
+	Timeout      time.Duration
+	ResyncPeriod time.Duration
+}
+
+// defaultClientSettings is used for the settings not given by cluster
+var defaultClientSettings ClientSettings
+
+// SetDefaultClientSettings sets the global default client settings, it
+// should be called before any cluster connected.
+func SetDefaultClientSettings(settings ClientSettings) {
+	defaultClientSettings = settings
+}
+
+// ClientSettingsOf returns the client settings of cluster merged with global defaults
+func ClientSettingsOf(cluster clusterv1.Cluster) ClientSettings {
+	settings := defaultClientSettings
+
+	s := cluster.Spec.ClientSettings
+	if s == nil {
+		return settings
+	}
+
+	if s.QPS > 0 {
+		settings.QPS = float32(s.QPS)
+	}
+	if s.Burst > 0 {
+		settings.Burst = int(s.Burst)
+	}
+	if s.Timeout != nil {
+		settings.Timeout = s.Timeout.Duration
+	}
+	if s.ResyncPeriod != nil {
+		settings.ResyncPeriod = s.ResyncPeriod.Duration
+	}
+
+	return settings
+}
+
+// applyTo sets QPS, burst and timeout into config
+func (s ClientSettings) applyTo(config *rest.Config) {
+	if s.QPS > 0 {
+		config.QPS = s.QPS
+	}
+	if s.Burst > 0 {
+		config.Burst = s.Burst
+	}
+	if s.Timeout > 0 {
+		config.Timeout = s.Timeout
+	}
+}
+
+// wrapTransport makes the round tripper honor QPS, burst and timeout,
+// the config of rest client does not take effect on raw transport.
+func (s ClientSettings) wrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if s.QPS <= 0 && s.Timeout <= 0 {
+		return rt
+	}
+
+	srt := &settingsRoundTripper{rt: rt, timeout: s.Timeout}
+	if s.QPS > 0 {
+		burst := s.Burst
+		if burst <= 0 {
+			burst = rest.DefaultBurst
+		}
+		srt.limiter = flowcontrol.NewTokenBucketRateLimiter(s.QPS, burst)
+	}
+
+	return srt
+}
+
+type settingsRoundTripper struct {
+	rt      http.RoundTripper
+	limiter flowcontrol.RateLimiter
+	timeout time.Duration
+}
+
+func (rt *settingsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.limiter != nil {
+		if err := rt.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	if rt.timeout <= 0 || isLongRunning(req) {
+		return rt.rt.RoundTrip(req)
+	}
+
+	// the timeout covers reading of response body as http.Client does
+	ctx, cancel := context.WithTimeout(req.Context(), rt.timeout)
+	resp, err := rt.rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// WrappedRoundTripper implements net.RoundTripperWrapper
+func (rt *settingsRoundTripper) WrappedRoundTripper() http.RoundTripper {
+	return rt.rt
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// longRunningSubresources are subresources of pod streaming until client or
+// container exits, they may arrive without upgrade header such as through
+// websocket proxies
+var longRunningSubresources = sets.NewString("exec", "attach", "portforward")
+
+// isLongRunning tells if request is watch, log following or upgraded
+// connection such as exec and port-forward
+func isLongRunning(req *http.Request) bool {
+	if len(req.Header.Get("Upgrade")) > 0 {
+		return true
+	}
+
+	query := req.URL.Query()
+	if isTrue(query.Get("watch")) || isTrue(query.Get("follow")) {
+		return true
+	}
+
+	// path of pod subresource ends with pods/{name}/{subresource}
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	if n := len(parts); n >= 3 && parts[n-3] == "pods" && longRunningSubresources.Has(parts[n-1]) {
+		return true
+	}
+
+	return strings.Contains(req.URL.Path, "/watch/")
+}
+
+// isTrue parses bool query parameter as kube-apiserver does, such as 1 and True
+func isTrue(v string) bool {
+	b, err := strconv.ParseBool(v)
+	return err == nil && b
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+func TestClientSettingsOf(t *testing.T) {
+	assert := assert.New(t)
+
+	defer SetDefaultClientSettings(ClientSettings{})
+	SetDefaultClientSettings(ClientSettings{QPS: 50, Burst: 100, Timeout: time.Minute})
+
+	cluster := clusterv1.Cluster{}
+	assert.Equal(ClientSettings{QPS: 50, Burst: 100, Timeout: time.Minute}, ClientSettingsOf(cluster))
+
+	cluster.Spec.ClientSettings = &clusterv1.ClientSettings{
+		QPS:          20,
+		Timeout:      &metav1.Duration{Duration: 5 * time.Second},
+		ResyncPeriod: &metav1.Duration{Duration: time.Hour},
+	}
+	settings := ClientSettingsOf(cluster)
+	assert.Equal(ClientSettings{QPS: 20, Burst: 100, Timeout: 5 * time.Second, ResyncPeriod: time.Hour}, settings)
+
+	config := &rest.Config{}
+	settings.applyTo(config)
+	assert.Equal(float32(20), config.QPS)
+	assert.Equal(100, config.Burst)
+	assert.Equal(5*time.Second, config.Timeout)
+}
+
+func TestWrapTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	assert.Equal(http.DefaultTransport, ClientSettings{}.wrapTransport(http.DefaultTransport))
+
+	rt := ClientSettings{Timeout: 50 * time.Millisecond}.wrapTransport(http.DefaultTransport)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/pods", nil)
+	_, err := rt.RoundTrip(req)
+	assert.NotNil(err)
+
+	// watch is not limited by timeout
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/v1/pods?watch=true", nil)
+	resp, err := rt.RoundTrip(req)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Nil(resp.Body.Close())
+}
+
+func TestIsLongRunning(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]bool{
+		"/api/v1/pods":                                false,
+		"/api/v1/pods?watch=false":                    false,
+		"/api/v1/pods?watch=true":                     true,
+		"/api/v1/pods?watch=1":                        true,
+		"/api/v1/pods?watch=True":                     true,
+		"/api/v1/watch/pods":                          true,
+		"/api/v1/namespaces/ns/pods/p/log":            false,
+		"/api/v1/namespaces/ns/pods/p/log?follow=1":   true,
+		"/api/v1/namespaces/ns/pods/p/exec?command=s": true,
+		"/api/v1/namespaces/ns/pods/p/attach":         true,
+		"/api/v1/namespaces/ns/pods/p/portforward":    true,
+		"/api/v1/namespaces/ns/pods/exec":             false,
+	}
+	for url, want := range tests {
+		req, _ := http.NewRequest(http.MethodPost, url, nil)
+		assert.Equal(want, isLongRunning(req), url)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+	req.Header.Set("Upgrade", "SPDY/3.1")
+	assert.True(isLongRunning(req))
+}
@@ -19,28 +19,48 @@ package multicluster
 import (
 	"context"
 	"fmt"
-	"github.com/kubecube-io/kubecube/pkg/utils/informer"
+	"time"
 
-	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kubecube-io/kubecube/pkg/apis"
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/informer"
 	"github.com/kubecube-io/kubecube/pkg/utils/keys"
 	"github.com/kubecube-io/kubecube/pkg/utils/worker"
 )
 
+const (
+	// reconnectBaseDelay and reconnectMaxDelay bound the exponential backoff
+	// of reconnecting unreachable cluster: 5s, 10s, 20s ... 5m, 5m
+	reconnectBaseDelay = 5 * time.Second
+	reconnectMaxDelay  = 5 * time.Minute
+
+	// probeTimeout is the timeout to probe kube-apiserver of cluster
+	probeTimeout = 10 * time.Second
+)
+
 // SyncMgr only running when process as subsidiary
 type SyncMgr struct {
 	cache       cache.Cache
+	client      client.Client
 	Informer    cache.Informer
 	Worker      worker.Interface
 	isWithScout bool
+
+	// backoff counts the failed attempts of connecting cluster
+	// and tells how long to wait before next attempt
+	backoff workqueue.RateLimiter
 }
 
 func NewSyncMgr(config *rest.Config, isWithScout bool) (*SyncMgr, error) {
@@ -52,13 +72,24 @@ func NewSyncMgr(config *rest.Config, isWithScout bool) (*SyncMgr, error) {
 		return nil, err
 	}
 
+	cli, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+
 	cluster := clusterv1.Cluster{}
 	im, err := c.GetInformer(context.Background(), &cluster)
 	if err != nil {
 		return nil, err
 	}
 
-	return &SyncMgr{cache: c, Informer: im, isWithScout: isWithScout}, nil
+	return &SyncMgr{
+		cache:       c,
+		client:      cli,
+		Informer:    im,
+		isWithScout: isWithScout,
+		backoff:     workqueue.NewItemExponentialFailureRateLimiter(reconnectBaseDelay, reconnectMaxDelay),
+	}, nil
 }
 
 func NewSyncMgrWithDefaultSetting(config *rest.Config, isWithScout bool) (*SyncMgr, error) {
@@ -132,9 +163,16 @@ func (m *SyncMgr) OnClusterDelete(obj interface{}) {
 func (m *SyncMgr) OnClusterUpdate(oldObj, newObj interface{}) {
 	oldCluster := oldObj.(*clusterv1.Cluster)
 	newCluster := newObj.(*clusterv1.Cluster)
-	initFailedState, ProcessingState := clusterv1.ClusterInitFailed, clusterv1.ClusterProcessing
-	if oldCluster.Status.State == &initFailedState &&
-		newCluster.Status.State == &ProcessingState {
+	oldState, newState := stateOf(oldCluster), stateOf(newCluster)
+	retrying := oldState == clusterv1.ClusterInitFailed && newState == clusterv1.ClusterProcessing
+	// cluster turns unreachable should be reconnected with backoff
+	broken := oldState != newState && isUnreachableState(newState)
+	// kubeconfig changed means the credential of cluster rotated, the rotation
+	// of kubeconfig in secret is known by the rotation time of status
+	rotated := clusterv1.IsKubeConfigChanged(oldCluster, newCluster) ||
+		!oldCluster.Status.LastCredentialRotation.Equal(newCluster.Status.LastCredentialRotation)
+	tuned := clusterv1.IsConnectionChanged(oldCluster, newCluster)
+	if retrying || broken || rotated || tuned {
 		key, err := ClusterWideKeyFunc(newObj)
 		if err != nil {
 			return
@@ -157,7 +195,8 @@ func (m *SyncMgr) ReconcileCluster(key worker.QueueKey) error {
 	if err != nil {
 		// delete internal cluster if cluster was deleted
 		if errors.IsNotFound(err) {
-			err = ManagerImpl.Del(cluster.Name)
+			m.backoff.Forget(key)
+			err = ManagerImpl.Del(ckey.Name)
 			if err != nil {
 				clog.Warn(err.Error())
 			}
@@ -166,26 +205,144 @@ func (m *SyncMgr) ReconcileCluster(key worker.QueueKey) error {
 		return err
 	}
 
+	err = m.connectCluster(*cluster, m.backoff.NumRequeues(key) > 0)
+	if err != nil {
+		m.reconnectLater(key, cluster, err)
+		return nil
+	}
+
+	m.reconnected(key, cluster)
+
+	return nil
+}
+
+// connectCluster ensures the internal cluster of cluster is connected. The
+// internal cluster is rebuilt if the credential or client settings
+// of cluster changed, or if it is abnormal and kube-apiserver turns reachable
+// after reconnecting.
+func (m *SyncMgr) connectCluster(cluster clusterv1.Cluster, reconnecting bool) error {
+	internalCluster, abnormal := ManagerImpl.Get(cluster.Name)
+	if internalCluster != nil {
+		resolved, err := ResolveCluster(cluster)
+		if err != nil {
+			return fmt.Errorf("load kubeconfig of cluster %v failed: %v", cluster.Name, err)
+		}
+
+		switch {
+		case internalCluster.IsKubeConfigChanged(resolved), internalCluster.IsConnectionChanged(resolved):
+			err = ManagerImpl.Rebuild(resolved)
+			if err != nil {
+				return err
+			}
+		case abnormal != nil:
+			err = probeCluster(resolved)
+			if err != nil {
+				return err
+			}
+			// kube-apiserver reachable at first attempt means the connection
+			// is fine, the abnormal is up to warden
+			if !reconnecting {
+				return nil
+			}
+			return ManagerImpl.Rebuild(resolved)
+		}
+	}
+
 	if m.isWithScout {
-		err = AddInternalClusterWithScout(*cluster)
+		err := AddInternalClusterWithScout(cluster)
 		if err != nil {
-			clog.Error("add internal cluster %v failed: %v", cluster.Name, err)
-			return err
+			return fmt.Errorf("add internal cluster %v failed: %v", cluster.Name, err)
 		}
 
 		// start to scout for warden
 		err = ManagerImpl.ScoutFor(context.Background(), cluster.Name)
 		if err != nil {
-			clog.Error("scout for %v warden failed: %v", cluster.Name, err)
-			return err
+			return fmt.Errorf("scout for %v warden failed: %v", cluster.Name, err)
 		}
 	} else {
-		err = AddInternalCluster(*cluster)
+		err := AddInternalCluster(cluster)
 		if err != nil {
-			clog.Error("add internal cluster %v failed: %v", cluster.Name, err)
-			return err
+			return fmt.Errorf("add internal cluster %v failed: %v", cluster.Name, err)
 		}
 	}
 
 	return nil
 }
+
+// reconnectLater records the failed attempt into status of cluster and
+// requeues the cluster with exponential backoff
+func (m *SyncMgr) reconnectLater(key worker.QueueKey, cluster *clusterv1.Cluster, connectErr error) {
+	delay := m.backoff.When(key)
+	attempts := m.backoff.NumRequeues(key)
+
+	clog.Warn("connect to cluster %v failed %v times, retry after %v: %v", cluster.Name, attempts, delay, connectErr)
+
+	now := metav1.Now()
+	err := utils.UpdateClusterStatus(context.Background(), m.client, cluster, func(c *clusterv1.Cluster) {
+		c.Status.ReconnectAttempts = int32(attempts)
+		c.Status.LastReconnectError = connectErr.Error()
+		c.Status.LastReconnectTime = &now
+	})
+	if err != nil {
+		clog.Warn("update reconnect status of cluster %v failed: %v", cluster.Name, err)
+	}
+
+	m.Worker.AddAfter(key, delay)
+}
+
+// reconnected resets the backoff and the reconnect status of cluster
+func (m *SyncMgr) reconnected(key worker.QueueKey, cluster *clusterv1.Cluster) {
+	if m.backoff.NumRequeues(key) > 0 {
+		clog.Info("cluster %v reconnected after %v attempts", cluster.Name, m.backoff.NumRequeues(key))
+	}
+	m.backoff.Forget(key)
+
+	if cluster.Status.ReconnectAttempts == 0 && len(cluster.Status.LastReconnectError) == 0 {
+		return
+	}
+
+	err := utils.UpdateClusterStatus(context.Background(), m.client, cluster, func(c *clusterv1.Cluster) {
+		c.Status.ReconnectAttempts = 0
+		c.Status.LastReconnectError = ""
+	})
+	if err != nil {
+		clog.Warn("reset reconnect status of cluster %v failed: %v", cluster.Name, err)
+	}
+}
+
+// probeCluster tells if kube-apiserver of cluster is reachable
+func probeCluster(cluster clusterv1.Cluster) error {
+	config, err := RestConfigOf(cluster)
+	if err != nil {
+		return err
+	}
+	config.Timeout = probeTimeout
+
+	cli, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.ServerVersion()
+	if err != nil {
+		return fmt.Errorf("probe kube-apiserver of cluster %v failed: %v", cluster.Name, err)
+	}
+
+	return nil
+}
+
+func stateOf(cluster *clusterv1.Cluster) clusterv1.ClusterState {
+	if cluster.Status.State == nil {
+		return ""
+	}
+	return *cluster.Status.State
+}
+
+func isUnreachableState(state clusterv1.ClusterState) bool {
+	switch state {
+	case clusterv1.ClusterInitFailed, clusterv1.ClusterReconnectedFailed, clusterv1.ClusterAbnormal:
+		return true
+	default:
+		return false
+	}
+}
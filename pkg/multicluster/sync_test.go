@@ -0,0 +1,125 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubecube-io/kubecube/pkg/apis"
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/keys"
+	"github.com/kubecube-io/kubecube/pkg/utils/worker"
+)
+
+// recordWorker records items added to queue instead of processing them
+type recordWorker struct {
+	worker.Interface
+	added  []interface{}
+	delays []time.Duration
+}
+
+func (w *recordWorker) AddRateLimited(item interface{}) {
+	w.added = append(w.added, item)
+}
+
+func (w *recordWorker) AddAfter(item interface{}, duration time.Duration) {
+	w.added = append(w.added, item)
+	w.delays = append(w.delays, duration)
+}
+
+func newTestSyncMgr(cluster *clusterv1.Cluster) (*SyncMgr, *recordWorker) {
+	scheme := runtime.NewScheme()
+	_ = apis.AddToScheme(scheme)
+
+	w := &recordWorker{}
+	return &SyncMgr{
+		client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster).Build(),
+		Worker:  w,
+		backoff: workqueue.NewItemExponentialFailureRateLimiter(reconnectBaseDelay, reconnectMaxDelay),
+	}, w
+}
+
+func TestReconnectWithBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+	m, w := newTestSyncMgr(cluster)
+	key := keys.ClusterWideKey{Name: cluster.Name}
+
+	for i := 0; i < 3; i++ {
+		m.reconnectLater(key, cluster, fmt.Errorf("connection refused %v", i))
+	}
+	assert.Equal([]time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second}, w.delays)
+
+	got := &clusterv1.Cluster{}
+	assert.Nil(m.client.Get(context.Background(), types.NamespacedName{Name: cluster.Name}, got))
+	assert.Equal(int32(3), got.Status.ReconnectAttempts)
+	assert.Equal("connection refused 2", got.Status.LastReconnectError)
+	assert.NotNil(got.Status.LastReconnectTime)
+
+	m.reconnected(key, got)
+	assert.Equal(0, m.backoff.NumRequeues(key))
+
+	got = &clusterv1.Cluster{}
+	assert.Nil(m.client.Get(context.Background(), types.NamespacedName{Name: cluster.Name}, got))
+	assert.Equal(int32(0), got.Status.ReconnectAttempts)
+	assert.Empty(got.Status.LastReconnectError)
+
+	// backoff starts over after reconnected
+	m.reconnectLater(key, got, fmt.Errorf("connection refused"))
+	assert.Equal(5*time.Second, w.delays[len(w.delays)-1])
+}
+
+func TestOnClusterUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	newCluster := func(state clusterv1.ClusterState, attempts int32) *clusterv1.Cluster {
+		return &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "member-1"},
+			Status:     clusterv1.ClusterStatus{State: &state, ReconnectAttempts: attempts},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		old, new *clusterv1.Cluster
+		enqueued bool
+	}{
+		{"retrying", newCluster(clusterv1.ClusterInitFailed, 0), newCluster(clusterv1.ClusterProcessing, 0), true},
+		{"turns abnormal", newCluster(clusterv1.ClusterNormal, 0), newCluster(clusterv1.ClusterAbnormal, 0), true},
+		{"reconnect timeout", newCluster(clusterv1.ClusterInitFailed, 0), newCluster(clusterv1.ClusterReconnectedFailed, 0), true},
+		{"reconnect status changed", newCluster(clusterv1.ClusterAbnormal, 1), newCluster(clusterv1.ClusterAbnormal, 2), false},
+		{"recovered", newCluster(clusterv1.ClusterAbnormal, 0), newCluster(clusterv1.ClusterNormal, 0), false},
+	}
+
+	for _, tt := range tests {
+		w := &recordWorker{}
+		m := &SyncMgr{Worker: w}
+		m.OnClusterUpdate(tt.old, tt.new)
+		assert.Equal(tt.enqueued, len(w.added) == 1, tt.name)
+	}
+}
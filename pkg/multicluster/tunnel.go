@@ -0,0 +1,62 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/tunnel"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
+)
+
+// TunnelServer accepts the tunnels opened by warden of clusters in tunnel mode.
+// Tunnel is held by the KubeCube instance which warden connected to, so clusters
+// in tunnel mode are reachable only from that instance.
+var TunnelServer = tunnel.NewServer(authorizeTunnel)
+
+// authorizeTunnel allows the tunnel of cluster in tunnel mode only, and the
+// agent must sign the request with the kubeconfig that cluster registered with
+func authorizeTunnel(ctx context.Context, cluster string, r *http.Request) error {
+	localCluster, err := ManagerImpl.Get(constants.LocalCluster)
+	if err != nil {
+		return err
+	}
+
+	c := &clusterv1.Cluster{}
+	err = localCluster.Client.Cache().Get(ctx, types.NamespacedName{Name: cluster}, c)
+	if err != nil {
+		return fmt.Errorf("get cluster %v failed: %v", cluster, err)
+	}
+
+	if c.Spec.ConnectionMode != clusterv1.ConnectionTunnel {
+		return fmt.Errorf("cluster %v is not in tunnel mode", cluster)
+	}
+
+	// read kubeconfig directly rather than caching all secrets of pivot cluster
+	credential, err := kubeconfig.LoadClusterKubeConfig(ctx, localCluster.Client.Direct(), c)
+	if err != nil {
+		return err
+	}
+
+	return tunnel.Authenticate(r, cluster, credential)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/kubecube-io/kubecube/pkg/clog"
+)
+
+// defaultRetryInterval is the interval to reconnect when tunnel closed
+const defaultRetryInterval = 5 * time.Second
+
+// Agent opens tunnel to KubeCube and pipes the connections
+// dialed through tunnel to its upstream kube-apiserver
+type Agent struct {
+	// Cluster is the name of cluster the agent running in
+	Cluster string
+
+	// PivotCubeHost is the address of KubeCube to connect
+	PivotCubeHost string
+
+	// Upstream is the address of kube-apiserver, see UpstreamOf
+	Upstream string
+
+	// RetryInterval is the interval to reconnect when tunnel closed
+	RetryInterval time.Duration
+
+	// Credential is the raw kubeconfig of cluster registered to KubeCube,
+	// agent signs the tunnel request with it to prove the ownership of cluster
+	Credential []byte
+
+	// TLSConfig is used to verify KubeCube when serves with https,
+	// the system roots are used if nil
+	TLSConfig *tls.Config
+}
+
+// Run keeps the tunnel connected util received stop signal
+func (a *Agent) Run(stop <-chan struct{}) {
+	interval := a.RetryInterval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	clog.Info("open tunnel of cluster %v to %v", a.Cluster, a.PivotCubeHost)
+
+	wait.Until(func() {
+		err := a.Connect(ctx)
+		if err != nil && ctx.Err() == nil {
+			clog.Warn("tunnel of cluster %v closed: %v", a.Cluster, err)
+		}
+	}, interval, stop)
+}
+
+// Connect opens tunnel and serves it util tunnel closed or context done
+func (a *Agent) Connect(ctx context.Context) error {
+	u, err := a.tunnelURL()
+	if err != nil {
+		return err
+	}
+
+	conn, err := a.dial(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", Protocol)
+	req.Header.Set("Authorization", "Bearer "+Sign(a.Cluster, a.Credential, time.Now()))
+
+	err = req.Write(conn)
+	if err != nil {
+		return fmt.Errorf("request tunnel failed: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return fmt.Errorf("read response of tunnel failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		return fmt.Errorf("tunnel rejected with %v: %v", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	clog.Info("tunnel of cluster %v connected", a.Cluster)
+
+	// stop serving when context done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	server := &http2.Server{}
+	server.ServeConn(withBuffered(conn, br), &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: http.HandlerFunc(a.pipe),
+	})
+
+	return fmt.Errorf("tunnel disconnected")
+}
+
+// pipe pipes the CONNECT stream to upstream
+func (a *Agent) pipe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "only CONNECT is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	upstream, err := (&net.Dialer{Timeout: dialTimeout}).DialContext(r.Context(), "tcp", a.Upstream)
+	if err != nil {
+		clog.Warn("dial upstream %v failed: %v", a.Upstream, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(upstream, r.Body)
+		errCh <- err
+	}()
+	go func() {
+		_, err := io.Copy(flushWriter{w: w}, upstream)
+		errCh <- err
+	}()
+
+	// either side closed means the connection is over
+	select {
+	case <-errCh:
+	case <-r.Context().Done():
+	}
+}
+
+func (a *Agent) tunnelURL() (*url.URL, error) {
+	host := a.PivotCubeHost
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		// default, use https as scheme
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(host, "/") + Path)
+	if err != nil {
+		return nil, err
+	}
+
+	u.RawQuery = url.Values{ClusterParam: []string{a.Cluster}}.Encode()
+
+	return u, nil
+}
+
+func (a *Agent) dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	address := u.Host
+	if len(u.Port()) == 0 {
+		if u.Scheme == "http" {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		} else {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+
+	if u.Scheme == "http" {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	config := a.TLSConfig
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	// tunnel upgrades over http/1.1 only
+	config.NextProtos = []string{"http/1.1"}
+	if len(config.ServerName) == 0 {
+		config.ServerName = u.Hostname()
+	}
+
+	return (&tls.Dialer{NetDialer: dialer, Config: config}).DialContext(ctx, "tcp", address)
+}
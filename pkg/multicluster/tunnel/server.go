@@ -0,0 +1,209 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/kubecube-io/kubecube/pkg/clog"
+)
+
+const (
+	// pingInterval and pingTimeout are used for health check of tunnel,
+	// tunnel without response of ping will be closed
+	pingInterval = 30 * time.Second
+	pingTimeout  = 15 * time.Second
+)
+
+// AuthorizeFunc decides if the agent is allowed to open tunnel for cluster,
+// it should authenticate the agent by the credential of cluster, see Authenticate
+type AuthorizeFunc func(ctx context.Context, cluster string, r *http.Request) error
+
+// Server accepts tunnels from agents and dials through them
+type Server struct {
+	sync.RWMutex
+
+	// sessions holds the tunnel of clusters, only the latest
+	// tunnel is kept when agent reconnected, agent must pass
+	// authorize before its tunnel replaces the existed one
+	sessions map[string]*http2.ClientConn
+
+	authorize AuthorizeFunc
+	transport *http2.Transport
+}
+
+// NewServer returns tunnel server, the agents are always rejected if authorize is nil
+func NewServer(authorize AuthorizeFunc) *Server {
+	return &Server{
+		sessions:  make(map[string]*http2.ClientConn),
+		authorize: authorize,
+		transport: &http2.Transport{
+			ReadIdleTimeout: pingInterval,
+			PingTimeout:     pingTimeout,
+		},
+	}
+}
+
+// ServeHTTP upgrades the request of agent to tunnel
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := r.URL.Query().Get(ClusterParam)
+	if len(cluster) == 0 {
+		http.Error(w, "cluster is required", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(r.Header.Get("Upgrade"), Protocol) {
+		http.Error(w, fmt.Sprintf("upgrade to %v is required", Protocol), http.StatusBadRequest)
+		return
+	}
+
+	if s.authorize == nil {
+		clog.Warn("reject tunnel of cluster %v from %v: no authorizer", cluster, r.RemoteAddr)
+		http.Error(w, "tunnel is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if err := s.authorize(r.Context(), cluster, r); err != nil {
+		clog.Warn("reject tunnel of cluster %v from %v: %v", cluster, r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can not be hijacked", http.StatusInternalServerError)
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		clog.Warn("hijack connection of cluster %v failed: %v", cluster, err)
+		return
+	}
+
+	_, err = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: "+Protocol+"\r\n\r\n")
+	if err != nil {
+		clog.Warn("upgrade tunnel of cluster %v failed: %v", cluster, err)
+		_ = conn.Close()
+		return
+	}
+
+	cc, err := s.transport.NewClientConn(withBuffered(conn, rw.Reader))
+	if err != nil {
+		clog.Warn("establish tunnel of cluster %v failed: %v", cluster, err)
+		_ = conn.Close()
+		return
+	}
+
+	s.add(cluster, cc)
+
+	clog.Info("tunnel of cluster %v established from %v", cluster, r.RemoteAddr)
+}
+
+// add keeps the tunnel of cluster and closes the stale one
+func (s *Server) add(cluster string, cc *http2.ClientConn) {
+	s.Lock()
+	stale := s.sessions[cluster]
+	s.sessions[cluster] = cc
+	s.Unlock()
+
+	if stale != nil {
+		_ = stale.Close()
+	}
+}
+
+// session returns the alive tunnel of cluster
+func (s *Server) session(cluster string) (*http2.ClientConn, error) {
+	s.RLock()
+	cc, ok := s.sessions[cluster]
+	s.RUnlock()
+
+	if !ok {
+		// the tunnel may be held by another KubeCube replica
+		return nil, fmt.Errorf("tunnel of cluster %v is not connected to this KubeCube instance", cluster)
+	}
+
+	if state := cc.State(); state.Closed || state.Closing {
+		s.Lock()
+		if s.sessions[cluster] == cc {
+			delete(s.sessions, cluster)
+		}
+		s.Unlock()
+		return nil, fmt.Errorf("tunnel of cluster %v is disconnected", cluster)
+	}
+
+	return cc, nil
+}
+
+// IsConnected tells if the tunnel of cluster is alive
+func (s *Server) IsConnected(cluster string) bool {
+	_, err := s.session(cluster)
+	return err == nil
+}
+
+// Dialer returns the function that dials through the tunnel of cluster. The address
+// is only informative, agent always pipes the connection to its upstream.
+func (s *Server) Dialer(cluster string) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return s.DialContext(ctx, cluster, address)
+	}
+}
+
+// DialContext opens a connection to the upstream of agent through the tunnel of cluster
+func (s *Server) DialContext(ctx context.Context, cluster, address string) (net.Conn, error) {
+	cc, err := s.session(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodConnect, "https://"+address, pr)
+	if err != nil {
+		_ = pw.Close()
+		return nil, err
+	}
+	req.ContentLength = -1
+
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		_ = pw.Close()
+		return nil, fmt.Errorf("dial %v through tunnel of cluster %v failed: %v", address, cluster, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = pw.Close()
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("dial %v through tunnel of cluster %v failed: %v", address, cluster, resp.Status)
+	}
+
+	return &streamConn{
+		body:   resp.Body,
+		writer: pw,
+		local:  tunnelAddr(cluster),
+		remote: tunnelAddr(address),
+	}, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tunnel implements reverse tunnel for clusters that KubeCube can not
+// reach directly, such as edge clusters behind NAT.
+//
+// The agent (warden) dials out to KubeCube and upgrades the connection, then
+// the roles are reversed: KubeCube speaks http2 as client over the upgraded
+// connection and the agent serves as http2 server. Every connection dialed
+// through tunnel is a http2 CONNECT stream that the agent pipes to its
+// upstream kube-apiserver, so TLS is still end to end between KubeCube and
+// kube-apiserver and the agent is not able to see or forge the traffic.
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+const (
+	// Protocol is the upgrade protocol of tunnel
+	Protocol = "kubecube-tunnel"
+
+	// Path is the api path of KubeCube that agent connects to
+	Path = constants.ApiPathRoot + "/tunnel"
+
+	// ClusterParam is the query param of cluster name the tunnel belongs to
+	ClusterParam = "cluster"
+
+	// dialTimeout is the timeout of dialing pivot and upstream
+	dialTimeout = 10 * time.Second
+
+	// maxClockSkew is the max difference between the time credential signed
+	// by agent and the time verified by server, limits replay of credential
+	maxClockSkew = 5 * time.Minute
+)
+
+// DialFunc dials the address through tunnel
+type DialFunc = func(ctx context.Context, network, address string) (net.Conn, error)
+
+// UpstreamOf returns the address of kube-apiserver the agent should pipe to
+func UpstreamOf(config *rest.Config) (string, error) {
+	host := config.Host
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+
+	if len(u.Port()) > 0 {
+		return u.Host, nil
+	}
+
+	if u.Scheme == "http" {
+		return net.JoinHostPort(u.Hostname(), "80"), nil
+	}
+
+	return net.JoinHostPort(u.Hostname(), "443"), nil
+}
+
+// Sign signs cluster at given time with the credential of cluster, the credential
+// is the raw kubeconfig of cluster that both agent and KubeCube hold
+func Sign(cluster string, credential []byte, t time.Time) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, credential)
+	mac.Write([]byte(cluster + ":" + ts))
+	return ts + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Authenticate verifies the bearer token of request is signed with the credential of cluster
+func Authenticate(r *http.Request, cluster string, credential []byte) error {
+	if len(credential) == 0 {
+		return fmt.Errorf("credential of cluster %v is empty", cluster)
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return fmt.Errorf("credential of cluster %v is required", cluster)
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+
+	i := strings.Index(token, ":")
+	if i < 0 {
+		return fmt.Errorf("malformed credential of cluster %v", cluster)
+	}
+	unix, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed credential of cluster %v", cluster)
+	}
+
+	signed := time.Unix(unix, 0)
+	if skew := time.Since(signed); skew > maxClockSkew || skew < -maxClockSkew {
+		return fmt.Errorf("credential of cluster %v is expired", cluster)
+	}
+
+	if !hmac.Equal([]byte(token), []byte(Sign(cluster, credential, signed))) {
+		return fmt.Errorf("invalid credential of cluster %v", cluster)
+	}
+
+	return nil
+}
+
+// bufferedConn is a net.Conn with the data buffered when upgrading
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// withBuffered returns conn that reads the data buffered first if any
+func withBuffered(conn net.Conn, r *bufio.Reader) net.Conn {
+	if r.Buffered() == 0 {
+		return conn
+	}
+	return &bufferedConn{Conn: conn, r: r}
+}
+
+// streamConn is a net.Conn over a http2 CONNECT stream
+type streamConn struct {
+	body   io.ReadCloser
+	writer *io.PipeWriter
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *streamConn) Read(p []byte) (int, error) {
+	return c.body.Read(p)
+}
+
+func (c *streamConn) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+func (c *streamConn) Close() error {
+	_ = c.writer.Close()
+	return c.body.Close()
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline does nothing, deadlines are not supported by stream. Requests
+// through tunnel are bounded by the context and timeout of client instead,
+// the stream is reset when the context of request done.
+func (c *streamConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline does nothing as SetDeadline
+func (c *streamConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline does nothing as SetDeadline
+func (c *streamConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// tunnelAddr is the address of a stream in tunnel
+type tunnelAddr string
+
+func (a tunnelAddr) Network() string {
+	return Protocol
+}
+
+func (a tunnelAddr) String() string {
+	return string(a)
+}
+
+// flushWriter flushes every write to make stream real-time
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newFakeAPIServer returns a https server acts like kube-apiserver of a kind cluster
+func newFakeAPIServer(cluster string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/version":
+			_, _ = fmt.Fprintf(w, `{"major":"1","minor":"20","gitVersion":"v1.20.0-%v"}`, cluster)
+		default:
+			_, _ = fmt.Fprintf(w, `{"cluster":%q,"path":%q}`, cluster, r.URL.Path)
+		}
+	}))
+}
+
+// credentialOf returns the fake kubeconfig of cluster used to sign tunnel request
+func credentialOf(cluster string) []byte {
+	return []byte("kubeconfig-of-" + cluster)
+}
+
+// authorizeByCredential authenticates agents with the credential of clusters
+func authorizeByCredential(ctx context.Context, cluster string, r *http.Request) error {
+	return Authenticate(r, cluster, credentialOf(cluster))
+}
+
+// newPivot returns a https server of KubeCube and the tls config to verify it
+func newPivot(server *Server) (*httptest.Server, *tls.Config) {
+	pivot := httptest.NewTLSServer(server)
+	pool := x509.NewCertPool()
+	pool.AddCert(pivot.Certificate())
+	return pivot, &tls.Config{RootCAs: pool}
+}
+
+func startAgent(cluster, pivot string, tlsConfig *tls.Config, upstream *httptest.Server) chan struct{} {
+	stop := make(chan struct{})
+	agent := &Agent{
+		Cluster:       cluster,
+		PivotCubeHost: pivot,
+		Upstream:      upstream.Listener.Addr().String(),
+		RetryInterval: 100 * time.Millisecond,
+		Credential:    credentialOf(cluster),
+		TLSConfig:     tlsConfig,
+	}
+	go agent.Run(stop)
+	return stop
+}
+
+func TestTunnel(t *testing.T) {
+	assert := assert.New(t)
+
+	kindA, kindB := newFakeAPIServer("kind-a"), newFakeAPIServer("kind-b")
+	defer kindA.Close()
+	defer kindB.Close()
+
+	server := NewServer(authorizeByCredential)
+	pivot, tlsConfig := newPivot(server)
+	defer pivot.Close()
+
+	stopA := startAgent("kind-a", pivot.URL, tlsConfig, kindA)
+	stopB := startAgent("kind-b", pivot.URL, tlsConfig, kindB)
+	defer close(stopB)
+
+	err := wait.PollImmediate(50*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return server.IsConnected("kind-a") && server.IsConnected("kind-b"), nil
+	})
+	assert.Nil(err)
+
+	// the address of kubeconfig is not reachable from pivot, traffic goes through tunnel
+	for _, cluster := range []string{"kind-a", "kind-b"} {
+		config := &rest.Config{
+			Host:            "https://10.255.255.1:6443",
+			Dial:            server.Dialer(cluster),
+			TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+			Timeout:         5 * time.Second,
+		}
+
+		cli, err := kubernetes.NewForConfig(config)
+		assert.Nil(err)
+		v, err := cli.Discovery().ServerVersion()
+		assert.Nil(err)
+		assert.Equal("v1.20.0-"+cluster, v.GitVersion)
+
+		ts, err := rest.TransportFor(config)
+		assert.Nil(err)
+		resp, err := (&http.Client{Transport: ts, Timeout: 5 * time.Second}).Get(config.Host + "/api/v1/pods")
+		assert.Nil(err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Contains(string(body), cluster)
+	}
+
+	_, err = server.DialContext(context.Background(), "unknown", "10.255.255.1:6443")
+	assert.NotNil(err)
+
+	// tunnel disconnected after agent stopped
+	close(stopA)
+	err = wait.PollImmediate(50*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return !server.IsConnected("kind-a"), nil
+	})
+	assert.Nil(err)
+	assert.True(server.IsConnected("kind-b"))
+}
+
+func TestTunnelRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	server := NewServer(func(ctx context.Context, cluster string, r *http.Request) error {
+		return fmt.Errorf("cluster %v is not in tunnel mode", cluster)
+	})
+	pivot := httptest.NewServer(server)
+	defer pivot.Close()
+
+	agent := &Agent{Cluster: "forbidden", PivotCubeHost: pivot.URL, Upstream: "127.0.0.1:6443"}
+	err := agent.Connect(context.Background())
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "403"), err.Error())
+	assert.False(server.IsConnected("forbidden"))
+}
+
+func TestTunnelAuthentication(t *testing.T) {
+	assert := assert.New(t)
+
+	kind := newFakeAPIServer("kind-a")
+	defer kind.Close()
+
+	server := NewServer(authorizeByCredential)
+	pivot, tlsConfig := newPivot(server)
+	defer pivot.Close()
+
+	stop := startAgent("kind-a", pivot.URL, tlsConfig, kind)
+	defer close(stop)
+
+	err := wait.PollImmediate(50*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return server.IsConnected("kind-a"), nil
+	})
+	assert.Nil(err)
+	cc, err := server.session("kind-a")
+	assert.Nil(err)
+
+	// agents without valid credential are not able to replace the tunnel
+	for name, credential := range map[string][]byte{
+		"none":  nil,
+		"wrong": credentialOf("kind-b"),
+	} {
+		agent := &Agent{Cluster: "kind-a", PivotCubeHost: pivot.URL, Upstream: "127.0.0.1:6443", Credential: credential, TLSConfig: tlsConfig}
+		err = agent.Connect(context.Background())
+		assert.NotNil(err, name)
+		assert.True(strings.Contains(err.Error(), "403"), err.Error())
+	}
+
+	replay := httptest.NewRequest(http.MethodGet, Path, nil)
+	replay.Header.Set("Authorization", "Bearer "+Sign("kind-a", credentialOf("kind-a"), time.Now().Add(-time.Hour)))
+	assert.NotNil(Authenticate(replay, "kind-a", credentialOf("kind-a")))
+
+	current, err := server.session("kind-a")
+	assert.Nil(err)
+	assert.Equal(cc, current)
+
+	// KubeCube is not trusted without its ca
+	agent := &Agent{Cluster: "kind-a", PivotCubeHost: pivot.URL, Upstream: "127.0.0.1:6443", Credential: credentialOf("kind-a")}
+	err = agent.Connect(context.Background())
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "certificate"), err.Error())
+}
+
+func TestNilAuthorizer(t *testing.T) {
+	assert := assert.New(t)
+
+	server := NewServer(nil)
+	pivot := httptest.NewServer(server)
+	defer pivot.Close()
+
+	agent := &Agent{Cluster: "kind-a", PivotCubeHost: pivot.URL, Upstream: "127.0.0.1:6443", Credential: credentialOf("kind-a")}
+	err := agent.Connect(context.Background())
+	assert.NotNil(err)
+	assert.True(strings.Contains(err.Error(), "403"), err.Error())
+	assert.False(server.IsConnected("kind-a"))
+}
+
+func TestUpstreamOf(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]string{
+		"https://10.0.0.1:6443":          "10.0.0.1:6443",
+		"https://kubernetes.default.svc": "kubernetes.default.svc:443",
+		"http://127.0.0.1":               "127.0.0.1:80",
+		"10.0.0.1:6443":                  "10.0.0.1:6443",
+	}
+	for host, want := range tests {
+		got, err := UpstreamOf(&rest.Config{Host: host})
+		assert.Nil(err)
+		assert.Equal(want, got)
+	}
+}
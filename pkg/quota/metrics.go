@@ -0,0 +1,77 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+)
+
+const collectTimeout = 5 * time.Second
+
+var (
+	quotaLabels = []string{"quota", "target_kind", "target_name", "resource"}
+
+	hardDesc = prometheus.NewDesc("kubecube_quota_hard", "Hard limit of cube resource quota by resource.", quotaLabels, nil)
+	usedDesc = prometheus.NewDesc("kubecube_quota_used", "Used of cube resource quota by resource.", quotaLabels, nil)
+)
+
+// collector exports hard and used of cube resource quotas
+type collector struct {
+	cli client.Reader
+}
+
+// NewCollector returns the collector of cube resource quotas read by cli
+func NewCollector(cli client.Reader) prometheus.Collector {
+	return &collector{cli: cli}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hardDesc
+	ch <- usedDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	quotas := quotav1.CubeResourceQuotaList{}
+	err := c.cli.List(ctx, &quotas)
+	if err != nil {
+		clog.Warn("list cube resource quotas for metrics failed: %v", err)
+		return
+	}
+
+	for _, q := range quotas.Items {
+		collectResourceList(ch, hardDesc, q, q.Status.Hard)
+		collectResourceList(ch, usedDesc, q, q.Status.Used)
+	}
+}
+
+func collectResourceList(ch chan<- prometheus.Metric, desc *prometheus.Desc, q quotav1.CubeResourceQuota, list v1.ResourceList) {
+	for name, quantity := range list {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, quantity.AsApproximateFloat64(),
+			q.Name, string(q.Spec.Target.Kind), q.Spec.Target.Name, string(name))
+	}
+}
@@ -0,0 +1,389 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+const (
+	// archivePrefix is the name prefix of secret holds archive bundle of tenant
+	archivePrefix = "tenant-archive-"
+
+	// bundleKey is the key of bundle in archive secret
+	bundleKey = "bundle.json.gz"
+
+	// maxBundleSize is the max size of gzipped bundle, a secret is limited
+	// to 1MiB and the rest is left for metadata of secret
+	maxBundleSize = 1<<20 - 64<<10
+)
+
+// archiveKinds are the kinds exported into archive bundle, kinds are
+// restored in the order so that workloads come after their dependencies.
+// PersistentVolumeClaims are kept in cluster to avoid data loss.
+var archiveKinds = []schema.GroupVersionKind{
+	{Version: "v1", Kind: "ConfigMap"},
+	{Version: "v1", Kind: "Secret"},
+	{Version: "v1", Kind: "ServiceAccount"},
+	{Version: "v1", Kind: "Service"},
+	{Group: "apps", Version: "v1", Kind: "Deployment"},
+	{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	{Group: "batch", Version: "v1", Kind: "Job"},
+	// version of CronJob is resolved by cronJobVersion
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+}
+
+// ArchiveName returns the name of secret holds archive bundle of tenant
+func ArchiveName(tenant string) string {
+	return archivePrefix + tenant
+}
+
+// Archive suspends tenant, exports resources of tenant into a bundle stored
+// as a secret in cube namespace and deletes the exported resources. Bundle
+// exported before is merged so that an interrupted archive is resumable.
+// Tenant too large to archive is refused before suspended.
+func Archive(ctx context.Context, cli client.Client, tenant string) error {
+	namespaces, err := NamespacesOf(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+	bundle, _, err := exportBundle(ctx, cli, tenant, namespaces)
+	if err != nil {
+		return err
+	}
+	if _, err = encodeBundle(tenant, bundle); err != nil {
+		return err
+	}
+
+	err = Suspend(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+
+	// export again since workloads are changed by suspension
+	namespaces, err = NamespacesOf(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+	bundle, exported, err := exportBundle(ctx, cli, tenant, namespaces)
+	if err != nil {
+		return err
+	}
+	data, err := encodeBundle(tenant, bundle)
+	if err != nil {
+		return err
+	}
+
+	err = saveBundle(ctx, cli, tenant, data)
+	if err != nil {
+		return err
+	}
+
+	for i := range exported {
+		err = cli.Delete(ctx, &exported[i], client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	clog.Info("tenant %v archived with %v resources", tenant, len(exported))
+
+	return labelNamespaces(ctx, cli, namespaces, tenantv1.TenantArchived)
+}
+
+// Restore re-applies the archive bundle of tenant and deletes the bundle,
+// the restored workloads keep suspended until tenant resumed
+func Restore(ctx context.Context, cli client.Client, tenant string) error {
+	objs, err := loadBundle(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+
+	for i := range objs {
+		err = cli.Create(ctx, typedObject(cli.Scheme(), &objs[i]))
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("restore %v %v/%v failed: %v", objs[i].GetKind(), objs[i].GetNamespace(), objs[i].GetName(), err)
+		}
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: ArchiveName(tenant), Namespace: env.CubeNamespace()}}
+	err = cli.Delete(ctx, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	clog.Info("tenant %v restored with %v resources", tenant, len(objs))
+
+	return nil
+}
+
+// exportBundle lists resources of tenant to archive and merges them into the
+// bundle exported before, both the merged bundle and the exported are returned
+func exportBundle(ctx context.Context, cli client.Client, tenant string, namespaces []corev1.Namespace) ([]unstructured.Unstructured, []unstructured.Unstructured, error) {
+	objs, err := loadBundle(ctx, cli, tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cronJobGVK := cronJobVersion(cli)
+	exported := []unstructured.Unstructured{}
+	for _, ns := range namespaces {
+		for _, gvk := range archiveKinds {
+			if gvk.GroupKind() == cronJobGVK.GroupKind() {
+				gvk = cronJobGVK
+			}
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			err = cli.List(ctx, list, client.InNamespace(ns.Name))
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, obj := range list.Items {
+				if !shouldArchive(&obj) {
+					continue
+				}
+				exported = append(exported, obj)
+			}
+		}
+	}
+
+	return mergeBundle(objs, exported), exported, nil
+}
+
+// shouldArchive filters out the resources managed by controllers, resources
+// created by kubernetes automatically and resources synced by KubeCube. The
+// resources to archive are cleaned to be created again.
+func shouldArchive(obj *unstructured.Unstructured) bool {
+	if len(obj.GetOwnerReferences()) > 0 || obj.GetDeletionTimestamp() != nil {
+		return false
+	}
+	if _, ok := obj.GetAnnotations()[constants.SyncAnnotation]; ok {
+		return false
+	}
+	if _, ok := obj.GetLabels()["hnc.x-k8s.io/inherited-from"]; ok {
+		return false
+	}
+
+	switch obj.GetKind() {
+	case "ConfigMap":
+		if obj.GetName() == "kube-root-ca.crt" {
+			return false
+		}
+	case "ServiceAccount":
+		if obj.GetName() == "default" {
+			return false
+		}
+	case "Secret":
+		t, _, _ := unstructured.NestedString(obj.Object, "type")
+		if t == string(corev1.SecretTypeServiceAccountToken) {
+			return false
+		}
+	case "Service":
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	case "Job":
+		// selector and labels are generated by job controller
+		unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+		unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", "controller-uid")
+		unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", "job-name")
+	}
+
+	for _, field := range []string{"uid", "resourceVersion", "creationTimestamp", "generation", "selfLink", "managedFields"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	return true
+}
+
+// typedObject converts unstructured object to typed object if its kind is
+// known by scheme, returns the unstructured object if failed
+func typedObject(scheme *runtime.Scheme, obj *unstructured.Unstructured) client.Object {
+	typed, err := scheme.New(obj.GroupVersionKind())
+	if err != nil {
+		return obj
+	}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed)
+	if err != nil {
+		return obj
+	}
+	o, ok := typed.(client.Object)
+	if !ok {
+		return obj
+	}
+	return o
+}
+
+// mergeBundle merges exported resources into bundle, the exported ones win
+func mergeBundle(bundle, exported []unstructured.Unstructured) []unstructured.Unstructured {
+	type key struct {
+		gvk             schema.GroupVersionKind
+		namespace, name string
+	}
+	keyOf := func(obj *unstructured.Unstructured) key {
+		return key{gvk: obj.GroupVersionKind(), namespace: obj.GetNamespace(), name: obj.GetName()}
+	}
+
+	index := make(map[key]int, len(bundle))
+	for i := range bundle {
+		index[keyOf(&bundle[i])] = i
+	}
+	for i := range exported {
+		if j, ok := index[keyOf(&exported[i])]; ok {
+			bundle[j] = exported[i]
+			continue
+		}
+		bundle = append(bundle, exported[i])
+	}
+
+	return bundle
+}
+
+// loadBundle reads archive bundle of tenant, returns empty if no bundle found
+func loadBundle(ctx context.Context, cli client.Reader, tenant string) ([]unstructured.Unstructured, error) {
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, types.NamespacedName{Name: ArchiveName(tenant), Namespace: env.CubeNamespace()}, secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(secret.Data[bundleKey]))
+	if err != nil {
+		return nil, fmt.Errorf("read archive bundle of tenant %v failed: %v", tenant, err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read archive bundle of tenant %v failed: %v", tenant, err)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	err = list.UnmarshalJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode archive bundle of tenant %v failed: %v", tenant, err)
+	}
+
+	return list.Items, nil
+}
+
+// encodeBundle encodes resources into gzipped json of resources list, error
+// returned if it exceeds the size of secret
+func encodeBundle(tenant string, objs []unstructured.Unstructured) ([]byte, error) {
+	items := make([]map[string]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, obj.Object)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() > maxBundleSize {
+		return nil, fmt.Errorf("archive bundle of tenant %v is %v bytes which exceeds the limit %v bytes", tenant, buf.Len(), maxBundleSize)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// saveBundle writes encoded archive bundle of tenant into secret
+func saveBundle(ctx context.Context, cli client.Client, tenant string, data []byte) error {
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, types.NamespacedName{Name: ArchiveName(tenant), Namespace: env.CubeNamespace()}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ArchiveName(tenant),
+				Namespace: env.CubeNamespace(),
+				Labels:    map[string]string{constants.TenantLabel: tenant},
+			},
+			Data: map[string][]byte{bundleKey: data},
+		}
+		return cli.Create(ctx, secret)
+	}
+
+	secret.Data = map[string][]byte{bundleKey: data}
+	return cli.Update(ctx, secret)
+}
+
+// IsArchive tells if object is the archive bundle of tenant
+func IsArchive(obj client.Object) bool {
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return false
+	}
+	_, ok := obj.GetLabels()[constants.TenantLabel]
+	return ok && obj.GetNamespace() == env.CubeNamespace() && strings.HasPrefix(obj.GetName(), archivePrefix)
+}
+
+// ListArchives lists archive bundles of tenants in cluster, they are lost if
+// cube namespace deleted
+func ListArchives(ctx context.Context, cli client.Reader) ([]corev1.Secret, error) {
+	secrets := &corev1.SecretList{}
+	err := cli.List(ctx, secrets, client.InNamespace(env.CubeNamespace()), client.HasLabels{constants.TenantLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []corev1.Secret
+	for i := range secrets.Items {
+		if IsArchive(&secrets.Items[i]) {
+			archives = append(archives, secrets.Items[i])
+		}
+	}
+
+	return archives, nil
+}
@@ -0,0 +1,443 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// DesiredState returns the lifecycle state specified by tenant
+func DesiredState(tenant *tenantv1.Tenant) tenantv1.TenantState {
+	if len(tenant.Spec.State) == 0 {
+		return tenantv1.TenantActive
+	}
+	return tenant.Spec.State
+}
+
+// AppliedState returns the lifecycle state applied to namespace of tenant
+func AppliedState(ns *corev1.Namespace) tenantv1.TenantState {
+	state, ok := ns.Labels[constants.TenantStateLabel]
+	if !ok {
+		return tenantv1.TenantActive
+	}
+	return tenantv1.TenantState(state)
+}
+
+// TenantOfNamespace returns the tenant namespace belongs to, returns
+// empty if namespace belongs to no tenant
+func TenantOfNamespace(ns *corev1.Namespace) string {
+	if tenant, ok := ns.Labels[constants.HncTenantLabel]; ok {
+		return tenant
+	}
+	if strings.HasPrefix(ns.Name, constants.TenantNsPrefix) {
+		return strings.TrimPrefix(ns.Name, constants.TenantNsPrefix)
+	}
+	return ""
+}
+
+// NamespacesOf returns all namespaces of tenant in cluster, includes the
+// namespace of tenant itself
+func NamespacesOf(ctx context.Context, cli client.Reader, tenant string) ([]corev1.Namespace, error) {
+	nsList := &corev1.NamespaceList{}
+	err := cli.List(ctx, nsList, client.MatchingLabels{constants.HncTenantLabel: tenant})
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := nsList.Items
+	ns := corev1.Namespace{}
+	err = cli.Get(ctx, types.NamespacedName{Name: constants.TenantNsPrefix + tenant}, &ns)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if err == nil {
+		namespaces = append([]corev1.Namespace{ns}, namespaces...)
+	}
+
+	return namespaces, nil
+}
+
+// ApplyState moves tenant from the state applied in cluster to the desired state.
+// Archived tenant is restored from bundle before any other transitions.
+func ApplyState(ctx context.Context, cli client.Client, tenant string, desired tenantv1.TenantState) error {
+	ns := &corev1.Namespace{}
+	err := cli.Get(ctx, types.NamespacedName{Name: constants.TenantNsPrefix + tenant}, ns)
+	if err != nil {
+		// state is applied once namespace of tenant created
+		return client.IgnoreNotFound(err)
+	}
+
+	current := AppliedState(ns)
+	if current == desired {
+		return nil
+	}
+
+	clog.Info("tenant %v transits from %v to %v", tenant, current, desired)
+
+	if current == tenantv1.TenantArchived {
+		if err = Restore(ctx, cli, tenant); err != nil {
+			return fmt.Errorf("restore tenant %v failed: %v", tenant, err)
+		}
+	}
+
+	switch desired {
+	case tenantv1.TenantActive:
+		err = Resume(ctx, cli, tenant)
+	case tenantv1.TenantSuspended:
+		err = Suspend(ctx, cli, tenant)
+	case tenantv1.TenantArchived:
+		err = Archive(ctx, cli, tenant)
+	default:
+		err = fmt.Errorf("unknown state %v", desired)
+	}
+	if err != nil {
+		return fmt.Errorf("transit tenant %v to %v failed: %v", tenant, desired, err)
+	}
+
+	return nil
+}
+
+// Suspend scales workloads of tenant to zero, suspends cronjobs and labels
+// namespaces of tenant to block writes. Autoscalers of workloads are removed
+// and recorded into workloads so that workloads are kept at zero.
+func Suspend(ctx context.Context, cli client.Client, tenant string) error {
+	namespaces, err := NamespacesOf(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+
+	// label namespaces first to stop new workloads coming in
+	err = labelNamespaces(ctx, cli, namespaces, tenantv1.TenantSuspended)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		autoscalers, err := autoscalersOf(ctx, cli, ns.Name)
+		if err != nil {
+			return err
+		}
+
+		deployList := &appsv1.DeploymentList{}
+		if err = cli.List(ctx, deployList, client.InNamespace(ns.Name)); err != nil {
+			return err
+		}
+		for i := range deployList.Items {
+			deploy := &deployList.Items[i]
+			hpa := autoscalers[scaleTargetKey("Deployment", deploy.Name)]
+			if err = suspendWorkload(ctx, cli, deploy, &deploy.ObjectMeta, &deploy.Spec.Replicas, hpa); err != nil {
+				return err
+			}
+		}
+
+		stsList := &appsv1.StatefulSetList{}
+		if err = cli.List(ctx, stsList, client.InNamespace(ns.Name)); err != nil {
+			return err
+		}
+		for i := range stsList.Items {
+			sts := &stsList.Items[i]
+			hpa := autoscalers[scaleTargetKey("StatefulSet", sts.Name)]
+			if err = suspendWorkload(ctx, cli, sts, &sts.ObjectMeta, &sts.Spec.Replicas, hpa); err != nil {
+				return err
+			}
+		}
+
+		cronJobs, err := cronJobsOf(ctx, cli, ns.Name)
+		if err != nil {
+			return err
+		}
+		for i := range cronJobs {
+			cronJob := &cronJobs[i]
+			if suspended, _, _ := unstructured.NestedBool(cronJob.Object, "spec", "suspend"); suspended {
+				continue
+			}
+			annotations := cronJob.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[constants.SuspendedAnnotation] = "true"
+			cronJob.SetAnnotations(annotations)
+			if err = unstructured.SetNestedField(cronJob.Object, true, "spec", "suspend"); err != nil {
+				return err
+			}
+			if err = cli.Update(ctx, typedObject(cli.Scheme(), cronJob)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resume reverts what Suspend did, workloads are scaled to the replicas
+// before suspended and their autoscalers are recreated
+func Resume(ctx context.Context, cli client.Client, tenant string) error {
+	namespaces, err := NamespacesOf(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		deployList := &appsv1.DeploymentList{}
+		if err = cli.List(ctx, deployList, client.InNamespace(ns.Name)); err != nil {
+			return err
+		}
+		for i := range deployList.Items {
+			deploy := &deployList.Items[i]
+			if err = resumeWorkload(ctx, cli, deploy, &deploy.ObjectMeta, &deploy.Spec.Replicas); err != nil {
+				return err
+			}
+		}
+
+		stsList := &appsv1.StatefulSetList{}
+		if err = cli.List(ctx, stsList, client.InNamespace(ns.Name)); err != nil {
+			return err
+		}
+		for i := range stsList.Items {
+			sts := &stsList.Items[i]
+			if err = resumeWorkload(ctx, cli, sts, &sts.ObjectMeta, &sts.Spec.Replicas); err != nil {
+				return err
+			}
+		}
+
+		cronJobs, err := cronJobsOf(ctx, cli, ns.Name)
+		if err != nil {
+			return err
+		}
+		for i := range cronJobs {
+			cronJob := &cronJobs[i]
+			annotations := cronJob.GetAnnotations()
+			if _, ok := annotations[constants.SuspendedAnnotation]; !ok {
+				continue
+			}
+			delete(annotations, constants.SuspendedAnnotation)
+			cronJob.SetAnnotations(annotations)
+			if err = unstructured.SetNestedField(cronJob.Object, false, "spec", "suspend"); err != nil {
+				return err
+			}
+			if err = cli.Update(ctx, typedObject(cli.Scheme(), cronJob)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// unlabel namespaces at last to keep writes blocked until resumed
+	return labelNamespaces(ctx, cli, namespaces, tenantv1.TenantActive)
+}
+
+// suspendWorkload scales workload to zero and removes its autoscaler if any, the
+// replicas and autoscaler are recorded into workload before autoscaler removed
+func suspendWorkload(ctx context.Context, cli client.Client, obj client.Object, meta *metav1.ObjectMeta, replicas **int32, hpa *autoscalingv1.HorizontalPodAutoscaler) error {
+	changed := suspendReplicas(meta, *replicas)
+	if changed {
+		*replicas = new(int32)
+	}
+
+	if hpa != nil {
+		if err := suspendAutoscaler(meta, hpa); err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := cli.Update(ctx, obj); err != nil {
+			return err
+		}
+	}
+
+	if hpa != nil {
+		return client.IgnoreNotFound(cli.Delete(ctx, hpa))
+	}
+
+	return nil
+}
+
+// resumeWorkload recreates the autoscaler and scales workload to the replicas
+// recorded by suspendWorkload
+func resumeWorkload(ctx context.Context, cli client.Client, obj client.Object, meta *metav1.ObjectMeta, replicas **int32) error {
+	hpa := resumeAutoscaler(meta)
+	r, ok := resumeReplicas(meta)
+	if ok {
+		*replicas = &r
+	}
+	if !ok && hpa == nil {
+		return nil
+	}
+
+	if hpa != nil {
+		hpa.Namespace = meta.Namespace
+		err := cli.Create(ctx, hpa)
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	return cli.Update(ctx, obj)
+}
+
+// suspendReplicas records the replicas into annotations of workload, returns
+// false if workload is suspended already
+func suspendReplicas(obj *metav1.ObjectMeta, replicas *int32) bool {
+	if _, ok := obj.Annotations[constants.SuspendedReplicasAnnotation]; ok {
+		return false
+	}
+	r := int32(1)
+	if replicas != nil {
+		r = *replicas
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[constants.SuspendedReplicasAnnotation] = strconv.Itoa(int(r))
+	return true
+}
+
+// resumeReplicas pops the replicas recorded by suspendReplicas
+func resumeReplicas(obj *metav1.ObjectMeta) (int32, bool) {
+	v, ok := obj.Annotations[constants.SuspendedReplicasAnnotation]
+	if !ok {
+		return 0, false
+	}
+	delete(obj.Annotations, constants.SuspendedReplicasAnnotation)
+	replicas, err := strconv.Atoi(v)
+	if err != nil {
+		clog.Warn("invalid suspended replicas %v of %v/%v, resume to 1", v, obj.Namespace, obj.Name)
+		replicas = 1
+	}
+	return int32(replicas), true
+}
+
+// suspendAutoscaler records the autoscaler into annotations of workload it scales
+func suspendAutoscaler(obj *metav1.ObjectMeta, hpa *autoscalingv1.HorizontalPodAutoscaler) error {
+	recorded := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        hpa.Name,
+			Labels:      hpa.Labels,
+			Annotations: hpa.Annotations,
+		},
+		Spec: hpa.Spec,
+	}
+	b, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[constants.SuspendedAutoscalerAnnotation] = string(b)
+	return nil
+}
+
+// resumeAutoscaler pops the autoscaler recorded by suspendAutoscaler
+func resumeAutoscaler(obj *metav1.ObjectMeta) *autoscalingv1.HorizontalPodAutoscaler {
+	v, ok := obj.Annotations[constants.SuspendedAutoscalerAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(obj.Annotations, constants.SuspendedAutoscalerAnnotation)
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{}
+	if err := json.Unmarshal([]byte(v), hpa); err != nil {
+		clog.Warn("invalid suspended autoscaler of %v/%v, skip it: %v", obj.Namespace, obj.Name, err)
+		return nil
+	}
+	return hpa
+}
+
+// autoscalersOf returns the autoscalers in namespace indexed by their scale targets
+func autoscalersOf(ctx context.Context, cli client.Reader, namespace string) (map[string]*autoscalingv1.HorizontalPodAutoscaler, error) {
+	hpaList := &autoscalingv1.HorizontalPodAutoscalerList{}
+	err := cli.List(ctx, hpaList, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	autoscalers := make(map[string]*autoscalingv1.HorizontalPodAutoscaler, len(hpaList.Items))
+	for i := range hpaList.Items {
+		hpa := &hpaList.Items[i]
+		autoscalers[scaleTargetKey(hpa.Spec.ScaleTargetRef.Kind, hpa.Spec.ScaleTargetRef.Name)] = hpa
+	}
+	return autoscalers, nil
+}
+
+func scaleTargetKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// cronJobVersion returns the version of CronJob served by cluster, batch/v1 is
+// preferred and batch/v1beta1 is used for clusters before kubernetes 1.21
+func cronJobVersion(cli client.Client) schema.GroupVersionKind {
+	gvk := schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}
+	if mapper := cli.RESTMapper(); mapper != nil {
+		if _, err := mapper.RESTMapping(gvk.GroupKind(), "v1"); err == nil {
+			gvk.Version = "v1"
+		}
+	}
+	return gvk
+}
+
+// cronJobsOf returns the cronjobs in namespace of the version served by cluster
+func cronJobsOf(ctx context.Context, cli client.Client, namespace string) ([]unstructured.Unstructured, error) {
+	gvk := cronJobVersion(cli)
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	err := cli.List(ctx, list, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// labelNamespaces labels namespaces with the state, label of Active state is removed
+func labelNamespaces(ctx context.Context, cli client.Client, namespaces []corev1.Namespace, state tenantv1.TenantState) error {
+	for i := range namespaces {
+		ns := &namespaces[i]
+		if AppliedState(ns) == state {
+			continue
+		}
+		if state == tenantv1.TenantActive {
+			delete(ns.Labels, constants.TenantStateLabel)
+		} else {
+			if ns.Labels == nil {
+				ns.Labels = make(map[string]string)
+			}
+			ns.Labels[constants.TenantStateLabel] = string(state)
+		}
+		if err := cli.Update(ctx, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+func newTenantClient() client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	replicas, minReplicas := int32(3), int32(2)
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubecube-tenant-tenant-1"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "ns-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns-1"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "other"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		},
+		&batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "ns-1"}},
+		&autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns-1", Labels: map[string]string{"app": "web"}},
+			Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    5,
+			},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "ns-1"}, Data: map[string]string{"k": "v"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "ns-1"}},
+	).Build()
+}
+
+func TestSuspendAndResume(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	cli := newTenantClient()
+
+	assert.Nil(ApplyState(ctx, cli, "tenant-1", tenantv1.TenantSuspended))
+
+	deploy := &appsv1.Deployment{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, deploy))
+	assert.Equal(int32(0), *deploy.Spec.Replicas)
+	assert.Equal("3", deploy.Annotations[constants.SuspendedReplicasAnnotation])
+
+	cronJob := &batchv1beta1.CronJob{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "backup", Namespace: "ns-1"}, cronJob))
+	assert.True(*cronJob.Spec.Suspend)
+
+	// autoscaler is removed to keep workload at zero
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{}
+	assert.True(errors.IsNotFound(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, hpa)))
+	assert.Contains(deploy.Annotations, constants.SuspendedAutoscalerAnnotation)
+
+	// workloads out of tenant are untouched
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "other"}, deploy))
+	assert.Equal(int32(3), *deploy.Spec.Replicas)
+
+	for _, name := range []string{"kubecube-tenant-tenant-1", "ns-1"} {
+		ns := &corev1.Namespace{}
+		assert.Nil(cli.Get(ctx, types.NamespacedName{Name: name}, ns))
+		assert.Equal(tenantv1.TenantSuspended, AppliedState(ns))
+	}
+
+	// suspend again keeps the replicas recorded
+	assert.Nil(Suspend(ctx, cli, "tenant-1"))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, deploy))
+	assert.Equal("3", deploy.Annotations[constants.SuspendedReplicasAnnotation])
+
+	assert.Nil(ApplyState(ctx, cli, "tenant-1", tenantv1.TenantActive))
+
+	deploy = &appsv1.Deployment{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, deploy))
+	assert.Equal(int32(3), *deploy.Spec.Replicas)
+	assert.NotContains(deploy.Annotations, constants.SuspendedReplicasAnnotation)
+	assert.NotContains(deploy.Annotations, constants.SuspendedAutoscalerAnnotation)
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "backup", Namespace: "ns-1"}, cronJob))
+	assert.False(*cronJob.Spec.Suspend)
+
+	// autoscaler is recreated as it was
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, hpa))
+	assert.Equal(int32(2), *hpa.Spec.MinReplicas)
+	assert.Equal(int32(5), hpa.Spec.MaxReplicas)
+	assert.Equal("web", hpa.Spec.ScaleTargetRef.Name)
+	assert.Equal("web", hpa.Labels["app"])
+
+	ns := &corev1.Namespace{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "ns-1"}, ns))
+	assert.Equal(tenantv1.TenantActive, AppliedState(ns))
+}
+
+// mappedClient serves the kinds of mapper given
+type mappedClient struct {
+	client.Client
+	mapper meta.RESTMapper
+}
+
+func (c *mappedClient) RESTMapper() meta.RESTMapper {
+	return c.mapper
+}
+
+func TestCronJobVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	cli := newTenantClient()
+	assert.Equal("v1beta1", cronJobVersion(cli).Version)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}, meta.RESTScopeNamespace)
+	assert.Equal("v1beta1", cronJobVersion(&mappedClient{Client: cli, mapper: mapper}).Version)
+
+	mapper.Add(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, meta.RESTScopeNamespace)
+	assert.Equal("v1", cronJobVersion(&mappedClient{Client: cli, mapper: mapper}).Version)
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	cli := newTenantClient()
+
+	assert.Nil(ApplyState(ctx, cli, "tenant-1", tenantv1.TenantArchived))
+
+	err := cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, &appsv1.Deployment{})
+	assert.True(errors.IsNotFound(err))
+	err = cli.Get(ctx, types.NamespacedName{Name: "config", Namespace: "ns-1"}, &corev1.ConfigMap{})
+	assert.True(errors.IsNotFound(err))
+	// resources created by kubernetes are not archived
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "kube-root-ca.crt", Namespace: "ns-1"}, &corev1.ConfigMap{}))
+
+	objs, err := loadBundle(ctx, cli, "tenant-1")
+	assert.Nil(err)
+	assert.Len(objs, 3)
+
+	ns := &corev1.Namespace{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "ns-1"}, ns))
+	assert.Equal(tenantv1.TenantArchived, AppliedState(ns))
+
+	assert.Nil(ApplyState(ctx, cli, "tenant-1", tenantv1.TenantActive))
+
+	deploy := &appsv1.Deployment{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, deploy))
+	assert.Equal(int32(3), *deploy.Spec.Replicas)
+	cm := &corev1.ConfigMap{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "config", Namespace: "ns-1"}, cm))
+	assert.Equal("v", cm.Data["k"])
+
+	// bundle is deleted after restored
+	err = cli.Get(ctx, types.NamespacedName{Name: ArchiveName("tenant-1"), Namespace: env.CubeNamespace()}, &corev1.Secret{})
+	assert.True(errors.IsNotFound(err))
+}
+
+func TestArchiveTooLarge(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	cli := newTenantClient()
+
+	// random data is hardly compressed by gzip
+	raw := make([]byte, 2<<20)
+	_, err := rand.Read(raw)
+	assert.Nil(err)
+	large := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "large", Namespace: "ns-1"},
+		Data:       map[string]string{"k": base64.StdEncoding.EncodeToString(raw)},
+	}
+	assert.Nil(cli.Create(ctx, large))
+
+	err = ApplyState(ctx, cli, "tenant-1", tenantv1.TenantArchived)
+	assert.NotNil(err)
+
+	// tenant is left untouched
+	ns := &corev1.Namespace{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "ns-1"}, ns))
+	assert.Equal(tenantv1.TenantActive, AppliedState(ns))
+	deploy := &appsv1.Deployment{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "web", Namespace: "ns-1"}, deploy))
+	assert.Equal(int32(3), *deploy.Spec.Replicas)
+	err = cli.Get(ctx, types.NamespacedName{Name: ArchiveName("tenant-1"), Namespace: env.CubeNamespace()}, &corev1.Secret{})
+	assert.True(errors.IsNotFound(err))
+}
+
+func TestListArchives(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	cli := newTenantClient()
+
+	assert.Nil(ApplyState(ctx, cli, "tenant-1", tenantv1.TenantArchived))
+
+	archives, err := ListArchives(ctx, cli)
+	assert.Nil(err)
+	assert.Len(archives, 1)
+	assert.Equal(ArchiveName("tenant-1"), archives[0].Name)
+	assert.True(IsArchive(&archives[0]))
+}
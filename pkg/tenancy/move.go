@@ -0,0 +1,410 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
+
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/quota/cube"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// QuotaMove is a cube resource quota of project moved under the quota
+// of destination tenant in the same cluster
+type QuotaMove struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster,omitempty"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+}
+
+// ProjectMove describes what is changed when project moved between tenants
+type ProjectMove struct {
+	Project      string      `json:"project"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	Quotas       []QuotaMove `json:"quotas,omitempty"`
+	RoleBindings []string    `json:"roleBindings,omitempty"`
+}
+
+// PlanProjectMove validates project could be moved to tenant and returns the
+// plan of move without changing anything. Quotas of project are checked against
+// the quotas of tenant in the same cluster as if they were newly created there.
+func PlanProjectMove(ctx context.Context, cli client.Client, project *tenantv1.Project, tenant string) (*ProjectMove, error) {
+	from := project.Labels[constants.TenantLabel]
+	plan := &ProjectMove{Project: project.Name, From: from, To: tenant}
+
+	dest := &tenantv1.Tenant{}
+	err := cli.Get(ctx, types.NamespacedName{Name: tenant}, dest)
+	if err != nil {
+		return nil, fmt.Errorf("get tenant %v failed: %v", tenant, err)
+	}
+	if state := DesiredState(dest); state != tenantv1.TenantActive {
+		return nil, fmt.Errorf("can not move project to %v tenant %v", state, tenant)
+	}
+
+	if len(from) > 0 && from != tenant {
+		source := &tenantv1.Tenant{}
+		err = cli.Get(ctx, types.NamespacedName{Name: from}, source)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err == nil && DesiredState(source) != tenantv1.TenantActive {
+			return nil, fmt.Errorf("can not move project out of %v tenant %v", DesiredState(source), from)
+		}
+	}
+
+	plan.Quotas, err = planQuotaMoves(ctx, cli, project.Name, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings, err := roleBindingsToMove(ctx, cli, project.Name, tenant)
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range bindings {
+		plan.RoleBindings = append(plan.RoleBindings, rb.Namespace+"/"+rb.Name)
+	}
+
+	return plan, nil
+}
+
+// MoveProject moves project to tenant. The tenant label of project is changed
+// first, warden of every cluster re-parents namespaces of project once it synced,
+// then cube resource quotas and role bindings of project follow. Move is
+// validated again against the latest project and quotas right before the label
+// changed, and the label is rolled back if quotas failed to follow, so that
+// project is never left under tenant without its quotas. Move is idempotent so
+// that a failed move could be retried.
+func MoveProject(ctx context.Context, cli client.Client, project *tenantv1.Project, tenant string) (*ProjectMove, error) {
+	var plan *ProjectMove
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &tenantv1.Project{}
+		err := cli.Get(ctx, types.NamespacedName{Name: project.Name}, current)
+		if err != nil {
+			return err
+		}
+		plan, err = PlanProjectMove(ctx, cli, current, tenant)
+		if err != nil {
+			return err
+		}
+		return setTenantOf(ctx, cli, current, tenant)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("move project %v to tenant %v failed: %v", project.Name, tenant, err)
+	}
+
+	for i, m := range plan.Quotas {
+		if err = moveQuota(ctx, cli, m, tenant); err != nil {
+			rollbackMove(ctx, cli, plan, plan.Quotas[:i])
+			return nil, err
+		}
+	}
+
+	bindings, err := roleBindingsToMove(ctx, cli, project.Name, tenant)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bindings {
+		rb := &bindings[i]
+		rb.Labels[constants.TenantLabel] = tenant
+		if err = cli.Update(ctx, rb); err != nil {
+			return nil, fmt.Errorf("update tenant of role binding %v/%v failed: %v", rb.Namespace, rb.Name, err)
+		}
+	}
+
+	clog.Info("project %v moved from tenant %v to %v", project.Name, plan.From, tenant)
+
+	return plan, nil
+}
+
+// setTenantOf sets the tenant label of project if changed
+func setTenantOf(ctx context.Context, cli client.Client, project *tenantv1.Project, tenant string) error {
+	if project.Labels[constants.TenantLabel] == tenant {
+		return nil
+	}
+	if project.Labels == nil {
+		project.Labels = make(map[string]string)
+	}
+	project.Labels[constants.TenantLabel] = tenant
+	return cli.Update(ctx, project)
+}
+
+// rollbackMove moves the quotas already moved and the project back to the
+// source tenant, failures are logged only since the move is failed anyway
+func rollbackMove(ctx context.Context, cli client.Client, plan *ProjectMove, moved []QuotaMove) {
+	if len(plan.From) == 0 || plan.From == plan.To {
+		return
+	}
+
+	for _, m := range moved {
+		back := QuotaMove{Name: m.Name, Cluster: m.Cluster, From: m.To, To: m.From}
+		if err := moveQuota(ctx, cli, back, plan.From); err != nil {
+			clog.Error("roll back quota %v to %v failed: %v", m.Name, m.From, err)
+		}
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &tenantv1.Project{}
+		err := cli.Get(ctx, types.NamespacedName{Name: plan.Project}, current)
+		if err != nil {
+			return err
+		}
+		return setTenantOf(ctx, cli, current, plan.From)
+	})
+	if err != nil {
+		clog.Error("roll back tenant of project %v to %v failed: %v", plan.Project, plan.From, err)
+		return
+	}
+
+	clog.Warn("move of project %v to tenant %v rolled back", plan.Project, plan.To)
+}
+
+// planQuotaMoves returns the cube resource quotas of project to be moved under
+// the quotas of tenant, denies the move if any of them overloads tenant
+func planQuotaMoves(ctx context.Context, cli client.Client, project, tenant string) ([]QuotaMove, error) {
+	quotaList := &quotav1.CubeResourceQuotaList{}
+	err := cli.List(ctx, quotaList)
+	if err != nil {
+		return nil, err
+	}
+
+	// quotas of tenant by cluster
+	parents := make(map[string]string)
+	for _, q := range quotaList.Items {
+		if q.Spec.Target.Kind == quotav1.TenantObj && q.Spec.Target.Name == tenant {
+			parents[q.Labels[constants.ClusterLabel]] = q.Name
+		}
+	}
+
+	var moves []QuotaMove
+	for _, q := range quotaList.Items {
+		if q.Spec.Target.Kind != quotav1.ProjectObj || q.Spec.Target.Name != project {
+			continue
+		}
+
+		cluster := q.Labels[constants.ClusterLabel]
+		parent, ok := parents[cluster]
+		if !ok {
+			return nil, fmt.Errorf("tenant %v has no quota in cluster %v for quota %v of project", tenant, cluster, q.Name)
+		}
+		if q.Spec.ParentQuota == parent {
+			continue
+		}
+
+		moved := q.DeepCopy()
+		moved.Spec.ParentQuota = parent
+		isOverload, reason, err := cube.NewQuotaOperator(cli, moved, nil, ctx).Overload()
+		if err != nil {
+			return nil, err
+		}
+		if isOverload {
+			return nil, fmt.Errorf("quota %v of project exceeds quota %v of tenant %v: %v", q.Name, parent, tenant, reason)
+		}
+
+		moves = append(moves, QuotaMove{Name: q.Name, Cluster: cluster, From: q.Spec.ParentQuota, To: parent})
+	}
+
+	return moves, nil
+}
+
+// moveQuota re-parents the cube resource quota and refreshes the used of
+// both old and new parent quota
+func moveQuota(ctx context.Context, cli client.Client, move QuotaMove, tenant string) error {
+	old := &quotav1.CubeResourceQuota{}
+	moved := &quotav1.CubeResourceQuota{}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := cli.Get(ctx, types.NamespacedName{Name: move.Name}, moved)
+		if err != nil {
+			return err
+		}
+		moved.DeepCopyInto(old)
+		moved.Spec.ParentQuota = move.To
+		if moved.Labels == nil {
+			moved.Labels = make(map[string]string)
+		}
+		moved.Labels[constants.TenantLabel] = tenant
+		return cli.Update(ctx, moved)
+	})
+	if err != nil {
+		return fmt.Errorf("move quota %v to %v failed: %v", move.Name, move.To, err)
+	}
+
+	// quota is no longer counted in the quota of source tenant
+	if len(old.Spec.ParentQuota) > 0 && old.Spec.ParentQuota != move.To {
+		err = cube.NewQuotaOperator(cli, nil, old, ctx).UpdateParentStatus(true)
+		if err != nil {
+			return fmt.Errorf("release quota %v from %v failed: %v", move.Name, old.Spec.ParentQuota, err)
+		}
+	}
+
+	err = cube.NewQuotaOperator(cli, moved, nil, ctx).UpdateParentStatus(false)
+	if err != nil {
+		return fmt.Errorf("count quota %v in %v failed: %v", move.Name, move.To, err)
+	}
+
+	return nil
+}
+
+// roleBindingsToMove returns role bindings in namespaces of project still
+// labeled with other tenant, role bindings propagated by HNC are excluded
+func roleBindingsToMove(ctx context.Context, cli client.Reader, project, tenant string) ([]rbacv1.RoleBinding, error) {
+	namespaces, err := ProjectNamespacesOf(ctx, cli, project)
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []rbacv1.RoleBinding
+	for _, ns := range namespaces {
+		list := &rbacv1.RoleBindingList{}
+		err = cli.List(ctx, list, client.InNamespace(ns.Name), client.HasLabels{constants.TenantLabel})
+		if err != nil {
+			return nil, err
+		}
+		for _, rb := range list.Items {
+			if _, ok := rb.Labels[hncInheritedLabel]; ok {
+				continue
+			}
+			if rb.Labels[constants.TenantLabel] != tenant {
+				bindings = append(bindings, rb)
+			}
+		}
+	}
+
+	return bindings, nil
+}
+
+// ReparentProject moves namespace of project under namespace of tenant in
+// cluster. Namespace of project is turned into a full namespace before its old
+// anchor deleted, then re-parented and marked as subnamespace of tenant, so that
+// the anchor created in namespace of tenant adopts it. Anchors of namespaces
+// under project are relabeled with the new tenant.
+func ReparentProject(ctx context.Context, cli client.Client, project, tenant, tenantNs string) error {
+	projectNs := constants.ProjectNsPrefix + project
+
+	anchors := &hnc.SubnamespaceAnchorList{}
+	err := cli.List(ctx, anchors)
+	if err != nil {
+		return err
+	}
+
+	var anchor *hnc.SubnamespaceAnchor
+	for i := range anchors.Items {
+		a := &anchors.Items[i]
+		if a.Name == projectNs {
+			anchor = a
+			continue
+		}
+		if err = relabelAnchor(ctx, cli, a, project, tenant); err != nil {
+			return err
+		}
+	}
+
+	if anchor != nil && anchor.Namespace == tenantNs {
+		return nil
+	}
+
+	ns := &corev1.Namespace{}
+	err = cli.Get(ctx, types.NamespacedName{Name: projectNs}, ns)
+	if err != nil {
+		// namespace of new project is created by anchor
+		return client.IgnoreNotFound(err)
+	}
+
+	if anchor != nil {
+		clog.Info("move namespace %v of project %v from %v to %v", projectNs, project, anchor.Namespace, tenantNs)
+
+		// deleting anchor of full namespace keeps the namespace
+		if _, ok := ns.Annotations[hnc.SubnamespaceOf]; ok {
+			delete(ns.Annotations, hnc.SubnamespaceOf)
+			if err = cli.Update(ctx, ns); err != nil {
+				return fmt.Errorf("detach namespace %v from %v failed: %v", projectNs, anchor.Namespace, err)
+			}
+		}
+		err = cli.Delete(ctx, anchor)
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete anchor %v/%v failed: %v", anchor.Namespace, anchor.Name, err)
+		}
+	}
+
+	hierarchy := &hnc.HierarchyConfiguration{}
+	err = cli.Get(ctx, types.NamespacedName{Name: hnc.Singleton, Namespace: projectNs}, hierarchy)
+	switch {
+	case errors.IsNotFound(err):
+		hierarchy = &hnc.HierarchyConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: hnc.Singleton, Namespace: projectNs},
+			Spec:       hnc.HierarchyConfigurationSpec{Parent: tenantNs},
+		}
+		err = cli.Create(ctx, hierarchy)
+	case err == nil && hierarchy.Spec.Parent != tenantNs:
+		hierarchy.Spec.Parent = tenantNs
+		err = cli.Update(ctx, hierarchy)
+	}
+	if err != nil {
+		return fmt.Errorf("set parent of namespace %v to %v failed: %v", projectNs, tenantNs, err)
+	}
+
+	if ns.Annotations[hnc.SubnamespaceOf] != tenantNs {
+		if ns.Annotations == nil {
+			ns.Annotations = make(map[string]string)
+		}
+		ns.Annotations[hnc.SubnamespaceOf] = tenantNs
+		if err = cli.Update(ctx, ns); err != nil {
+			return fmt.Errorf("attach namespace %v to %v failed: %v", projectNs, tenantNs, err)
+		}
+	}
+
+	return nil
+}
+
+// relabelAnchor sets tenant of anchor under project, labels of anchor are
+// applied to its namespace by HNC
+func relabelAnchor(ctx context.Context, cli client.Client, anchor *hnc.SubnamespaceAnchor, project, tenant string) error {
+	inProject, tenantIndex := false, -1
+	for i, kv := range anchor.Spec.Labels {
+		switch {
+		case kv.Key == constants.HncProjectLabel && kv.Value == project:
+			inProject = true
+		case kv.Key == constants.HncTenantLabel && kv.Value != tenant:
+			tenantIndex = i
+		}
+	}
+	if !inProject || tenantIndex < 0 {
+		return nil
+	}
+
+	anchor.Spec.Labels[tenantIndex].Value = tenant
+	err := cli.Update(ctx, anchor)
+	if err != nil {
+		return fmt.Errorf("update tenant of anchor %v/%v failed: %v", anchor.Namespace, anchor.Name, err)
+	}
+	return nil
+}
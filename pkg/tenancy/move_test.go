@@ -0,0 +1,307 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
+
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/quota"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func newCubeQuota(name, cluster, parent string, kind quotav1.TargetKind, target, hard, used string) *quotav1.CubeResourceQuota {
+	q := &quotav1.CubeResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{constants.ClusterLabel: cluster}},
+		Spec: quotav1.CubeResourceQuotaSpec{
+			Hard:        corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(hard)},
+			ParentQuota: parent,
+			Target:      quotav1.TargetObj{Kind: kind, Name: target},
+		},
+		Status: quotav1.CubeResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(hard)},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse(used)},
+		},
+	}
+	if kind == quotav1.ProjectObj {
+		q.Labels[constants.ProjectLabel] = target
+		q.Labels[constants.TenantLabel] = "tenant-1"
+	}
+	return q
+}
+
+func newMoveClient(objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = tenantv1.AddToScheme(scheme)
+	_ = quotav1.AddToScheme(scheme)
+	_ = hnc.AddToScheme(scheme)
+
+	tenant1Quota := newCubeQuota("pivot.tenant.tenant-1", "pivot", "", quotav1.TenantObj, "tenant-1", "10", "2")
+	tenant1Quota.Status.SubResourceQuotas = []string{"pivot.project.project-1." + quota.SubFix}
+
+	objs = append(objs,
+		&tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "tenant-1"}},
+		&tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "tenant-2"}},
+		&tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: "tenant-3"}, Spec: tenantv1.TenantSpec{State: tenantv1.TenantSuspended}},
+		&tenantv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1", Labels: map[string]string{constants.TenantLabel: "tenant-1"}}},
+		tenant1Quota,
+		newCubeQuota("pivot.project.project-1", "pivot", "pivot.tenant.tenant-1", quotav1.ProjectObj, "project-1", "2", "0"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "kubecube-project-project-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{
+			Name:      "alice-in-project-1",
+			Namespace: "kubecube-project-project-1",
+			Labels:    map[string]string{constants.TenantLabel: "tenant-1", constants.ProjectLabel: "project-1"},
+		}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{
+			Name:      "bob-in-tenant-1",
+			Namespace: "kubecube-project-project-1",
+			Labels:    map[string]string{constants.TenantLabel: "tenant-1", hncInheritedLabel: "kubecube-tenant-tenant-1"},
+		}},
+	)
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+}
+
+func TestPlanProjectMove(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	project := &tenantv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1", Labels: map[string]string{constants.TenantLabel: "tenant-1"}}}
+
+	// tenant has no quota in cluster of project
+	cli := newMoveClient()
+	_, err := PlanProjectMove(ctx, cli, project, "tenant-2")
+	assert.NotNil(err)
+
+	// quota of project overloads tenant
+	cli = newMoveClient(newCubeQuota("pivot.tenant.tenant-2", "pivot", "", quotav1.TenantObj, "tenant-2", "4", "3"))
+	_, err = PlanProjectMove(ctx, cli, project, "tenant-2")
+	assert.NotNil(err)
+
+	// tenant not active
+	cli = newMoveClient(newCubeQuota("pivot.tenant.tenant-3", "pivot", "", quotav1.TenantObj, "tenant-3", "4", "0"))
+	_, err = PlanProjectMove(ctx, cli, project, "tenant-3")
+	assert.NotNil(err)
+
+	cli = newMoveClient(newCubeQuota("pivot.tenant.tenant-2", "pivot", "", quotav1.TenantObj, "tenant-2", "4", "2"))
+	plan, err := PlanProjectMove(ctx, cli, project, "tenant-2")
+	assert.Nil(err)
+	assert.Equal(&ProjectMove{
+		Project:      "project-1",
+		From:         "tenant-1",
+		To:           "tenant-2",
+		Quotas:       []QuotaMove{{Name: "pivot.project.project-1", Cluster: "pivot", From: "pivot.tenant.tenant-1", To: "pivot.tenant.tenant-2"}},
+		RoleBindings: []string{"kubecube-project-project-1/alice-in-project-1"},
+	}, plan)
+
+	// dry run changes nothing
+	current := &tenantv1.Project{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, current))
+	assert.Equal("tenant-1", current.Labels[constants.TenantLabel])
+}
+
+func TestMoveProject(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMoveClient(newCubeQuota("pivot.tenant.tenant-2", "pivot", "", quotav1.TenantObj, "tenant-2", "4", "0"))
+	project := &tenantv1.Project{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+
+	_, err := MoveProject(ctx, cli, project, "tenant-2")
+	assert.Nil(err)
+
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+	assert.Equal("tenant-2", project.Labels[constants.TenantLabel])
+
+	q := &quotav1.CubeResourceQuota{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "pivot.project.project-1"}, q))
+	assert.Equal("pivot.tenant.tenant-2", q.Spec.ParentQuota)
+	assert.Equal("tenant-2", q.Labels[constants.TenantLabel])
+
+	// used of quotas of both tenants refreshed
+	source := &quotav1.CubeResourceQuota{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "pivot.tenant.tenant-1"}, source))
+	assert.Empty(source.Status.SubResourceQuotas)
+	used := source.Status.Used[corev1.ResourceRequestsCPU]
+	assert.Equal("0", used.String())
+
+	dest := &quotav1.CubeResourceQuota{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "pivot.tenant.tenant-2"}, dest))
+	assert.Equal([]string{"pivot.project.project-1." + quota.SubFix}, dest.Status.SubResourceQuotas)
+	used = dest.Status.Used[corev1.ResourceRequestsCPU]
+	assert.Equal("2", used.String())
+
+	rb := &rbacv1.RoleBinding{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "alice-in-project-1", Namespace: "kubecube-project-project-1"}, rb))
+	assert.Equal("tenant-2", rb.Labels[constants.TenantLabel])
+
+	// role binding propagated from tenant is left to HNC
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "bob-in-tenant-1", Namespace: "kubecube-project-project-1"}, rb))
+	assert.Equal("tenant-1", rb.Labels[constants.TenantLabel])
+
+	// move again changes nothing
+	plan, err := MoveProject(ctx, cli, project, "tenant-2")
+	assert.Nil(err)
+	assert.Empty(plan.Quotas)
+	assert.Empty(plan.RoleBindings)
+}
+
+func TestReparentProject(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMoveClient(
+		&hnc.SubnamespaceAnchor{ObjectMeta: metav1.ObjectMeta{Name: "kubecube-project-project-1", Namespace: "kubecube-tenant-tenant-1"}},
+		&hnc.SubnamespaceAnchor{
+			ObjectMeta: metav1.ObjectMeta{Name: "ns-1", Namespace: "kubecube-project-project-1"},
+			Spec: hnc.SubnamespaceAnchorSpec{Labels: []hnc.MetaKVP{
+				{Key: constants.HncTenantLabel, Value: "tenant-1"},
+				{Key: constants.HncProjectLabel, Value: "project-1"},
+			}},
+		},
+		&hnc.HierarchyConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: hnc.Singleton, Namespace: "kubecube-project-project-1"},
+			Spec:       hnc.HierarchyConfigurationSpec{Parent: "kubecube-tenant-tenant-1"},
+		},
+	)
+	ns := &corev1.Namespace{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "kubecube-project-project-1"}, ns))
+	ns.Annotations = map[string]string{hnc.SubnamespaceOf: "kubecube-tenant-tenant-1"}
+	assert.Nil(cli.Update(ctx, ns))
+
+	assert.Nil(ReparentProject(ctx, cli, "project-1", "tenant-2", "kubecube-tenant-tenant-2"))
+
+	// anchor in old tenant is deleted and namespace is marked as subnamespace of new tenant
+	err := cli.Get(ctx, types.NamespacedName{Name: "kubecube-project-project-1", Namespace: "kubecube-tenant-tenant-1"}, &hnc.SubnamespaceAnchor{})
+	assert.True(errors.IsNotFound(err))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "kubecube-project-project-1"}, ns))
+	assert.Equal("kubecube-tenant-tenant-2", ns.Annotations[hnc.SubnamespaceOf])
+	hierarchy := &hnc.HierarchyConfiguration{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: hnc.Singleton, Namespace: "kubecube-project-project-1"}, hierarchy))
+	assert.Equal("kubecube-tenant-tenant-2", hierarchy.Spec.Parent)
+
+	// namespaces under project follow
+	anchor := &hnc.SubnamespaceAnchor{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "ns-1", Namespace: "kubecube-project-project-1"}, anchor))
+	assert.Equal(hnc.MetaKVP{Key: constants.HncTenantLabel, Value: "tenant-2"}, anchor.Spec.Labels[0])
+
+	// nothing to do once anchor created in new tenant
+	assert.Nil(cli.Create(ctx, &hnc.SubnamespaceAnchor{ObjectMeta: metav1.ObjectMeta{Name: "kubecube-project-project-1", Namespace: "kubecube-tenant-tenant-2"}}))
+	assert.Nil(ReparentProject(ctx, cli, "project-1", "tenant-2", "kubecube-tenant-tenant-2"))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "kubecube-project-project-1", Namespace: "kubecube-tenant-tenant-2"}, &hnc.SubnamespaceAnchor{}))
+}
+
+// failingClient fails to update the object with given name
+type failingClient struct {
+	client.Client
+	name string
+}
+
+func (c *failingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if obj.GetName() == c.name {
+		return errors.NewInternalError(fmt.Errorf("update %v failed", c.name))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestMoveProjectRollback(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := &failingClient{
+		Client: newMoveClient(newCubeQuota("pivot.tenant.tenant-2", "pivot", "", quotav1.TenantObj, "tenant-2", "4", "0")),
+		name:   "pivot.project.project-1",
+	}
+	project := &tenantv1.Project{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+
+	_, err := MoveProject(ctx, cli, project, "tenant-2")
+	assert.NotNil(err)
+
+	// project is back to source tenant since its quota failed to follow
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+	assert.Equal("tenant-1", project.Labels[constants.TenantLabel])
+
+	q := &quotav1.CubeResourceQuota{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "pivot.project.project-1"}, q))
+	assert.Equal("pivot.tenant.tenant-1", q.Spec.ParentQuota)
+
+	rb := &rbacv1.RoleBinding{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "alice-in-project-1", Namespace: "kubecube-project-project-1"}, rb))
+	assert.Equal("tenant-1", rb.Labels[constants.TenantLabel])
+}
+
+func TestMoveProjectRevalidate(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newMoveClient(newCubeQuota("pivot.tenant.tenant-2", "pivot", "", quotav1.TenantObj, "tenant-2", "4", "0"))
+	project := &tenantv1.Project{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+	_, err := PlanProjectMove(ctx, cli, project, "tenant-2")
+	assert.Nil(err)
+
+	// tenant is suspended after planned
+	dest := &tenantv1.Tenant{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "tenant-2"}, dest))
+	dest.Spec.State = tenantv1.TenantSuspended
+	assert.Nil(cli.Update(ctx, dest))
+
+	_, err = MoveProject(ctx, cli, project, "tenant-2")
+	assert.NotNil(err)
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "project-1"}, project))
+	assert.Equal("tenant-1", project.Labels[constants.TenantLabel])
+}
+
+func TestRollupParent(t *testing.T) {
+	assert := assert.New(t)
+
+	status := &tenantv1.CommonStatus{Clusters: []tenantv1.ClusterSummary{
+		{Name: "member-1", Parent: "kubecube-tenant-tenant-2"},
+		{Name: "pivot", Parent: "kubecube-tenant-tenant-1"},
+	}}
+	RollupParent(status, "kubecube-tenant-tenant-2", 1)
+	cond := meta.FindStatusCondition(status.Conditions, tenantv1.Reparented)
+	assert.Equal(metav1.ConditionFalse, cond.Status)
+	assert.Equal(tenantv1.ReasonParentMismatched, cond.Reason)
+	assert.Contains(cond.Message, "pivot")
+
+	status.Clusters[1].Parent = "kubecube-tenant-tenant-2"
+	RollupParent(status, "kubecube-tenant-tenant-2", 1)
+	assert.True(meta.IsStatusConditionTrue(status.Conditions, tenantv1.Reparented))
+}
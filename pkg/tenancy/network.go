@@ -0,0 +1,237 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+const (
+	// IsolationPolicyName is the name of network policy isolates namespace of tenant
+	IsolationPolicyName = "kubecube-tenant-isolation"
+
+	// ExceptionPolicyName is the name of network policy allows the exceptions of project
+	ExceptionPolicyName = "kubecube-project-exceptions"
+
+	// namespaceNameLabel is set to every namespace by kubernetes since v1.21
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// systemNamespaces returns the namespaces allowed to access namespaces of
+// every tenant, such as kube-system where dns and ingress controller running
+func systemNamespaces() []string {
+	return []string{metav1.NamespaceSystem, env.CubeNamespace()}
+}
+
+// tenantTreeSelector selects the namespace of tenant and all namespaces
+// under it by the depth label maintained by HNC
+func tenantTreeSelector(tenant string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      constants.TenantNsPrefix + tenant + constants.HncSuffix,
+			Operator: metav1.LabelSelectorOpExists,
+		}},
+	}
+}
+
+// ApplyTenantIsolation ensures the isolation policy in every namespace of tenant
+// if isolation enabled, or removes them otherwise
+func ApplyTenantIsolation(ctx context.Context, cli client.Client, tenant string, enabled bool) error {
+	namespaces, err := NamespacesOf(ctx, cli, tenant)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		if !enabled {
+			err = deletePolicy(ctx, cli, ns.Name, IsolationPolicyName)
+		} else {
+			err = applyPolicy(ctx, cli, isolationPolicy(tenant, ns.Name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyProjectExceptions ensures the exception policy in every namespace of
+// project if isolation of tenant enabled and project declares exceptions, or
+// removes them otherwise
+func ApplyProjectExceptions(ctx context.Context, cli client.Client, project string, enabled bool, peers []tenantv1.NetworkPeer) error {
+	namespaces, err := ProjectNamespacesOf(ctx, cli, project)
+	if err != nil {
+		return err
+	}
+
+	for _, ns := range namespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		if !enabled || len(peers) == 0 {
+			err = deletePolicy(ctx, cli, ns.Name, ExceptionPolicyName)
+		} else {
+			err = applyPolicy(ctx, cli, exceptionPolicy(project, ns.Name, peers))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateNetworkPeers checks the network exceptions declared by project
+func ValidateNetworkPeers(peers []tenantv1.NetworkPeer) error {
+	for i, peer := range peers {
+		set := 0
+		for _, v := range []string{peer.Tenant, peer.Project, peer.CIDR} {
+			if len(v) > 0 {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("networkExceptions[%v]: exactly one of tenant, project and cidr should be set", i)
+		}
+		if len(peer.CIDR) > 0 {
+			if _, _, err := net.ParseCIDR(peer.CIDR); err != nil {
+				return fmt.Errorf("networkExceptions[%v]: invalid cidr %v", i, peer.CIDR)
+			}
+		}
+	}
+	return nil
+}
+
+// isolationPolicy allows ingress from namespaces of the same tenant and
+// system namespaces, ingress from the others is denied
+func isolationPolicy(tenant, namespace string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IsolationPolicyName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.NetworkIsolationLabel: "true",
+				constants.TenantLabel:           tenant,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{NamespaceSelector: tenantTreeSelector(tenant)},
+					{NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      namespaceNameLabel,
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   systemNamespaces(),
+						}},
+					}},
+				},
+			}},
+		},
+	}
+}
+
+// exceptionPolicy allows ingress from the peers declared by project
+func exceptionPolicy(project, namespace string, peers []tenantv1.NetworkPeer) *networkingv1.NetworkPolicy {
+	from := make([]networkingv1.NetworkPolicyPeer, 0, len(peers))
+	for _, peer := range peers {
+		switch {
+		case len(peer.Tenant) > 0:
+			from = append(from, networkingv1.NetworkPolicyPeer{NamespaceSelector: tenantTreeSelector(peer.Tenant)})
+		case len(peer.Project) > 0:
+			from = append(from, networkingv1.NetworkPolicyPeer{NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{constants.HncProjectLabel: peer.Project},
+			}})
+		case len(peer.CIDR) > 0:
+			from = append(from, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: peer.CIDR}})
+		}
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ExceptionPolicyName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.NetworkIsolationLabel: "true",
+				constants.ProjectLabel:          project,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{{From: from}},
+		},
+	}
+}
+
+func applyPolicy(ctx context.Context, cli client.Client, policy *networkingv1.NetworkPolicy) error {
+	res, err := controllerutil.CreateOrUpdate(ctx, cli, policy, mutateFor(policy))
+	if err != nil {
+		return fmt.Errorf("apply network policy %v/%v failed: %v", policy.Namespace, policy.Name, err)
+	}
+	if res != controllerutil.OperationResultNone {
+		clog.Info("network policy %v/%v %v", policy.Namespace, policy.Name, res)
+	}
+	return nil
+}
+
+// deletePolicy deletes the network policy generated by isolation if exists
+func deletePolicy(ctx context.Context, cli client.Client, namespace, name string) error {
+	policy := &networkingv1.NetworkPolicy{}
+	err := cli.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, policy)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if _, ok := policy.Labels[constants.NetworkIsolationLabel]; !ok {
+		return nil
+	}
+	clog.Info("delete network policy %v/%v", namespace, name)
+	err = cli.Delete(ctx, policy)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// IsolationPolicyOwner returns the tenant or project the network policy
+// generated for, returns empty if policy is not generated by isolation
+func IsolationPolicyOwner(obj client.Object) (tenant string, project string) {
+	if _, ok := obj.GetLabels()[constants.NetworkIsolationLabel]; !ok {
+		return "", ""
+	}
+	return obj.GetLabels()[constants.TenantLabel], obj.GetLabels()[constants.ProjectLabel]
+}
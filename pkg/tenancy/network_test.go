@@ -0,0 +1,133 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+func TestApplyTenantIsolation(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// network policy of user with the same name is not touched
+	cli := newProjectClient(&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: IsolationPolicyName, Namespace: "other"}})
+
+	assert.Nil(ApplyTenantIsolation(ctx, cli, "tenant-1", true))
+
+	for _, ns := range []string{"kubecube-project-project-1", "ns-1"} {
+		policy := &networkingv1.NetworkPolicy{}
+		assert.Nil(cli.Get(ctx, types.NamespacedName{Name: IsolationPolicyName, Namespace: ns}, policy))
+		assert.Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policy.Spec.PolicyTypes)
+		assert.Len(policy.Spec.Ingress[0].From, 2)
+	}
+
+	assert.Nil(ApplyTenantIsolation(ctx, cli, "tenant-1", false))
+
+	err := cli.Get(ctx, types.NamespacedName{Name: IsolationPolicyName, Namespace: "ns-1"}, &networkingv1.NetworkPolicy{})
+	assert.True(errors.IsNotFound(err))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: IsolationPolicyName, Namespace: "other"}, &networkingv1.NetworkPolicy{}))
+}
+
+// allowedBy tells if ingress from namespace with labels is allowed by policy
+func allowedBy(policy *networkingv1.NetworkPolicy, nsLabels map[string]string) bool {
+	for _, rule := range policy.Spec.Ingress {
+		for _, peer := range rule.From {
+			if peer.NamespaceSelector == nil {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(peer.NamespaceSelector)
+			if err == nil && selector.Matches(labels.Set(nsLabels)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestIsolationPolicyPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := isolationPolicy("tenant-1", "ns-1")
+
+	// namespaces of the same tenant
+	assert.True(allowedBy(policy, map[string]string{"kubecube-tenant-tenant-1" + constants.HncSuffix: "0"}))
+	assert.True(allowedBy(policy, map[string]string{
+		"kubecube-tenant-tenant-1" + constants.HncSuffix: "2",
+		constants.HncTenantLabel:                         "tenant-1",
+	}))
+	// system namespaces
+	assert.True(allowedBy(policy, map[string]string{namespaceNameLabel: "kube-system"}))
+	assert.True(allowedBy(policy, map[string]string{namespaceNameLabel: env.CubeNamespace()}))
+
+	// namespace of other tenant has no tenant label of HNC, it is denied too
+	assert.False(allowedBy(policy, map[string]string{
+		"kubecube-tenant-tenant-2" + constants.HncSuffix: "0",
+		namespaceNameLabel: "kubecube-tenant-tenant-2",
+	}))
+	assert.False(allowedBy(policy, map[string]string{
+		"kubecube-tenant-tenant-2" + constants.HncSuffix: "2",
+		constants.HncTenantLabel:                         "tenant-2",
+	}))
+	assert.False(allowedBy(policy, map[string]string{namespaceNameLabel: "default"}))
+}
+
+func TestApplyProjectExceptions(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	cli := newProjectClient()
+
+	peers := []tenantv1.NetworkPeer{{Tenant: "tenant-2"}, {Project: "project-3"}, {CIDR: "10.0.0.0/8"}}
+	assert.Nil(ApplyProjectExceptions(ctx, cli, "project-1", true, peers))
+
+	policy := &networkingv1.NetworkPolicy{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: ExceptionPolicyName, Namespace: "ns-1"}, policy))
+	from := policy.Spec.Ingress[0].From
+	assert.Len(from, 3)
+	assert.True(allowedBy(policy, map[string]string{"kubecube-tenant-tenant-2" + constants.HncSuffix: "0"}))
+	assert.Equal(map[string]string{constants.HncProjectLabel: "project-3"}, from[1].NamespaceSelector.MatchLabels)
+	assert.Equal("10.0.0.0/8", from[2].IPBlock.CIDR)
+
+	_, project := IsolationPolicyOwner(policy)
+	assert.Equal("project-1", project)
+
+	// exceptions are removed once isolation of tenant disabled
+	assert.Nil(ApplyProjectExceptions(ctx, cli, "project-1", false, peers))
+	err := cli.Get(ctx, types.NamespacedName{Name: ExceptionPolicyName, Namespace: "ns-1"}, &networkingv1.NetworkPolicy{})
+	assert.True(errors.IsNotFound(err))
+}
+
+func TestValidateNetworkPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(ValidateNetworkPeers([]tenantv1.NetworkPeer{{Tenant: "tenant-2"}, {CIDR: "192.168.0.0/16"}}))
+	assert.NotNil(ValidateNetworkPeers([]tenantv1.NetworkPeer{{}}))
+	assert.NotNil(ValidateNetworkPeers([]tenantv1.NetworkPeer{{Tenant: "tenant-2", Project: "project-3"}}))
+	assert.NotNil(ValidateNetworkPeers([]tenantv1.NetworkPeer{{CIDR: "10.0.0.1"}}))
+}
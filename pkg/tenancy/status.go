@@ -0,0 +1,242 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tenancy computes the observed state of tenants and projects. Warden
+// of every cluster reports the state of its own cluster, and warden of pivot
+// cluster rolls up the reports of all clusters into conditions.
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// ClusterSummaryOf returns the state of tenant or project in cluster. The
+// namespace is the namespace of tenant or project itself, and namespaces
+// labeled with labelKey=labelValue are counted except the namespace itself.
+func ClusterSummaryOf(ctx context.Context, cli client.Reader, cluster, namespace, labelKey, labelValue string) (tenantv1.ClusterSummary, error) {
+	summary := tenantv1.ClusterSummary{Name: cluster}
+
+	ns := &corev1.Namespace{}
+	err := cli.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+	if client.IgnoreNotFound(err) != nil {
+		return summary, err
+	}
+	summary.NamespaceReady = err == nil && ns.Status.Phase == corev1.NamespaceActive && ns.DeletionTimestamp == nil
+	if err == nil {
+		summary.State = AppliedState(ns)
+		summary.Parent = ns.Annotations[hnc.SubnamespaceOf]
+	}
+
+	nsList := &corev1.NamespaceList{}
+	err = cli.List(ctx, nsList, client.MatchingLabels{labelKey: labelValue})
+	if err != nil {
+		return summary, err
+	}
+	for _, item := range nsList.Items {
+		if item.Name == namespace || strings.HasPrefix(item.Name, constants.ProjectNsPrefix) {
+			continue
+		}
+		summary.NamespaceCount++
+	}
+
+	return summary, nil
+}
+
+// SetClusterSummary adds or updates the summary of cluster into status,
+// returns true if status changed
+func SetClusterSummary(status *tenantv1.CommonStatus, summary tenantv1.ClusterSummary) bool {
+	for i := range status.Clusters {
+		if status.Clusters[i].Name == summary.Name {
+			if status.Clusters[i] == summary {
+				return false
+			}
+			status.Clusters[i] = summary
+			return true
+		}
+	}
+	status.Clusters = append(status.Clusters, summary)
+	sort.Slice(status.Clusters, func(i, j int) bool {
+		return status.Clusters[i].Name < status.Clusters[j].Name
+	})
+	return true
+}
+
+// PruneClusters removes the summaries of clusters not in given clusters
+func PruneClusters(status *tenantv1.CommonStatus, clusters sets.String) {
+	summaries := status.Clusters[:0]
+	for _, summary := range status.Clusters {
+		if clusters.Has(summary.Name) {
+			summaries = append(summaries, summary)
+		}
+	}
+	status.Clusters = summaries
+}
+
+// ClusterNames returns the names of all clusters managed by KubeCube
+func ClusterNames(ctx context.Context, cli client.Reader) (sets.String, error) {
+	clusterList := &clusterv1.ClusterList{}
+	err := cli.List(ctx, clusterList)
+	if err != nil {
+		return nil, err
+	}
+	clusters := sets.NewString()
+	for _, c := range clusterList.Items {
+		clusters.Insert(c.Name)
+	}
+	return clusters, nil
+}
+
+// CountMembers returns the number of users bound by rbac role bindings in namespace
+func CountMembers(ctx context.Context, cli client.Reader, namespace string) (int32, error) {
+	roleBindingList := &rbacv1.RoleBindingList{}
+	err := cli.List(ctx, roleBindingList, client.InNamespace(namespace))
+	if err != nil {
+		return 0, err
+	}
+
+	members := sets.NewString()
+	for _, rb := range roleBindingList.Items {
+		if t, err := strconv.ParseBool(rb.Labels[constants.RbacLabel]); err != nil || !t {
+			continue
+		}
+		for _, s := range rb.Subjects {
+			if s.Kind == rbacv1.UserKind {
+				members.Insert(s.Name)
+			}
+		}
+	}
+
+	return int32(members.Len()), nil
+}
+
+// QuotaOf returns the quota of CubeResourceQuota targets to given object,
+// returns nil if no CubeResourceQuota found
+func QuotaOf(ctx context.Context, cli client.Reader, kind quotav1.TargetKind, name string) (*tenantv1.QuotaSummary, error) {
+	quotaList := &quotav1.CubeResourceQuotaList{}
+	err := cli.List(ctx, quotaList)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quotaList.Items {
+		if q.Spec.Target.Kind == kind && q.Spec.Target.Name == name {
+			return &tenantv1.QuotaSummary{
+				Name: q.Name,
+				Hard: q.Status.Hard.DeepCopy(),
+				Used: q.Status.Used.DeepCopy(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Rollup computes the totals and conditions of status by summaries of
+// clusters, clusters are all clusters expected to report.
+func Rollup(status *tenantv1.CommonStatus, clusters sets.String, generation int64) {
+	status.NamespaceCount = 0
+	reported := sets.NewString()
+	notReady := []string{}
+	for _, summary := range status.Clusters {
+		status.NamespaceCount += summary.NamespaceCount
+		reported.Insert(summary.Name)
+		if !summary.NamespaceReady {
+			notReady = append(notReady, summary.Name)
+		}
+	}
+
+	if len(notReady) == 0 && reported.Len() > 0 {
+		setCondition(status, tenantv1.NamespaceReady, metav1.ConditionTrue, tenantv1.ReasonNamespaceActive,
+			"namespace is active in all clusters", generation)
+	} else {
+		setCondition(status, tenantv1.NamespaceReady, metav1.ConditionFalse, tenantv1.ReasonNamespaceNotReady,
+			fmt.Sprintf("namespace is not ready in clusters %v", notReady), generation)
+	}
+
+	if status.Quota != nil {
+		setCondition(status, tenantv1.QuotaBound, metav1.ConditionTrue, tenantv1.ReasonQuotaFound,
+			fmt.Sprintf("bound to CubeResourceQuota %v", status.Quota.Name), generation)
+	} else {
+		setCondition(status, tenantv1.QuotaBound, metav1.ConditionFalse, tenantv1.ReasonQuotaNotFound,
+			"no CubeResourceQuota targets to it", generation)
+	}
+
+	if missing := clusters.Difference(reported); missing.Len() == 0 {
+		setCondition(status, tenantv1.SyncedToClusters, metav1.ConditionTrue, tenantv1.ReasonSynced,
+			"reported by all clusters", generation)
+	} else {
+		setCondition(status, tenantv1.SyncedToClusters, metav1.ConditionFalse, tenantv1.ReasonNotSynced,
+			fmt.Sprintf("not reported by clusters %v", missing.List()), generation)
+	}
+}
+
+// RollupParent computes the condition of if the namespace of project is under
+// parent in all clusters reported
+func RollupParent(status *tenantv1.CommonStatus, parent string, generation int64) {
+	mismatched := []string{}
+	for _, summary := range status.Clusters {
+		if summary.Parent != parent {
+			mismatched = append(mismatched, summary.Name)
+		}
+	}
+
+	if len(mismatched) == 0 {
+		setCondition(status, tenantv1.Reparented, metav1.ConditionTrue, tenantv1.ReasonParentMatched,
+			fmt.Sprintf("namespace is under %v in all clusters", parent), generation)
+	} else {
+		setCondition(status, tenantv1.Reparented, metav1.ConditionFalse, tenantv1.ReasonParentMismatched,
+			fmt.Sprintf("namespace is not under %v in clusters %v", parent, mismatched), generation)
+	}
+}
+
+// RollupState returns the lifecycle state applied in all clusters, returns
+// empty if clusters are in different states
+func RollupState(status *tenantv1.CommonStatus) tenantv1.TenantState {
+	var state tenantv1.TenantState
+	for i, summary := range status.Clusters {
+		if i > 0 && summary.State != state {
+			return ""
+		}
+		state = summary.State
+	}
+	return state
+}
+
+func setCondition(status *tenantv1.CommonStatus, conditionType string, s metav1.ConditionStatus, reason, message string, generation int64) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             s,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
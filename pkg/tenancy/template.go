@@ -0,0 +1,360 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// hncInheritedLabel marks the resource propagated by HNC from ancestor namespace
+const hncInheritedLabel = "hnc.x-k8s.io/inherited-from"
+
+// ProjectNamespacesOf returns all namespaces of project in cluster, includes
+// the namespace of project itself
+func ProjectNamespacesOf(ctx context.Context, cli client.Reader, project string) ([]corev1.Namespace, error) {
+	nsList := &corev1.NamespaceList{}
+	err := cli.List(ctx, nsList, client.MatchingLabels{constants.HncProjectLabel: project})
+	if err != nil {
+		return nil, err
+	}
+
+	projectNs := constants.ProjectNsPrefix + project
+	for _, ns := range nsList.Items {
+		if ns.Name == projectNs {
+			return nsList.Items, nil
+		}
+	}
+
+	ns := corev1.Namespace{}
+	err = cli.Get(ctx, types.NamespacedName{Name: projectNs}, &ns)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+	if err == nil {
+		return append([]corev1.Namespace{ns}, nsList.Items...), nil
+	}
+
+	return nsList.Items, nil
+}
+
+// TemplateConflictError means the resources declared by template already
+// exist but are not created by template, they are left untouched
+type TemplateConflictError struct {
+	// Resources in form of kind namespace/name
+	Resources []string
+}
+
+func (e *TemplateConflictError) Error() string {
+	return fmt.Sprintf("resources not created by template already exist: %v", strings.Join(e.Resources, ", "))
+}
+
+// IsTemplateConflict tells if err is caused by the resources not created by template
+func IsTemplateConflict(err error) bool {
+	_, ok := err.(*TemplateConflictError)
+	return ok
+}
+
+// ApplyTemplate applies the resources declared by template to every namespace
+// of project and corrects the drift of them. Resources created by template but
+// no longer declared are deleted, a nil template deletes all of them. Existing
+// resources not created by template are never adopted, they are skipped and
+// reported by TemplateConflictError after the others applied, so is the
+// resource quota of template in namespace has resource quota already.
+func ApplyTemplate(ctx context.Context, cli client.Client, project string, template *tenantv1.ProjectTemplate) error {
+	namespaces, err := ProjectNamespacesOf(ctx, cli, project)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+
+	for _, ns := range namespaces {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+
+		desired, skipped, err := renderTemplate(ctx, cli, template, project, ns.Name)
+		if err != nil {
+			return err
+		}
+		conflicts = append(conflicts, skipped...)
+
+		applied := sets.NewString()
+		for _, obj := range desired {
+			owned, err := createdByTemplate(ctx, cli, obj)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				clog.Warn("%T %v/%v of template %v conflicts with existing one, skipped", obj, obj.GetNamespace(), obj.GetName(), template.Name)
+				conflicts = append(conflicts, fmt.Sprintf("%T %v/%v", obj, obj.GetNamespace(), obj.GetName()))
+				continue
+			}
+			res, err := applyObject(ctx, cli, obj)
+			if err != nil {
+				return fmt.Errorf("apply %T %v/%v of template failed: %v", obj, obj.GetNamespace(), obj.GetName(), err)
+			}
+			if res != controllerutil.OperationResultNone {
+				clog.Info("%T %v/%v of template %v %v", obj, obj.GetNamespace(), obj.GetName(), template.Name, res)
+			}
+			applied.Insert(templateKey(obj))
+		}
+
+		err = pruneTemplate(ctx, cli, ns.Name, applied)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &TemplateConflictError{Resources: conflicts}
+	}
+	return nil
+}
+
+// createdByTemplate tells if the resource does not exist yet or is created by template
+func createdByTemplate(ctx context.Context, cli client.Reader, obj client.Object) (bool, error) {
+	// decoding into a copy of desired one merges labels, use an empty one
+	existing := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	err := cli.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get %T %v/%v failed: %v", obj, obj.GetNamespace(), obj.GetName(), err)
+	}
+	_, ok := existing.GetLabels()[constants.ProjectTemplateLabel]
+	return ok, nil
+}
+
+// applyObject creates or updates resource of template. Role binding is
+// recreated when its roleRef changed since roleRef is immutable.
+func applyObject(ctx context.Context, cli client.Client, obj client.Object) (controllerutil.OperationResult, error) {
+	if rb, ok := obj.(*rbacv1.RoleBinding); ok {
+		existing := &rbacv1.RoleBinding{}
+		err := cli.Get(ctx, types.NamespacedName{Namespace: rb.Namespace, Name: rb.Name}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, err
+		}
+		if err == nil && existing.RoleRef != rb.RoleRef {
+			clog.Info("roleRef of role binding %v/%v changed, recreate it", rb.Namespace, rb.Name)
+			err = cli.Delete(ctx, existing)
+			if err != nil && !errors.IsNotFound(err) {
+				return controllerutil.OperationResultNone, err
+			}
+		}
+	}
+	return controllerutil.CreateOrUpdate(ctx, cli, obj, mutateFor(obj))
+}
+
+// renderTemplate returns the resources declared by template for namespace and
+// the existing resources conflict with template
+func renderTemplate(ctx context.Context, cli client.Reader, template *tenantv1.ProjectTemplate, project, namespace string) ([]client.Object, []string, error) {
+	if template == nil {
+		return nil, nil, nil
+	}
+
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.ProjectTemplateLabel: template.Name,
+				constants.ProjectLabel:         project,
+			},
+		}
+	}
+
+	objs := []client.Object{}
+	for _, cm := range template.Spec.ConfigMaps {
+		objs = append(objs, &corev1.ConfigMap{ObjectMeta: meta(cm.Name), Data: cm.Data})
+	}
+	for _, lr := range template.Spec.LimitRanges {
+		objs = append(objs, &corev1.LimitRange{ObjectMeta: meta(lr.Name), Spec: *lr.Spec.DeepCopy()})
+	}
+	for _, np := range template.Spec.NetworkPolicies {
+		objs = append(objs, &networkingv1.NetworkPolicy{ObjectMeta: meta(np.Name), Spec: *np.Spec.DeepCopy()})
+	}
+
+	// role bindings in namespaces under project are propagated by HNC
+	if namespace == constants.ProjectNsPrefix+project {
+		for _, rb := range template.Spec.RoleBindings {
+			objs = append(objs, &rbacv1.RoleBinding{ObjectMeta: meta(rb.Name), RoleRef: rb.RoleRef, Subjects: rb.Subjects})
+		}
+	}
+
+	if rq := template.Spec.ResourceQuota; rq != nil {
+		quotaList := &corev1.ResourceQuotaList{}
+		err := cli.List(ctx, quotaList, client.InNamespace(namespace))
+		if err != nil {
+			return nil, nil, err
+		}
+		// resource quota of KubeCube or user takes precedence
+		var conflicts []string
+		for i := range quotaList.Items {
+			q := &quotaList.Items[i]
+			if _, ok := q.Labels[constants.ProjectTemplateLabel]; !ok {
+				conflicts = append(conflicts, fmt.Sprintf("%T %v/%v", q, q.Namespace, q.Name))
+			}
+		}
+		if len(conflicts) > 0 {
+			clog.Warn("resource quota %v of template %v conflicts with existing ones in namespace %v, skipped", rq.Name, template.Name, namespace)
+			return objs, conflicts, nil
+		}
+		objs = append(objs, &corev1.ResourceQuota{ObjectMeta: meta(rq.Name), Spec: *rq.Spec.DeepCopy()})
+	}
+
+	return objs, nil, nil
+}
+
+// mutateFor returns the function sets the desired state into existing resource
+func mutateFor(desired client.Object) controllerutil.MutateFn {
+	// desired is overwritten by existing resource before mutated, keeps a copy
+	copied := desired.DeepCopyObject().(client.Object)
+	labels := copied.GetLabels()
+	switch d := copied.(type) {
+	case *corev1.ConfigMap:
+		return func() error {
+			obj := desired.(*corev1.ConfigMap)
+			obj.Data = d.Data
+			mergeLabels(obj, labels)
+			return nil
+		}
+	case *corev1.LimitRange:
+		return func() error {
+			obj := desired.(*corev1.LimitRange)
+			obj.Spec = d.Spec
+			mergeLabels(obj, labels)
+			return nil
+		}
+	case *networkingv1.NetworkPolicy:
+		return func() error {
+			obj := desired.(*networkingv1.NetworkPolicy)
+			obj.Spec = d.Spec
+			mergeLabels(obj, labels)
+			return nil
+		}
+	case *rbacv1.RoleBinding:
+		return func() error {
+			obj := desired.(*rbacv1.RoleBinding)
+			// roleRef is immutable, role binding with different one is
+			// recreated by applyObject
+			obj.RoleRef = d.RoleRef
+			obj.Subjects = d.Subjects
+			mergeLabels(obj, labels)
+			return nil
+		}
+	case *corev1.ResourceQuota:
+		return func() error {
+			obj := desired.(*corev1.ResourceQuota)
+			obj.Spec = d.Spec
+			mergeLabels(obj, labels)
+			return nil
+		}
+	}
+	return func() error { return nil }
+}
+
+func mergeLabels(obj client.Object, labels map[string]string) {
+	l := obj.GetLabels()
+	if l == nil {
+		l = make(map[string]string)
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	obj.SetLabels(l)
+}
+
+// pruneTemplate deletes the resources created by template in namespace but not applied
+func pruneTemplate(ctx context.Context, cli client.Client, namespace string, applied sets.String) error {
+	lists := []client.ObjectList{
+		&corev1.ConfigMapList{},
+		&corev1.LimitRangeList{},
+		&networkingv1.NetworkPolicyList{},
+		&rbacv1.RoleBindingList{},
+		&corev1.ResourceQuotaList{},
+	}
+
+	for _, list := range lists {
+		err := cli.List(ctx, list, client.InNamespace(namespace), client.HasLabels{constants.ProjectTemplateLabel})
+		if err != nil {
+			return err
+		}
+		for _, obj := range templatedObjects(list) {
+			if _, ok := obj.GetLabels()[hncInheritedLabel]; ok || applied.Has(templateKey(obj)) {
+				continue
+			}
+			clog.Info("delete %T %v/%v of template %v", obj, obj.GetNamespace(), obj.GetName(), obj.GetLabels()[constants.ProjectTemplateLabel])
+			err = cli.Delete(ctx, obj)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func templatedObjects(list client.ObjectList) []client.Object {
+	objs := []client.Object{}
+	switch l := list.(type) {
+	case *corev1.ConfigMapList:
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	case *corev1.LimitRangeList:
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	case *networkingv1.NetworkPolicyList:
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	case *rbacv1.RoleBindingList:
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	case *corev1.ResourceQuotaList:
+		for i := range l.Items {
+			objs = append(objs, &l.Items[i])
+		}
+	}
+	return objs
+}
+
+// templateKey identifies resource of template in namespace
+func templateKey(obj client.Object) string {
+	return fmt.Sprintf("%T/%v/%v", obj, obj.GetLabels()[constants.ProjectTemplateLabel], obj.GetName())
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func newProjectClient(objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	objs = append(objs,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "kubecube-project-project-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "ns-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+}
+
+func newTemplate() *tenantv1.ProjectTemplate {
+	return &tenantv1.ProjectTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: tenantv1.ProjectTemplateSpec{
+			LimitRanges: []tenantv1.TemplateLimitRange{{
+				Name: "limits",
+				Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+					Type:    corev1.LimitTypeContainer,
+					Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				}}},
+			}},
+			NetworkPolicies: []tenantv1.TemplateNetworkPolicy{{Name: "same-namespace"}},
+			RoleBindings: []tenantv1.TemplateRoleBinding{{
+				Name:     "viewers",
+				RoleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+				Subjects: []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+			}},
+			ResourceQuota: &tenantv1.TemplateResourceQuota{
+				Name: "default-quota",
+				Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}},
+			},
+			ConfigMaps: []tenantv1.TemplateConfigMap{{Name: "info", Data: map[string]string{"owner": "platform"}}},
+		},
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newProjectClient()
+	template := newTemplate()
+
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", template))
+
+	for _, ns := range []string{"kubecube-project-project-1", "ns-1"} {
+		assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "limits", Namespace: ns}, &corev1.LimitRange{}))
+		assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "same-namespace", Namespace: ns}, &networkingv1.NetworkPolicy{}))
+		cm := &corev1.ConfigMap{}
+		assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: ns}, cm))
+		assert.Equal("default", cm.Labels[constants.ProjectTemplateLabel])
+		assert.Equal("project-1", cm.Labels[constants.ProjectLabel])
+	}
+	err := cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "other"}, &corev1.ConfigMap{})
+	assert.True(errors.IsNotFound(err))
+
+	// role bindings are propagated to namespaces under project by HNC
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "viewers", Namespace: "kubecube-project-project-1"}, &rbacv1.RoleBinding{}))
+	err = cli.Get(ctx, types.NamespacedName{Name: "viewers", Namespace: "ns-1"}, &rbacv1.RoleBinding{})
+	assert.True(errors.IsNotFound(err))
+
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "default-quota", Namespace: "kubecube-project-project-1"}, &corev1.ResourceQuota{}))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "default-quota", Namespace: "ns-1"}, &corev1.ResourceQuota{}))
+
+	// drift is corrected
+	cm := &corev1.ConfigMap{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "ns-1"}, cm))
+	cm.Data = map[string]string{"owner": "someone"}
+	assert.Nil(cli.Update(ctx, cm))
+	assert.Nil(cli.Delete(ctx, &corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "ns-1"}}))
+
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", template))
+
+	cm = &corev1.ConfigMap{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "ns-1"}, cm))
+	assert.Equal(map[string]string{"owner": "platform"}, cm.Data)
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "limits", Namespace: "ns-1"}, &corev1.LimitRange{}))
+
+	// resources no longer declared are deleted
+	template.Spec.ConfigMaps = nil
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", template))
+	err = cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "ns-1"}, &corev1.ConfigMap{})
+	assert.True(errors.IsNotFound(err))
+
+	// all resources of template are deleted without template
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", nil))
+	err = cli.Get(ctx, types.NamespacedName{Name: "limits", Namespace: "kubecube-project-project-1"}, &corev1.LimitRange{})
+	assert.True(errors.IsNotFound(err))
+	err = cli.Get(ctx, types.NamespacedName{Name: "viewers", Namespace: "kubecube-project-project-1"}, &rbacv1.RoleBinding{})
+	assert.True(errors.IsNotFound(err))
+	err = cli.Get(ctx, types.NamespacedName{Name: "default-quota", Namespace: "ns-1"}, &corev1.ResourceQuota{})
+	assert.True(errors.IsNotFound(err))
+}
+
+func TestApplyTemplateConflict(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// config map of user has the same name with the one of template
+	cli := newProjectClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "info", Namespace: "ns-1"},
+		Data:       map[string]string{"owner": "user"},
+	})
+
+	err := ApplyTemplate(ctx, cli, "project-1", newTemplate())
+	assert.True(IsTemplateConflict(err))
+	assert.Equal([]string{"*v1.ConfigMap ns-1/info"}, err.(*TemplateConflictError).Resources)
+
+	// resource of user is not adopted
+	cm := &corev1.ConfigMap{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "ns-1"}, cm))
+	assert.Equal(map[string]string{"owner": "user"}, cm.Data)
+	assert.NotContains(cm.Labels, constants.ProjectTemplateLabel)
+
+	// the others are applied anyway
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "kubecube-project-project-1"}, &corev1.ConfigMap{}))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "limits", Namespace: "ns-1"}, &corev1.LimitRange{}))
+
+	// resource of user is not pruned either
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", nil))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "info", Namespace: "ns-1"}, &corev1.ConfigMap{}))
+}
+
+func TestApplyTemplateQuotaConflict(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// namespace has resource quota of KubeCube already
+	cli := newProjectClient(&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "ns-1"}})
+
+	err := ApplyTemplate(ctx, cli, "project-1", newTemplate())
+	assert.True(IsTemplateConflict(err))
+	assert.Equal([]string{"*v1.ResourceQuota ns-1/quota"}, err.(*TemplateConflictError).Resources)
+
+	// resource quota of KubeCube takes precedence
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "default-quota", Namespace: "kubecube-project-project-1"}, &corev1.ResourceQuota{}))
+	err = cli.Get(ctx, types.NamespacedName{Name: "default-quota", Namespace: "ns-1"}, &corev1.ResourceQuota{})
+	assert.True(errors.IsNotFound(err))
+
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", nil))
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "quota", Namespace: "ns-1"}, &corev1.ResourceQuota{}))
+}
+
+func TestApplyTemplateRoleRefChanged(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	cli := newProjectClient()
+	template := newTemplate()
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", template))
+
+	template.Spec.RoleBindings[0].RoleRef.Name = "edit"
+	assert.Nil(ApplyTemplate(ctx, cli, "project-1", template))
+
+	rb := &rbacv1.RoleBinding{}
+	assert.Nil(cli.Get(ctx, types.NamespacedName{Name: "viewers", Namespace: "kubecube-project-project-1"}, rb))
+	assert.Equal("edit", rb.RoleRef.Name)
+	assert.Equal("default", rb.Labels[constants.ProjectTemplateLabel])
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// namespaceEventDelay is the delay of reconciles triggered by namespaces,
+// events of namespaces in the window are coalesced into one reconcile
+const namespaceEventDelay = 5 * time.Second
+
+// NamespaceEventHandler enqueues the requests mapped from namespace by fn.
+// Namespaces change frequently and the tenant or project they belong to only
+// cares about labels and phase, so updates of other fields are ignored and
+// requests are delayed to coalesce bursts of events.
+func NamespaceEventHandler(fn handler.MapFunc) handler.EventHandler {
+	enqueue := func(obj client.Object, q workqueue.RateLimitingInterface) {
+		for _, req := range fn(obj) {
+			q.AddAfter(req, namespaceEventDelay)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			if !namespaceChanged(e.ObjectOld, e.ObjectNew) {
+				return
+			}
+			// namespace may be moved from one to another
+			enqueue(e.ObjectOld, q)
+			enqueue(e.ObjectNew, q)
+		},
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(e.Object, q)
+		},
+	}
+}
+
+// namespaceChanged tells if the fields of namespace that summary relies on changed
+func namespaceChanged(oldObj, newObj client.Object) bool {
+	if !equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels()) {
+		return true
+	}
+	if !equality.Semantic.DeepEqual(oldObj.GetDeletionTimestamp(), newObj.GetDeletionTimestamp()) {
+		return true
+	}
+
+	oldNs, ok1 := oldObj.(*corev1.Namespace)
+	newNs, ok2 := newObj.(*corev1.Namespace)
+	if !ok1 || !ok2 {
+		return true
+	}
+	return oldNs.Status.Phase != newNs.Status.Phase
+}
+
+// ManagedPredicate filters the resources in namespaces of users down to those
+// created by project template or network isolation, so that the controllers
+// are not triggered by resources of users. An update passes if either the old
+// or the new object is managed, in case the label is removed by user.
+func ManagedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return isManaged(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isManaged(e.ObjectOld) || isManaged(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return isManaged(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return isManaged(e.Object)
+		},
+	}
+}
+
+// isManaged tells if the object is created by project template or network isolation
+func isManaged(obj client.Object) bool {
+	labels := obj.GetLabels()
+	if _, ok := labels[constants.ProjectTemplateLabel]; ok {
+		return true
+	}
+	_, ok := labels[constants.NetworkIsolationLabel]
+	return ok
+}
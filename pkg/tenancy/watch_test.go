@@ -0,0 +1,108 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenancy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+// delayRecorder records the items added with delay
+type delayRecorder struct {
+	workqueue.RateLimitingInterface
+	added []interface{}
+}
+
+func (q *delayRecorder) AddAfter(item interface{}, duration time.Duration) {
+	q.added = append(q.added, item)
+}
+
+func TestNamespaceEventHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NamespaceEventHandler(func(obj client.Object) []reconcile.Request {
+		project, ok := obj.GetLabels()[constants.HncProjectLabel]
+		if !ok {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project}}}
+	})
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "ns-1", Labels: map[string]string{constants.HncProjectLabel: "project-1"}},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+	q := &delayRecorder{}
+	h.Create(event.CreateEvent{Object: ns}, q)
+	assert.Len(q.added, 1)
+
+	// changes not relied on are ignored
+	annotated := ns.DeepCopy()
+	annotated.Annotations = map[string]string{"foo": "bar"}
+	annotated.ResourceVersion = "2"
+	q = &delayRecorder{}
+	h.Update(event.UpdateEvent{ObjectOld: ns, ObjectNew: annotated}, q)
+	assert.Len(q.added, 0)
+
+	terminating := ns.DeepCopy()
+	terminating.Status.Phase = corev1.NamespaceTerminating
+	q = &delayRecorder{}
+	h.Update(event.UpdateEvent{ObjectOld: ns, ObjectNew: terminating}, q)
+	assert.Len(q.added, 2)
+
+	// both projects are reconciled when namespace moved
+	moved := ns.DeepCopy()
+	moved.Labels[constants.HncProjectLabel] = "project-2"
+	q = &delayRecorder{}
+	h.Update(event.UpdateEvent{ObjectOld: ns, ObjectNew: moved}, q)
+	assert.Equal([]interface{}{
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "project-1"}},
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "project-2"}},
+	}, q.added)
+}
+
+func TestManagedPredicate(t *testing.T) {
+	assert := assert.New(t)
+
+	p := ManagedPredicate()
+	user := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "cm-1", Namespace: "ns-1"}}
+	templated := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "cm-2", Namespace: "ns-1",
+		Labels: map[string]string{constants.ProjectTemplateLabel: "template-1", constants.ProjectLabel: "project-1"}}}
+	isolation := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "np-1", Namespace: "ns-1",
+		Labels: map[string]string{constants.NetworkIsolationLabel: "true"}}}
+
+	assert.False(p.Create(event.CreateEvent{Object: user}))
+	assert.True(p.Create(event.CreateEvent{Object: templated}))
+	assert.True(p.Create(event.CreateEvent{Object: isolation}))
+	assert.False(p.Delete(event.DeleteEvent{Object: user}))
+	assert.True(p.Delete(event.DeleteEvent{Object: templated}))
+
+	// label removed by user still triggers
+	assert.True(p.Update(event.UpdateEvent{ObjectOld: templated, ObjectNew: user}))
+	assert.False(p.Update(event.UpdateEvent{ObjectOld: user, ObjectNew: user}))
+}
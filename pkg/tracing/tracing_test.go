@@ -0,0 +1,62 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	shutdown, err := Init("test", &Config{})
+	assert.Nil(err)
+	defer func() { _ = shutdown(context.Background()) }()
+
+	tp := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	// span of cube proxy
+	ctx, parent := Start(context.Background(), "cube.proxy")
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.NotEmpty(header.Get("traceparent"))
+
+	// span of warden auth proxy joins the same trace
+	_, child := Start(Extract(context.Background(), header), "warden.authproxy")
+	defer End(child, nil)
+	defer End(parent, nil)
+
+	assert.Equal(parent.SpanContext().TraceID(), child.SpanContext().TraceID())
+	assert.NotEqual(parent.SpanContext().SpanID(), child.SpanContext().SpanID())
+	assert.True(trace.SpanContextFromContext(Extract(context.Background(), header)).IsRemote())
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Empty((&Config{SampleRatio: 0.5}).Validate())
+	assert.NotEmpty((&Config{SampleRatio: 2}).Validate())
+}
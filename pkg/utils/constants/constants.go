@@ -121,6 +121,27 @@ const (
 
 	// ForceDeleteAnnotation used to force deletion of some resources that are not allowed to be deleted
 	ForceDeleteAnnotation = "kubecube.io/force-delete"
+
+	// PivotResourceVersionAnnotation records the resource version in pivot cluster of resource synced by warden
+	PivotResourceVersionAnnotation = "pivotResourceVersion"
+
+	// TenantStateLabel records the lifecycle state of tenant applied to namespaces of tenant
+	TenantStateLabel = "kubecube.io/tenant-state"
+
+	// SuspendedReplicasAnnotation records the replicas of workload before tenant suspended
+	SuspendedReplicasAnnotation = "kubecube.io/suspended-replicas"
+
+	// SuspendedAnnotation marks the cronjob suspended by tenant suspension
+	SuspendedAnnotation = "kubecube.io/suspended"
+
+	// SuspendedAutoscalerAnnotation records the autoscaler of workload removed when tenant suspended
+	SuspendedAutoscalerAnnotation = "kubecube.io/suspended-autoscaler"
+
+	// ProjectTemplateLabel indicates the resource is created by the project template
+	ProjectTemplateLabel = "kubecube.io/project-template"
+
+	// NetworkIsolationLabel indicates the network policy is generated by tenant network isolation
+	NetworkIsolationLabel = "kubecube.io/network-isolation"
 )
 
 const (
@@ -129,6 +150,21 @@ const (
 
 	// CubeCnAnnotation is the annotation of cluster contains cluster cn name
 	CubeCnAnnotation = "cluster.kubecube.io/cn-name"
+
+	// CubeClusterRole is the cluster role of KubeCube in member cluster
+	CubeClusterRole = "kubecube-role"
+
+	// CubeClusterRoleBinding binds CubeClusterRole to warden in member cluster
+	CubeClusterRoleBinding = "kubecube-rolebinding"
+
+	// WardenWebhook is the validating webhook configuration of warden
+	WardenWebhook = "warden-validating-webhook-configuration"
+
+	// ClusterKubeConfigSecretPrefix is the name prefix of secret which stores kubeconfig of cluster
+	ClusterKubeConfigSecretPrefix = "cluster-kubeconfig-"
+
+	// ClusterKubeConfigSecretKey is the data key of kubeconfig in secret
+	ClusterKubeConfigSecretKey = "kubeconfig"
 )
 
 // hnc related const
@@ -47,6 +47,14 @@ func ClusterNotFoundError(clusterName string) *ErrorInfo {
 	return New(clusterNotFound, clusterName)
 }
 
+func ClusterInMaintenanceError(clusterName string) *ErrorInfo {
+	return New(clusterMaintenance, clusterName)
+}
+
+func FeatureDisabledError(feature, clusterName string) *ErrorInfo {
+	return New(featureDisabled, feature, clusterName)
+}
+
 func DealError(err error) *ErrorInfo {
 	return New(dealErrorType, err.Error())
 }
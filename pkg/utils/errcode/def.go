@@ -50,6 +50,8 @@ var (
 	getResourceError    = &ErrorInfo{http.StatusNotFound, "Get resource %s failed."}
 	invalidFileType     = &ErrorInfo{http.StatusBadRequest, "File type invalid."}
 	dealErrorType       = &ErrorInfo{http.StatusBadRequest, "deal fail, %v."}
+	clusterMaintenance  = &ErrorInfo{http.StatusServiceUnavailable, "cluster %s is under maintenance, writes are rejected."}
+	featureDisabled     = &ErrorInfo{http.StatusForbidden, "feature %s is disabled in cluster %s."}
 
 	// auth
 	authenticateError = &ErrorInfo{http.StatusUnauthorized, "Authenticate failed."}
@@ -23,6 +23,8 @@ var (
 	MissNameInObj      = New(&ErrorInfo{http.StatusBadRequest, "miss namespace in .metadata.name."})
 	deployYamlFail     = New(&ErrorInfo{http.StatusBadRequest, "deploy by yaml fail, %v"})
 	createMappingFail  = New(&ErrorInfo{http.StatusBadRequest, "create mapping fail, %v"})
+	MissTargetClusters = New(&ErrorInfo{http.StatusBadRequest, "miss target clusters or cluster selector."})
+	EmptyYamlBundle    = New(&ErrorInfo{http.StatusBadRequest, "no object found in yaml."})
 )
 
 func CreateMappingError(err string) *ErrorInfo {
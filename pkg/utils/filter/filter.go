@@ -19,6 +19,7 @@ package filter
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io/ioutil"
 
@@ -30,6 +31,7 @@ import (
 
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/conversion"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 )
 
 const (
@@ -67,10 +69,18 @@ type ConverterContext struct {
 }
 
 // ModifyResponse modify the response
-func (f *Filter) ModifyResponse(r *http.Response, filterCondition *Condition) error {
+func (f *Filter) ModifyResponse(r *http.Response, filterCondition *Condition) (err error) {
+	ctx := context.Background()
+	if r.Request != nil {
+		ctx = r.Request.Context()
+	}
+	_, span := tracing.Start(ctx, "cube.filter.modifyResponse")
+	defer func() {
+		tracing.End(span, err)
+	}()
+
 	// get info from response
 	var body []byte
-	var err error
 	codeType := r.Header.Get("Content-Encoding")
 	switch codeType {
 	case "gzip":
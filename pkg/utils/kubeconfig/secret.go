@@ -0,0 +1,128 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+// SecretRefForCluster returns the reference of secret that stores kubeconfig of cluster
+func SecretRefForCluster(cluster string) *corev1.SecretReference {
+	return &corev1.SecretReference{
+		Name:      constants.ClusterKubeConfigSecretPrefix + cluster,
+		Namespace: env.CubeNamespace(),
+	}
+}
+
+// MakeSecretForCluster makes secret which stores kubeconfig of cluster
+func MakeSecretForCluster(cluster string, kubeConfig []byte) *corev1.Secret {
+	ref := SecretRefForCluster(cluster)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ref.Name,
+			Namespace: ref.Namespace,
+			Labels:    map[string]string{constants.ClusterLabel: cluster},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{constants.ClusterKubeConfigSecretKey: kubeConfig},
+	}
+}
+
+// LoadClusterKubeConfig loads raw kubeconfig of cluster from referenced secret.
+// The deprecated inline kubeconfig is returned directly if it not migrated yet.
+func LoadClusterKubeConfig(ctx context.Context, cli client.Reader, cluster *clusterv1.Cluster) ([]byte, error) {
+	if len(cluster.Spec.KubeConfig) > 0 {
+		return cluster.Spec.KubeConfig, nil
+	}
+
+	ref := cluster.Spec.KubeConfigSecretRef
+	if ref == nil {
+		return nil, fmt.Errorf("kubeconfig of cluster %v is empty", cluster.Name)
+	}
+
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("get kubeconfig secret %v/%v of cluster %v failed: %v", ref.Namespace, ref.Name, cluster.Name, err)
+	}
+
+	kubeConfig, ok := secret.Data[constants.ClusterKubeConfigSecretKey]
+	if !ok || len(kubeConfig) == 0 {
+		return nil, fmt.Errorf("key %v not found in kubeconfig secret %v/%v of cluster %v", constants.ClusterKubeConfigSecretKey, ref.Namespace, ref.Name, cluster.Name)
+	}
+
+	return kubeConfig, nil
+}
+
+// ResolveCluster returns a copy of cluster with kubeconfig loaded from secret, the
+// copy is used in memory only and must not be written back to avoid leaking kubeconfig.
+func ResolveCluster(ctx context.Context, cli client.Reader, cluster *clusterv1.Cluster) (*clusterv1.Cluster, error) {
+	kubeConfig, err := LoadClusterKubeConfig(ctx, cli, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := cluster.DeepCopy()
+	resolved.Spec.KubeConfig = kubeConfig
+
+	return resolved, nil
+}
+
+// EnsureSecretForCluster creates or updates the secret which stores kubeconfig of cluster
+func EnsureSecretForCluster(ctx context.Context, cli client.Client, cluster string, kubeConfig []byte) (*corev1.SecretReference, error) {
+	ref := SecretRefForCluster(cluster)
+
+	err := cli.Create(ctx, MakeSecretForCluster(cluster, kubeConfig))
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		err = UpdateKubeConfigSecret(ctx, cli, ref, kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ref, nil
+}
+
+// UpdateKubeConfigSecret updates the kubeconfig stored in referenced secret
+func UpdateKubeConfigSecret(ctx context.Context, cli client.Client, ref *corev1.SecretReference, kubeConfig []byte) error {
+	secret := &corev1.Secret{}
+	err := cli.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, secret)
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[constants.ClusterKubeConfigSecretKey] = kubeConfig
+
+	return cli.Update(ctx, secret)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+func TestLoadClusterKubeConfig(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	cli := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}
+
+	// nothing to load
+	_, err := LoadClusterKubeConfig(ctx, cli, cluster)
+	assert.NotNil(err)
+
+	// inline kubeconfig not migrated yet
+	cluster.Spec.KubeConfig = []byte("inline")
+	kubeConfig, err := LoadClusterKubeConfig(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Equal("inline", string(kubeConfig))
+
+	// referred secret not found
+	cluster.Spec.KubeConfig = nil
+	cluster.Spec.KubeConfigSecretRef = SecretRefForCluster(cluster.Name)
+	_, err = LoadClusterKubeConfig(ctx, cli, cluster)
+	assert.NotNil(err)
+
+	ref, err := EnsureSecretForCluster(ctx, cli, cluster.Name, []byte("v1"))
+	assert.Nil(err)
+	assert.Equal(*cluster.Spec.KubeConfigSecretRef, *ref)
+
+	resolved, err := ResolveCluster(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Equal("v1", string(resolved.Spec.KubeConfig))
+	assert.Nil(cluster.Spec.KubeConfig)
+
+	// secret exists already
+	_, err = EnsureSecretForCluster(ctx, cli, cluster.Name, []byte("v2"))
+	assert.Nil(err)
+	kubeConfig, err = LoadClusterKubeConfig(ctx, cli, cluster)
+	assert.Nil(err)
+	assert.Equal("v2", string(kubeConfig))
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+)
+
+// IsClusterInMaintenance tells if cluster is under maintenance, cluster
+// not found is treated as not in maintenance.
+func IsClusterInMaintenance(ctx context.Context, cli client.Reader, cluster string) (bool, error) {
+	c := &clusterv1.Cluster{}
+	err := cli.Get(ctx, types.NamespacedName{Name: cluster}, c)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return c.Spec.Maintenance, nil
+}
+
+// IsMutatingMethod tells if the http method would change resources
+func IsMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// MaintenanceWatcher keeps the maintenance flag of cluster in memory, the flag
+// is refreshed by the informer of cluster rather than a get per check
+type MaintenanceWatcher struct {
+	cluster       string
+	inMaintenance int32
+}
+
+// NewMaintenanceWatcher returns a watcher of maintenance of cluster
+func NewMaintenanceWatcher(cluster string) *MaintenanceWatcher {
+	return &MaintenanceWatcher{cluster: cluster}
+}
+
+// Watch registers the watcher into the informer of cluster in cache
+func (w *MaintenanceWatcher) Watch(ctx context.Context, c cache.Cache) error {
+	informer, err := c.GetInformer(ctx, &clusterv1.Cluster{})
+	if err != nil {
+		return err
+	}
+
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: w.set,
+		UpdateFunc: func(_, newObj interface{}) {
+			w.set(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if c, ok := obj.(*clusterv1.Cluster); ok && c.Name == w.cluster {
+				atomic.StoreInt32(&w.inMaintenance, 0)
+			}
+		},
+	})
+
+	return nil
+}
+
+func (w *MaintenanceWatcher) set(obj interface{}) {
+	c, ok := obj.(*clusterv1.Cluster)
+	if !ok || c.Name != w.cluster {
+		return
+	}
+	var v int32
+	if c.Spec.Maintenance {
+		v = 1
+	}
+	atomic.StoreInt32(&w.inMaintenance, v)
+}
+
+// InMaintenance tells if cluster is under maintenance, a nil watcher
+// is never in maintenance
+func (w *MaintenanceWatcher) InMaintenance() bool {
+	if w == nil {
+		return false
+	}
+	return atomic.LoadInt32(&w.inMaintenance) == 1
+}
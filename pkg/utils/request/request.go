@@ -27,19 +27,29 @@ import (
 )
 
 func AddFieldManager(req *http.Request, username string) error {
+	fieldManager, err := FieldManagerFor(username)
+	if err != nil {
+		return err
+	}
+
+	return AddQuery(req, constants.FieldManager, fieldManager)
+}
+
+// FieldManagerFor returns the field manager of user used by server-side apply
+func FieldManagerFor(username string) (string, error) {
 	for _, r := range username {
 		if !unicode.IsPrint(r) {
-			return fmt.Errorf("username not printable")
+			return "", fmt.Errorf("username not printable")
 		}
 	}
 
 	username = "cube-" + username
 
 	if len(username) > 128 {
-		return fmt.Errorf("username should not be longer than 128")
+		return "", fmt.Errorf("username should not be longer than 128")
 	}
 
-	return AddQuery(req, constants.FieldManager, username)
+	return username, nil
 }
 
 func AddQuery(req *http.Request, key, value string) error {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -27,9 +28,9 @@ import (
 	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 )
 
+// UpdateClusterStatus applies updateFn to the latest cluster and updates its status,
+// the status of given cluster will be refreshed after update succeed.
 func UpdateClusterStatus(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster, updateFn func(cluster *clusterv1.Cluster)) error {
-	updateFn(cluster)
-
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		newCluster := &clusterv1.Cluster{}
 		err := cli.Get(ctx, types.NamespacedName{Name: cluster.Name}, newCluster)
@@ -37,16 +38,32 @@ func UpdateClusterStatus(ctx context.Context, cli client.Client, cluster *cluste
 			return err
 		}
 
-		newCluster.Status = cluster.Status
+		updateFn(newCluster)
 
 		err = cli.Status().Update(ctx, newCluster, &client.UpdateOptions{})
 		if err != nil {
 			return err
 		}
+
+		cluster.Status = newCluster.Status
+
 		return nil
 	})
 }
 
+// UpdateClusterConditions sets conditions of cluster and updates the
+// state of cluster derived from conditions
+func UpdateClusterConditions(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster, conditions ...metav1.Condition) error {
+	updateFn := func(cluster *clusterv1.Cluster) {
+		for _, condition := range conditions {
+			condition.ObservedGeneration = cluster.Generation
+			clusterv1.SetClusterCondition(&cluster.Status, condition)
+		}
+	}
+
+	return UpdateClusterStatus(ctx, cli, cluster, updateFn)
+}
+
 func UpdateClusterStatusByState(ctx context.Context, cli client.Client, cluster *clusterv1.Cluster, state clusterv1.ClusterState) error {
 	updateFn := func(cluster *clusterv1.Cluster) {
 		reason := fmt.Sprintf("cluster(%v) is %s", cluster.Name, state)
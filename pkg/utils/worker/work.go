@@ -41,6 +41,8 @@ const (
 type Interface interface {
 	// AddRateLimited adds item to queue.
 	AddRateLimited(item interface{})
+	// AddAfter adds item to queue after the given duration.
+	AddAfter(item interface{}, duration time.Duration)
 	// EnqueueRateLimited generates the key for objects then adds the key as an item to queue.
 	EnqueueRateLimited(obj runtime.Object)
 	Run(workerNumber int, stopChan <-chan struct{})
@@ -100,6 +102,15 @@ func (w *workerImpl) AddRateLimited(item interface{}) {
 	w.queue.AddRateLimited(item)
 }
 
+func (w *workerImpl) AddAfter(item interface{}, duration time.Duration) {
+	if item == nil {
+		clog.Warn("Ignore nil item from queue")
+		return
+	}
+
+	w.queue.AddAfter(item, duration)
+}
+
 func (w *workerImpl) handleError(err error, key interface{}) {
 	if err == nil || errors.HasStatusCause(err, v1.NamespaceTerminatingCause) {
 		w.queue.Forget(key)
@@ -29,6 +29,10 @@ type Config struct {
 	PeriodSecond  int
 	WaitSecond    int
 	RetryCounts   int
+	CubeCACert    string
+
+	// tunnel
+	EnableTunnel bool
 
 	// api server
 	JwtSecret string
@@ -37,6 +41,9 @@ type Config struct {
 	TlsCert   string
 	TlsKey    string
 
+	// metrics
+	MetricsPort int
+
 	// local manager
 	AllowPrivileged   bool
 	LeaderElect       bool
@@ -25,8 +25,13 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/homedir"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -34,6 +39,10 @@ import (
 type Helm struct {
 	K8sConfig    *rest.Config
 	ActionConfig map[string]*action.Configuration
+
+	// useRestConfig means K8sConfig is used as it is rather than
+	// only host, token and ca of it
+	useRestConfig bool
 }
 
 func NewHelm() *Helm {
@@ -49,6 +58,16 @@ func NewHelm() *Helm {
 	}
 }
 
+// NewHelmForConfig makes helm operates the cluster of given config,
+// such as member cluster operated from pivot cluster
+func NewHelmForConfig(config *rest.Config) *Helm {
+	return &Helm{
+		K8sConfig:     config,
+		ActionConfig:  make(map[string]*action.Configuration),
+		useRestConfig: true,
+	}
+}
+
 // get action config
 func (h *Helm) GetActionConfig(namespace string) (*action.Configuration, error) {
 	if c, ok := h.ActionConfig[namespace]; ok {
@@ -56,13 +75,19 @@ func (h *Helm) GetActionConfig(namespace string) (*action.Configuration, error)
 	}
 	// init action config
 	actionConfig := new(action.Configuration)
-	kubeConfig := genericclioptions.NewConfigFlags(false)
-	kubeConfig.APIServer = &h.K8sConfig.Host
-	kubeConfig.BearerToken = &h.K8sConfig.BearerToken
-	kubeConfig.CAFile = &h.K8sConfig.CAFile
-	kubeConfig.Namespace = &namespace
+	var getter genericclioptions.RESTClientGetter
+	if h.useRestConfig {
+		getter = &restConfigGetter{config: h.K8sConfig, namespace: namespace}
+	} else {
+		kubeConfig := genericclioptions.NewConfigFlags(false)
+		kubeConfig.APIServer = &h.K8sConfig.Host
+		kubeConfig.BearerToken = &h.K8sConfig.BearerToken
+		kubeConfig.CAFile = &h.K8sConfig.CAFile
+		kubeConfig.Namespace = &namespace
+		getter = kubeConfig
+	}
 	log := clog.WithName("hotplug-helm")
-	err := actionConfig.Init(kubeConfig, namespace, os.Getenv("HELM_DRIVER"), log.Info)
+	err := actionConfig.Init(getter, namespace, os.Getenv("HELM_DRIVER"), log.Info)
 	if err != nil {
 		clog.Error("can not create helm action config")
 		return nil, err
@@ -176,3 +201,44 @@ func (h *Helm) Uninstall(namespace string, name string) error {
 	clog.Info("uninstall the release success: %v", relaese.Info)
 	return nil
 }
+
+// restConfigGetter implements genericclioptions.RESTClientGetter with rest config
+type restConfigGetter struct {
+	config    *rest.Config
+	namespace string
+}
+
+func (g *restConfigGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *restConfigGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(g.config)
+	if err != nil {
+		return nil, err
+	}
+	return &uncachedDiscovery{DiscoveryInterface: dc}, nil
+}
+
+func (g *restConfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	dc, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return restmapper.NewShortcutExpander(mapper, dc), nil
+}
+
+func (g *restConfigGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: g.namespace}}
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), overrides)
+}
+
+// uncachedDiscovery queries apiserver every time
+type uncachedDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (d *uncachedDiscovery) Fresh() bool { return true }
+
+func (d *uncachedDiscovery) Invalidate() {}
@@ -19,11 +19,24 @@ package hotplug
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	hotplugv1 "github.com/kubecube-io/kubecube/pkg/apis/hotplug/v1"
+	cubeutils "github.com/kubecube-io/kubecube/pkg/utils"
 	"github.com/kubecube-io/kubecube/pkg/warden/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -33,6 +46,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/metrics"
 )
 
 const (
@@ -41,6 +55,9 @@ const (
 	success = "success"
 	enabled = "enabled"
 	//disabled = "disabled"
+
+	// maintenanceRequeueInterval is the interval to retry when cluster under maintenance
+	maintenanceRequeueInterval = 30 * time.Second
 )
 
 var _ reconcile.Reconciler = &HotplugReconciler{}
@@ -51,14 +68,23 @@ type HotplugReconciler struct {
 	Scheme          *runtime.Scheme
 	isMemberCluster bool
 	clusterName     string
+
+	// maintenance tells if cluster is under maintenance
+	maintenance *cubeutils.MaintenanceWatcher
+
+	// PivotClient reads labels of cluster from pivot cluster to
+	// match the cluster selector of hotplugs
+	PivotClient client.Reader
 }
 
-func newReconciler(mgr manager.Manager, isMemberCluster bool, clusterName string) (*HotplugReconciler, error) {
+func newReconciler(mgr manager.Manager, isMemberCluster bool, clusterName string, maintenance *cubeutils.MaintenanceWatcher, pivotClient client.Reader) (*HotplugReconciler, error) {
 	r := &HotplugReconciler{
 		Client:          mgr.GetClient(),
 		Scheme:          mgr.GetScheme(),
 		isMemberCluster: isMemberCluster,
 		clusterName:     clusterName,
+		maintenance:     maintenance,
+		PivotClient:     pivotClient,
 	}
 	return r, nil
 }
@@ -73,10 +99,16 @@ func newReconciler(mgr manager.Manager, isMemberCluster bool, clusterName string
 // 3、install/upgrade component
 func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := clog.WithName("controller").WithValues("hotplug", req.NamespacedName)
+
+	// hotplug changes are paused when cluster under maintenance
+	if h.maintenance.InMaintenance() {
+		log.Info("cluster %v is under maintenance, skip hotplug changes", h.clusterName)
+		return ctrl.Result{RequeueAfter: maintenanceRequeueInterval}, nil
+	}
+
 	// get hotplug info
 	commonConfig := hotplugv1.Hotplug{}
 	clusterConfig := hotplugv1.Hotplug{}
-	hotplugConfig := hotplugv1.Hotplug{}
 	switch req.Name {
 	case common:
 		err := h.Client.Get(ctx, req.NamespacedName, &commonConfig)
@@ -88,15 +120,10 @@ func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{}, err
 		}
 		err = h.Client.Get(ctx, types.NamespacedName{Name: utils.Cluster}, &clusterConfig)
-		if err != nil {
-			if !errors.IsNotFound(err) {
-				log.Error("get cluster hotplug fail, %v", err)
-				return ctrl.Result{}, err
-			}
-			// cluster config is nil
-			hotplugConfig = MergeHotplug(commonConfig, clusterConfig)
-		} else {
-			hotplugConfig = MergeHotplug(commonConfig, clusterConfig)
+		if err != nil && !errors.IsNotFound(err) {
+			// cluster config could be nil
+			log.Error("get cluster hotplug fail, %v", err)
+			return ctrl.Result{}, err
 		}
 	case utils.Cluster:
 		err := h.Client.Get(ctx, req.NamespacedName, &clusterConfig)
@@ -112,11 +139,40 @@ func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			log.Warn("get common hotplug fail, %v", err)
 			return ctrl.Result{}, err
 		}
-		hotplugConfig = MergeHotplug(commonConfig, clusterConfig)
 	default:
-		log.Warn("this hotplug not match this cluster, %s != %s", req.Name, utils.Cluster)
-		return ctrl.Result{}, nil
+		selected, err := h.selectedHotplugs(ctx)
+		if err != nil {
+			log.Error("list hotplugs selecting cluster fail, %v", err)
+			return ctrl.Result{}, err
+		}
+		if _, ok := selected[req.Name]; !ok {
+			log.Warn("this hotplug not match this cluster, %s != %s", req.Name, utils.Cluster)
+			return ctrl.Result{}, nil
+		}
+		err = h.Client.Get(ctx, types.NamespacedName{Name: common}, &commonConfig)
+		if err != nil {
+			log.Warn("get common hotplug fail, %v", err)
+			return ctrl.Result{}, err
+		}
+		err = h.Client.Get(ctx, types.NamespacedName{Name: utils.Cluster}, &clusterConfig)
+		if err != nil && !errors.IsNotFound(err) {
+			log.Error("get cluster hotplug fail, %v", err)
+			return ctrl.Result{}, err
+		}
+	}
+
+	// hotplugs selecting this cluster override common one, and are
+	// overridden by the one named after cluster
+	selected, err := h.selectedHotplugs(ctx)
+	if err != nil {
+		log.Error("list hotplugs selecting cluster fail, %v", err)
+		return ctrl.Result{}, err
+	}
+	hotplugConfig := commonConfig
+	for _, name := range sortedNames(selected) {
+		hotplugConfig = MergeHotplug(hotplugConfig, selected[name])
 	}
+	hotplugConfig = MergeHotplug(hotplugConfig, clusterConfig)
 
 	// helm do
 	results := []*hotplugv1.DeployResult{}
@@ -202,6 +258,7 @@ func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	phase := success
 	for _, r := range results {
+		metrics.ObserveHotplugResult(h.clusterName, r.Name, r.Result == success)
 		if r.Result == fail {
 			phase = fail
 			continue
@@ -214,7 +271,7 @@ func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// update status
 	commonConfig.Status.Phase = phase
 	commonConfig.Status.Results = results
-	err := h.Client.Status().Update(ctx, &commonConfig)
+	err = h.Client.Status().Update(ctx, &commonConfig)
 	if err != nil {
 		log.Error("update common hotplug fail, %v", err)
 		return ctrl.Result{}, err
@@ -228,10 +285,67 @@ func (h *HotplugReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{}, err
 		}
 	}
+	if selectedConfig, ok := selected[req.Name]; ok {
+		selectedConfig.Status.Phase = phase
+		selectedConfig.Status.Results = results
+		err := h.Client.Status().Update(ctx, &selectedConfig)
+		if err != nil {
+			log.Error("update hotplug %v fail, %v", req.Name, err)
+			return ctrl.Result{}, err
+		}
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// selectedHotplugs returns hotplugs whose cluster selector matches labels of
+// this cluster, keyed by name
+func (h *HotplugReconciler) selectedHotplugs(ctx context.Context) (map[string]hotplugv1.Hotplug, error) {
+	hotplugs := hotplugv1.HotplugList{}
+	err := h.Client.List(ctx, &hotplugs)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterLabels labels.Set
+	selected := make(map[string]hotplugv1.Hotplug)
+	for _, hotplug := range hotplugs.Items {
+		if hotplug.Spec.ClusterSelector == nil || hotplug.Name == common || hotplug.Name == utils.Cluster {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(hotplug.Spec.ClusterSelector)
+		if err != nil {
+			clog.Warn("invalid cluster selector of hotplug %v: %v", hotplug.Name, err)
+			continue
+		}
+		if clusterLabels == nil {
+			cluster := clusterv1.Cluster{}
+			err = h.PivotClient.Get(ctx, types.NamespacedName{Name: utils.Cluster}, &cluster)
+			if err != nil {
+				return nil, err
+			}
+			clusterLabels = cluster.Labels
+			if clusterLabels == nil {
+				clusterLabels = labels.Set{}
+			}
+		}
+		if selector.Matches(clusterLabels) {
+			selected[hotplug.Name] = hotplug
+		}
+	}
+
+	return selected, nil
+}
+
+func sortedNames(hotplugs map[string]hotplugv1.Hotplug) []string {
+	names := make([]string, 0, len(hotplugs))
+	for name := range hotplugs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func addSuccessResult(result *hotplugv1.DeployResult, message string) {
 	clog.Info("component:%s, message:%s", result.Name, message)
 	result.Result = success
@@ -245,12 +359,32 @@ func addFailResult(result *hotplugv1.DeployResult, message string) {
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func SetupWithManager(mgr ctrl.Manager, isMemberCluster bool, clusterName string) error {
-	r, err := newReconciler(mgr, isMemberCluster, clusterName)
+// Labels of cluster are watched in pivot cache to rerun hotplug when
+// the hotplugs selecting cluster changed.
+func SetupWithManager(mgr ctrl.Manager, isMemberCluster bool, clusterName string, maintenance *cubeutils.MaintenanceWatcher, pivotCache cache.Cache) error {
+	r, err := newReconciler(mgr, isMemberCluster, clusterName, maintenance, pivotCache)
 	if err != nil {
 		return err
 	}
+
+	clusterToCommon := func(obj client.Object) []reconcile.Request {
+		if obj.GetName() != clusterName {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: common}}}
+	}
+
+	labelsChanged := predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool { return true },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !reflect.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+		},
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&hotplugv1.Hotplug{}).
+		Watches(source.NewKindWithCache(&clusterv1.Cluster{}, pivotCache), handler.EnqueueRequestsFromMapFunc(clusterToCommon), builder.WithPredicates(labelsChanged)).
 		Complete(r)
 }
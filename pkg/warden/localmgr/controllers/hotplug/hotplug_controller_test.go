@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	hotplugv1 "github.com/kubecube-io/kubecube/pkg/apis/hotplug/v1"
 	"github.com/kubecube-io/kubecube/pkg/warden/localmgr/controllers/hotplug"
 	"github.com/kubecube-io/kubecube/pkg/warden/utils"
@@ -73,6 +74,48 @@ var _ = Describe("HotplugController", func() {
 		_, err = hotplugCtrl.Reconcile(ctx, req)
 		Expect(err).NotTo(HaveOccurred())
 	})
+
+	It("test hotplug selecting cluster by labels", func() {
+		scheme := runtime.NewScheme()
+		_ = hotplugv1.AddToScheme(scheme)
+		_ = clusterv1.AddToScheme(scheme)
+		_ = corev1.AddToScheme(scheme)
+
+		cluster := clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-prod", Labels: map[string]string{"env": "prod"}}}
+		commonHotplug := hotplugTemplate("common")
+		prodHotplug := hotplugTemplate("prod")
+		prodHotplug.Spec.Component[0].Status = "disabled"
+		prodHotplug.Spec.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+		devHotplug := hotplugTemplate("dev")
+		devHotplug.Spec.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(&cluster, &commonHotplug, &prodHotplug, &devHotplug).Build()
+
+		hotplugCtrl := hotplug.HotplugReconciler{}
+		hotplugCtrl.Client = fakeClient
+		hotplugCtrl.Scheme = scheme
+		hotplugCtrl.PivotClient = fakeClient
+
+		utils.Cluster = "member-prod"
+
+		ctx := context.Background()
+		for _, name := range []string{"common", "prod", "dev"} {
+			_, err := hotplugCtrl.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: name}})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		// hotplug selecting cluster overrides common one
+		for _, name := range []string{"common", "prod"} {
+			h := hotplugv1.Hotplug{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Name: name}, &h)).To(Succeed())
+			Expect(h.Status.Results).To(HaveLen(1))
+			Expect(h.Status.Results[0].Status).To(Equal("disabled"))
+		}
+
+		// hotplug not selecting cluster is skipped
+		h := hotplugv1.Hotplug{}
+		Expect(fakeClient.Get(ctx, types.NamespacedName{Name: "dev"}, &h)).To(Succeed())
+		Expect(h.Status.Results).To(BeEmpty())
+	})
 })
 
 // tenant template
@@ -21,19 +21,30 @@ import (
 	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
 
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/warden/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
 )
@@ -44,18 +55,38 @@ const (
 	// Default timeouts to be used in TimeoutContext
 	waitInterval = 2 * time.Second
 	waitTimeout  = 120 * time.Second
+
+	// statusResyncPeriod is the period to refresh status of project
+	statusResyncPeriod = 5 * time.Minute
 )
 
 // ProjectReconciler reconciles a Project object
 type ProjectReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// IsMemberCluster is true if warden runs in member cluster
+	IsMemberCluster bool
+	// ClusterName is the name of cluster warden runs in
+	ClusterName string
+	// PivotClient is the client of pivot cluster where status reported to
+	PivotClient client.Client
 }
 
-func newReconciler(mgr manager.Manager) (*ProjectReconciler, error) {
+func newReconciler(mgr manager.Manager, isMemberCluster bool, clusterName string, pivotClient client.Client) (*ProjectReconciler, error) {
+	// resources in namespaces of users are only watched by metadata of
+	// those managed, read them directly to avoid caching all of them
+	cli, err := utils.NewUncachedClient(mgr, &corev1.ConfigMap{}, &corev1.LimitRange{}, &corev1.ResourceQuota{},
+		&networkingv1.NetworkPolicy{}, &rbacv1.RoleBinding{})
+	if err != nil {
+		return nil, err
+	}
 	r := &ProjectReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          cli,
+		Scheme:          mgr.GetScheme(),
+		IsMemberCluster: isMemberCluster,
+		ClusterName:     clusterName,
+		PivotClient:     pivotClient,
 	}
 	return r, nil
 }
@@ -63,6 +94,16 @@ func newReconciler(mgr manager.Manager) (*ProjectReconciler, error) {
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projects,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projects/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projects/finalizers,verbs=update
+//+kubebuilder:rbac:groups=quota.kubecube.io,resources=cuberesourcequotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.kubecube.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projecttemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps;limitranges;resourcequotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=hnc.x-k8s.io,resources=subnamespaceanchors;hierarchyconfigurations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -130,6 +171,13 @@ func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, fmt.Errorf("the tenant %s do not content .spec.namespace", tenantName)
 	}
 
+	// move namespaces of project under tenant if project moved from other tenant
+	err = tenancy.ReparentProject(ctx, r.Client, project.Name, tenant.Name, tenant.Spec.Namespace)
+	if err != nil {
+		log.Warn("reparent project namespaces fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
 	subnamesapceAchor := hnc.SubnamespaceAnchor{}
 	// Weather subnamespaceAchor exist
 	err = r.Client.Get(ctx, types.NamespacedName{Namespace: tenant.Spec.Namespace, Name: project.Spec.Namespace}, &subnamesapceAchor)
@@ -170,7 +218,104 @@ func (r *ProjectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// bootstrap namespaces of project by template
+	err = r.applyTemplate(ctx, &project)
+	if tenancy.IsTemplateConflict(err) {
+		// resources of users are not overwritten, retry makes no sense
+		log.Warn("apply project template partially, %v", err)
+	} else if err != nil {
+		log.Warn("apply project template fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// allow network exceptions of project if tenant isolated
+	err = tenancy.ApplyProjectExceptions(ctx, r.Client, project.Name, tenant.Spec.NetworkIsolation, project.Spec.NetworkExceptions)
+	if err != nil {
+		log.Warn("apply project network exceptions fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// report status of project in current cluster to pivot cluster
+	err = r.updateStatus(ctx, &project, &tenant)
+	if err != nil {
+		log.Warn("update project status fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: statusResyncPeriod}, nil
+}
+
+// applyTemplate applies the template referenced by project to its namespaces,
+// resources of template are removed if project references no template
+func (r *ProjectReconciler) applyTemplate(ctx context.Context, project *tenantv1.Project) error {
+	if len(project.Spec.Template) == 0 {
+		return tenancy.ApplyTemplate(ctx, r.Client, project.Name, nil)
+	}
+
+	template := &tenantv1.ProjectTemplate{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: project.Spec.Template}, template)
+	if err != nil {
+		return fmt.Errorf("get project template %v failed: %v", project.Spec.Template, err)
+	}
+
+	return tenancy.ApplyTemplate(ctx, r.Client, project.Name, template)
+}
+
+// updateStatus reports the summary of project in current cluster into the status
+// of project in pivot cluster. Warden of pivot cluster rolls up the summaries of
+// all clusters as well.
+func (r *ProjectReconciler) updateStatus(ctx context.Context, project *tenantv1.Project, tenant *tenantv1.Tenant) error {
+	summary, err := tenancy.ClusterSummaryOf(ctx, r.Client, r.ClusterName, constants.ProjectNsPrefix+project.Name, constants.HncProjectLabel, project.Name)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &tenantv1.Project{}
+		err := r.PivotClient.Get(ctx, types.NamespacedName{Name: project.Name}, current)
+		if err != nil {
+			return err
+		}
+
+		status := current.Status.DeepCopy()
+		tenancy.SetClusterSummary(&status.CommonStatus, summary)
+		if !r.IsMemberCluster {
+			err = r.rollupStatus(ctx, current, status, tenant)
+			if err != nil {
+				return err
+			}
+		}
+
+		if equality.Semantic.DeepEqual(status, &current.Status) {
+			return nil
+		}
+		current.Status = *status
+		return r.PivotClient.Status().Update(ctx, current)
+	})
+}
+
+// rollupStatus computes the status of project from pivot cluster
+func (r *ProjectReconciler) rollupStatus(ctx context.Context, project *tenantv1.Project, status *tenantv1.ProjectStatus, tenant *tenantv1.Tenant) error {
+	clusters, err := tenancy.ClusterNames(ctx, r.PivotClient)
+	if err != nil {
+		return err
+	}
+	tenancy.PruneClusters(&status.CommonStatus, clusters)
+
+	status.MemberCount, err = tenancy.CountMembers(ctx, r.PivotClient, constants.ProjectNsPrefix+project.Name)
+	if err != nil {
+		return err
+	}
+
+	status.Quota, err = tenancy.QuotaOf(ctx, r.PivotClient, quotav1.ProjectObj, project.Name)
+	if err != nil {
+		return err
+	}
+
+	tenancy.Rollup(&status.CommonStatus, clusters, project.Generation)
+	tenancy.RollupParent(&status.CommonStatus, tenant.Spec.Namespace, project.Generation)
+
+	return nil
 }
 
 func (r *ProjectReconciler) deleteProject(projectName string) (ctrl.Result, error) {
@@ -218,12 +363,94 @@ func (r *ProjectReconciler) deleteSubNSOfProject(projectName string) error {
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func SetupWithManager(mgr ctrl.Manager) error {
-	r, err := newReconciler(mgr)
+func SetupWithManager(mgr ctrl.Manager, isMemberCluster bool, clusterName string, pivotClient client.Client) error {
+	r, err := newReconciler(mgr, isMemberCluster, clusterName, pivotClient)
 	if err != nil {
 		return err
 	}
+	managed := builder.WithPredicates(tenancy.ManagedPredicate())
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tenantv1.Project{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, tenancy.NamespaceEventHandler(namespaceToProject)).
+		Watches(&source.Kind{Type: &quotav1.CubeResourceQuota{}}, handler.EnqueueRequestsFromMapFunc(quotaToProject)).
+		Watches(&source.Kind{Type: &tenantv1.ProjectTemplate{}}, handler.EnqueueRequestsFromMapFunc(r.templateToProjects)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(templatedToProject), managed, builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.LimitRange{}}, handler.EnqueueRequestsFromMapFunc(templatedToProject), managed, builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &corev1.ResourceQuota{}}, handler.EnqueueRequestsFromMapFunc(templatedToProject), managed, builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, handler.EnqueueRequestsFromMapFunc(networkPolicyToProject), managed, builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &tenantv1.Tenant{}}, handler.EnqueueRequestsFromMapFunc(r.tenantToProjects)).
+		Watches(&source.Kind{Type: &rbacv1.RoleBinding{}}, handler.EnqueueRequestsFromMapFunc(templatedToProject), managed, builder.OnlyMetadata).
 		Complete(r)
 }
+
+// templateToProjects maps project template to the projects reference it
+func (r *ProjectReconciler) templateToProjects(obj client.Object) []reconcile.Request {
+	projectList := &tenantv1.ProjectList{}
+	err := r.Client.List(context.Background(), projectList)
+	if err != nil {
+		clog.Warn("list projects failed: %v", err)
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, project := range projectList.Items {
+		if project.Spec.Template == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: project.Name}})
+		}
+	}
+	return requests
+}
+
+// tenantToProjects maps tenant to its projects
+func (r *ProjectReconciler) tenantToProjects(obj client.Object) []reconcile.Request {
+	projectList := &tenantv1.ProjectList{}
+	err := r.Client.List(context.Background(), projectList, client.MatchingLabels{constants.TenantLabel: obj.GetName()})
+	if err != nil {
+		clog.Warn("list projects of tenant %v failed: %v", obj.GetName(), err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(projectList.Items))
+	for _, project := range projectList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: project.Name}})
+	}
+	return requests
+}
+
+// networkPolicyToProject maps network policy created by template or
+// isolation exceptions to its project
+func networkPolicyToProject(obj client.Object) []reconcile.Request {
+	if _, project := tenancy.IsolationPolicyOwner(obj); len(project) > 0 {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project}}}
+	}
+	return templatedToProject(obj)
+}
+
+// templatedToProject maps resource created by template to its project, so
+// that the drift of resource is corrected
+func templatedToProject(obj client.Object) []reconcile.Request {
+	if _, ok := obj.GetLabels()[constants.ProjectTemplateLabel]; !ok {
+		return nil
+	}
+	project, ok := obj.GetLabels()[constants.ProjectLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project}}}
+}
+
+// namespaceToProject maps namespace to the project it belongs to
+func namespaceToProject(obj client.Object) []reconcile.Request {
+	project, ok := obj.GetLabels()[constants.HncProjectLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: project}}}
+}
+
+// quotaToProject maps CubeResourceQuota to the project it targets to
+func quotaToProject(obj client.Object) []reconcile.Request {
+	quota, ok := obj.(*quotav1.CubeResourceQuota)
+	if !ok || quota.Spec.Target.Kind != quotav1.ProjectObj {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: quota.Spec.Target.Name}}}
+}
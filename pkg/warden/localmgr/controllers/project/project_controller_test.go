@@ -22,6 +22,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,6 +32,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 	tenantctrl "github.com/kubecube-io/kubecube/pkg/warden/localmgr/controllers/tenant"
@@ -64,16 +69,22 @@ func TestReconcile(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = tenantv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
 	_ = hnc.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = quotav1.AddToScheme(scheme)
 
 	// crete
 	tenant1 := tenantTemplate("test-tenant1")
 	project1 := projectTemplate("test-tenant1", "test-project1")
-	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(&tenant1, &project1).Build()
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(&tenant1, &project1, &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "pivot-cluster"}}).Build()
 
 	tenantReconciler := tenantctrl.TenantReconciler{}
 	tenantReconciler.Client = fakeClient
 	tenantReconciler.Scheme = scheme
+	tenantReconciler.ClusterName = "pivot-cluster"
+	tenantReconciler.PivotClient = fakeClient
 
 	req := ctrl.Request{}
 	req.Name = "test-tenant1"
@@ -91,6 +102,8 @@ func TestReconcile(t *testing.T) {
 	projectReconcile := ProjectReconciler{}
 	projectReconcile.Client = fakeClient
 	projectReconcile.Scheme = scheme
+	projectReconcile.ClusterName = "pivot-cluster"
+	projectReconcile.PivotClient = fakeClient
 
 	req.Name = "test-project1"
 	req.NamespacedName = types.NamespacedName{Name: req.Name}
@@ -100,4 +113,12 @@ func TestReconcile(t *testing.T) {
 	subnamespace := hnc.SubnamespaceAnchor{}
 	err = fakeClient.Get(ctx, types.NamespacedName{Namespace: "kubecube-tenant-test-tenant1", Name: "kubecube-project-test-project1"}, &subnamespace)
 	assert.Nil(err)
+
+	project := tenantv1.Project{}
+	err = fakeClient.Get(ctx, types.NamespacedName{Name: "test-project1"}, &project)
+	assert.Nil(err)
+	assert.Equal([]tenantv1.ClusterSummary{{Name: "pivot-cluster"}}, project.Status.Clusters)
+	assert.True(meta.IsStatusConditionFalse(project.Status.Conditions, tenantv1.QuotaBound))
+	// namespace of project is not created by HNC yet
+	assert.True(meta.IsStatusConditionFalse(project.Status.Conditions, tenantv1.Reparented))
 }
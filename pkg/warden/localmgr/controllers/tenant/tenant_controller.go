@@ -19,23 +19,32 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	v1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/warden/utils"
 )
 
 var _ reconcile.Reconciler = &TenantReconciler{}
@@ -44,18 +53,37 @@ const (
 	// Default timeouts to be used in TimeoutContext
 	waitInterval = 2 * time.Second
 	waitTimeout  = 120 * time.Second
+
+	// statusResyncPeriod is the period to refresh status of tenant
+	statusResyncPeriod = 5 * time.Minute
 )
 
 // TenantReconciler reconciles a Tenant object
 type TenantReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// IsMemberCluster is true if warden runs in member cluster
+	IsMemberCluster bool
+	// ClusterName is the name of cluster warden runs in
+	ClusterName string
+	// PivotClient is the client of pivot cluster where status reported to
+	PivotClient client.Client
 }
 
-func newReconciler(mgr manager.Manager) (*TenantReconciler, error) {
+func newReconciler(mgr manager.Manager, isMemberCluster bool, clusterName string, pivotClient client.Client) (*TenantReconciler, error) {
+	// network policies are only watched by metadata of those managed,
+	// read them directly to avoid caching all of them
+	cli, err := utils.NewUncachedClient(mgr, &networkingv1.NetworkPolicy{})
+	if err != nil {
+		return nil, err
+	}
 	r := &TenantReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          cli,
+		Scheme:          mgr.GetScheme(),
+		IsMemberCluster: isMemberCluster,
+		ClusterName:     clusterName,
+		PivotClient:     pivotClient,
 	}
 	return r, nil
 }
@@ -63,6 +91,16 @@ func newReconciler(mgr manager.Manager) (*TenantReconciler, error) {
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=tenants,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=tenants/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=tenant.kubecube.io,resources=tenants/finalizers,verbs=update
+//+kubebuilder:rbac:groups=tenant.kubecube.io,resources=projects,verbs=get;list;watch
+//+kubebuilder:rbac:groups=quota.kubecube.io,resources=cuberesourcequotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cluster.kubecube.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps;secrets;serviceaccounts;services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -140,7 +178,92 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// move tenant to the lifecycle state specified
+	err = tenancy.ApplyState(ctx, r.Client, tenant.Name, tenancy.DesiredState(&tenant))
+	if err != nil {
+		log.Error("apply tenant state fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// isolate network of tenant if enabled
+	err = tenancy.ApplyTenantIsolation(ctx, r.Client, tenant.Name, tenant.Spec.NetworkIsolation)
+	if err != nil {
+		log.Error("apply tenant network isolation fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	// report status of tenant in current cluster to pivot cluster
+	err = r.updateStatus(ctx, &tenant)
+	if err != nil {
+		log.Warn("update tenant status fail, %v", err)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: statusResyncPeriod}, nil
+}
+
+// updateStatus reports the summary of tenant in current cluster into the status
+// of tenant in pivot cluster. Warden of pivot cluster rolls up the summaries of
+// all clusters as well.
+func (r *TenantReconciler) updateStatus(ctx context.Context, tenant *tenantv1.Tenant) error {
+	summary, err := tenancy.ClusterSummaryOf(ctx, r.Client, r.ClusterName, constants.TenantNsPrefix+tenant.Name, constants.HncTenantLabel, tenant.Name)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &tenantv1.Tenant{}
+		err := r.PivotClient.Get(ctx, types.NamespacedName{Name: tenant.Name}, current)
+		if err != nil {
+			return err
+		}
+
+		status := current.Status.DeepCopy()
+		tenancy.SetClusterSummary(&status.CommonStatus, summary)
+		if !r.IsMemberCluster {
+			err = r.rollupStatus(ctx, current, status)
+			if err != nil {
+				return err
+			}
+		}
+
+		if equality.Semantic.DeepEqual(status, &current.Status) {
+			return nil
+		}
+		current.Status = *status
+		return r.PivotClient.Status().Update(ctx, current)
+	})
+}
+
+// rollupStatus computes the status of tenant from pivot cluster
+func (r *TenantReconciler) rollupStatus(ctx context.Context, tenant *tenantv1.Tenant, status *tenantv1.TenantStatus) error {
+	clusters, err := tenancy.ClusterNames(ctx, r.PivotClient)
+	if err != nil {
+		return err
+	}
+	tenancy.PruneClusters(&status.CommonStatus, clusters)
+
+	status.MemberCount, err = tenancy.CountMembers(ctx, r.PivotClient, constants.TenantNsPrefix+tenant.Name)
+	if err != nil {
+		return err
+	}
+
+	status.Quota, err = tenancy.QuotaOf(ctx, r.PivotClient, v1.TenantObj, tenant.Name)
+	if err != nil {
+		return err
+	}
+
+	projectList := &tenantv1.ProjectList{}
+	err = r.PivotClient.List(ctx, projectList, client.MatchingLabels{constants.TenantLabel: tenant.Name})
+	if err != nil {
+		return err
+	}
+	status.ProjectCount = int32(len(projectList.Items))
+	status.State = tenancy.RollupState(&status.CommonStatus)
+
+	tenancy.Rollup(&status.CommonStatus, clusters, tenant.Generation)
+
+	return nil
 }
 func (r *TenantReconciler) deleteTenant(tenantName string) (ctrl.Result, error) {
 	// get projects in tenant
@@ -196,12 +319,56 @@ func (r *TenantReconciler) deleteCubeResourceQuotaOfTenant(tenantName string) er
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func SetupWithManager(mgr ctrl.Manager) error {
-	r, err := newReconciler(mgr)
+func SetupWithManager(mgr ctrl.Manager, isMemberCluster bool, clusterName string, pivotClient client.Client) error {
+	r, err := newReconciler(mgr, isMemberCluster, clusterName, pivotClient)
 	if err != nil {
 		return err
 	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tenantv1.Tenant{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, tenancy.NamespaceEventHandler(namespaceToTenant)).
+		Watches(&source.Kind{Type: &tenantv1.Project{}}, handler.EnqueueRequestsFromMapFunc(projectToTenant)).
+		Watches(&source.Kind{Type: &v1.CubeResourceQuota{}}, handler.EnqueueRequestsFromMapFunc(quotaToTenant)).
+		Watches(&source.Kind{Type: &networkingv1.NetworkPolicy{}}, handler.EnqueueRequestsFromMapFunc(networkPolicyToTenant),
+			builder.WithPredicates(tenancy.ManagedPredicate()), builder.OnlyMetadata).
 		Complete(r)
 }
+
+// networkPolicyToTenant maps isolation network policy to its tenant
+func networkPolicyToTenant(obj client.Object) []reconcile.Request {
+	tenant, _ := tenancy.IsolationPolicyOwner(obj)
+	if len(tenant) == 0 {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tenant}}}
+}
+
+// namespaceToTenant maps namespace to the tenant it belongs to
+func namespaceToTenant(obj client.Object) []reconcile.Request {
+	tenant, ok := obj.GetLabels()[constants.HncTenantLabel]
+	if !ok {
+		if !strings.HasPrefix(obj.GetName(), constants.TenantNsPrefix) {
+			return nil
+		}
+		tenant = strings.TrimPrefix(obj.GetName(), constants.TenantNsPrefix)
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tenant}}}
+}
+
+// projectToTenant maps project to the tenant it belongs to
+func projectToTenant(obj client.Object) []reconcile.Request {
+	tenant, ok := obj.GetLabels()[constants.TenantLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tenant}}}
+}
+
+// quotaToTenant maps CubeResourceQuota to the tenant it targets to
+func quotaToTenant(obj client.Object) []reconcile.Request {
+	quota, ok := obj.(*v1.CubeResourceQuota)
+	if !ok || quota.Spec.Target.Kind != v1.TenantObj {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: quota.Spec.Target.Name}}}
+}
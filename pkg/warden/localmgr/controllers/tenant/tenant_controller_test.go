@@ -22,13 +22,20 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
+	quotav1 "github.com/kubecube-io/kubecube/pkg/apis/quota/v1"
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 )
 
 // tenant template
@@ -49,6 +56,10 @@ func TestReconcile(t *testing.T) {
 	scheme := runtime.NewScheme()
 	_ = tenantv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = quotav1.AddToScheme(scheme)
 
 	// crete
 	tenant1 := tenantTemplate("test-tenant1")
@@ -57,6 +68,8 @@ func TestReconcile(t *testing.T) {
 	tenantReconciler := TenantReconciler{}
 	tenantReconciler.Client = fakeClient
 	tenantReconciler.Scheme = scheme
+	tenantReconciler.ClusterName = "pivot-cluster"
+	tenantReconciler.PivotClient = fakeClient
 
 	req := ctrl.Request{}
 	req.Name = "test-tenant1"
@@ -69,5 +82,94 @@ func TestReconcile(t *testing.T) {
 	namespace := corev1.Namespace{}
 	err = fakeClient.Get(ctx, types.NamespacedName{Name: "kubecube-tenant-test-tenant1"}, &namespace)
 	assert.Nil(err)
+}
+
+func TestReconcileStatus(t *testing.T) {
+	assert := assert.New(t)
+	scheme := runtime.NewScheme()
+	_ = tenantv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = quotav1.AddToScheme(scheme)
+
+	tenant1 := tenantTemplate("tenant-1")
+	objs := []runtime.Object{
+		&tenant1,
+		&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "pivot-cluster"}},
+		&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubecube-tenant-tenant-1"},
+			Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "kubecube-project-project-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "ns-1",
+			Labels: map[string]string{constants.HncTenantLabel: "tenant-1", constants.HncProjectLabel: "project-1"},
+		}},
+		&tenantv1.Project{ObjectMeta: metav1.ObjectMeta{Name: "project-1", Labels: map[string]string{constants.TenantLabel: "tenant-1"}}},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "rb-1", Namespace: "kubecube-tenant-tenant-1", Labels: map[string]string{constants.RbacLabel: "true"}},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}, {Kind: rbacv1.UserKind, Name: "bob"}},
+		},
+		&quotav1.CubeResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-1.quota"},
+			Spec:       quotav1.CubeResourceQuotaSpec{Target: quotav1.TargetObj{Kind: quotav1.TenantObj, Name: "tenant-1"}},
+			Status: quotav1.CubeResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				Used: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			},
+		},
+	}
+	pivotClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+	memberClient := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(tenant1.DeepCopy()).Build()
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "tenant-1"}}
+
+	pivot := TenantReconciler{Client: pivotClient, Scheme: scheme, ClusterName: "pivot-cluster", PivotClient: pivotClient}
+	_, err := pivot.Reconcile(ctx, req)
+	assert.Nil(err)
+
+	tenant := tenantv1.Tenant{}
+	assert.Nil(pivotClient.Get(ctx, req.NamespacedName, &tenant))
+	assert.Equal([]tenantv1.ClusterSummary{{Name: "pivot-cluster", NamespaceReady: true, NamespaceCount: 1, State: tenantv1.TenantActive}}, tenant.Status.Clusters)
+	assert.Equal(tenantv1.TenantActive, tenant.Status.State)
+	assert.Equal(int32(1), tenant.Status.NamespaceCount)
+	assert.Equal(int32(2), tenant.Status.MemberCount)
+	assert.Equal(int32(1), tenant.Status.ProjectCount)
+	assert.Equal("tenant-1.quota", tenant.Status.Quota.Name)
+	assert.True(meta.IsStatusConditionTrue(tenant.Status.Conditions, tenantv1.NamespaceReady))
+	assert.True(meta.IsStatusConditionTrue(tenant.Status.Conditions, tenantv1.QuotaBound))
+	assert.True(meta.IsStatusConditionFalse(tenant.Status.Conditions, tenantv1.SyncedToClusters))
+
+	// warden of member cluster reports its summary to pivot cluster
+	member := TenantReconciler{Client: memberClient, Scheme: scheme, IsMemberCluster: true, ClusterName: "member-1", PivotClient: pivotClient}
+	_, err = member.Reconcile(ctx, req)
+	assert.Nil(err)
+
+	_, err = pivot.Reconcile(ctx, req)
+	assert.Nil(err)
+
+	tenant = tenantv1.Tenant{}
+	assert.Nil(pivotClient.Get(ctx, req.NamespacedName, &tenant))
+	assert.Len(tenant.Status.Clusters, 2)
+	assert.Equal("member-1", tenant.Status.Clusters[0].Name)
+	assert.True(meta.IsStatusConditionTrue(tenant.Status.Conditions, tenantv1.SyncedToClusters))
+	// namespace of tenant in member cluster is just created and not active yet
+	assert.True(meta.IsStatusConditionFalse(tenant.Status.Conditions, tenantv1.NamespaceReady))
+
+	// summary of removed cluster is pruned
+	assert.Nil(pivotClient.Delete(ctx, &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "member-1"}}))
+	_, err = pivot.Reconcile(ctx, req)
+	assert.Nil(err)
 
+	tenant = tenantv1.Tenant{}
+	assert.Nil(pivotClient.Get(ctx, req.NamespacedName, &tenant))
+	assert.Len(tenant.Status.Clusters, 1)
+	assert.True(meta.IsStatusConditionTrue(tenant.Status.Conditions, tenantv1.NamespaceReady))
 }
@@ -29,6 +29,7 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/apis"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	multiclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	cubeutils "github.com/kubecube-io/kubecube/pkg/utils"
 	"github.com/kubecube-io/kubecube/pkg/utils/env"
 	"github.com/kubecube-io/kubecube/pkg/utils/exit"
 	"github.com/kubecube-io/kubecube/pkg/warden/localmgr/controllers/service"
@@ -60,6 +61,7 @@ type LocalManager struct {
 	IsMemberCluster   bool
 	Cluster           string
 	PivotClient       multiclient.Client
+	Maintenance       *cubeutils.MaintenanceWatcher
 
 	NginxNamespace           string
 	NginxTcpServiceConfigMap string
@@ -109,7 +111,7 @@ func (m *LocalManager) Initialize() error {
 		return err
 	}
 
-	reporter.RegisterCheckFunc(m.readyzCheck)
+	reporter.RegisterCheckFunc("localmgr", m.readyzCheck)
 
 	return nil
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package localmgr
 
 import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	admisson "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -35,7 +36,7 @@ import (
 func setupControllersWithManager(m *LocalManager) error {
 	var err error
 
-	err = hotplug.SetupWithManager(m.Manager, m.IsMemberCluster, m.Cluster)
+	err = hotplug.SetupWithManager(m.Manager, m.IsMemberCluster, m.Cluster, m.Maintenance, m.PivotClient.Cache())
 	if err != nil {
 		return err
 	}
@@ -45,12 +46,22 @@ func setupControllersWithManager(m *LocalManager) error {
 	//	return err
 	//}
 
-	err = tenant.SetupWithManager(m.Manager)
+	// status of tenants and projects is reconciled frequently, reads of pivot
+	// cluster go through cache and only writes go to pivot cluster directly
+	cachedPivotClient, err := client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader: m.PivotClient.Cache(),
+		Client:      m.PivotClient.Direct(),
+	})
 	if err != nil {
 		return err
 	}
 
-	err = project.SetupWithManager(m.Manager)
+	err = tenant.SetupWithManager(m.Manager, m.IsMemberCluster, m.Cluster, cachedPivotClient)
+	if err != nil {
+		return err
+	}
+
+	err = project.SetupWithManager(m.Manager, m.IsMemberCluster, m.Cluster, cachedPivotClient)
 	if err != nil {
 		return err
 	}
@@ -72,6 +83,7 @@ func setupControllersWithManager(m *LocalManager) error {
 func setupWithWebhooks(m *LocalManager) {
 	hookServer := m.GetWebhookServer()
 	hookServer.Register("/warden-validate-tenant-kubecube-io-v1-tenant", &webhook.Admission{Handler: &tenant2.Validator{Client: m.GetClient(), IsMember: m.IsMemberCluster}})
+	hookServer.Register("/warden-validate-tenant-state", &webhook.Admission{Handler: &tenant2.StateValidator{Client: m.GetClient()}})
 	hookServer.Register("/warden-validate-tenant-kubecube-io-v1-project", &webhook.Admission{Handler: &project2.Validator{Client: m.GetClient(), IsMember: m.IsMemberCluster}})
 	hookServer.Register("/validate-core-kubernetes-v1-resource-quota", &webhook.Admission{Handler: &quota2.ResourceQuotaValidator{PivotClient: m.PivotClient.Direct(), LocalClient: m.GetClient()}})
 	hookServer.Register("/warden-validate-hotplug-kubecube-io-v1-hotplug", admisson.ValidatingWebhookFor(hotplug2.NewHotplugValidator(m.IsMemberCluster)))
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
+	"github.com/kubecube-io/kubecube/pkg/utils/env"
+)
+
+const (
+	// wardenServiceAccount is the service account warden runs as in cube namespace
+	wardenServiceAccount = "default"
+
+	// kubeSystemServiceAccountPrefix is the username prefix of controllers of
+	// kubernetes running with service account credentials
+	kubeSystemServiceAccountPrefix = "system:serviceaccount:kube-system:"
+)
+
+// StateValidator blocks writes into namespaces of suspended or archived tenant,
+// including subresources such as deployments/scale, pods/eviction and streams
+// of pods/exec, pods/attach and pods/portforward which come as CONNECT.
+// Requests from system components are allowed, such as warden itself and the
+// controllers of kubernetes, so that suspension and archive are able to proceed.
+type StateValidator struct {
+	Client client.Client
+}
+
+func (r *StateValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if len(req.Namespace) == 0 || isSystemUser(req.UserInfo.Username, req.UserInfo.Groups) {
+		return admission.Allowed("")
+	}
+
+	ns := &corev1.Namespace{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: req.Namespace}, ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if state := tenancy.AppliedState(ns); state != tenantv1.TenantActive {
+		return admission.Denied(fmt.Sprintf("tenant %v of namespace %v is %v", tenancy.TenantOfNamespace(ns), ns.Name, state))
+	}
+
+	return admission.Allowed("")
+}
+
+// isSystemUser tells if the request comes from warden, cluster admin, nodes or
+// controllers of kubernetes. Service accounts of users are not exempted.
+func isSystemUser(username string, groups []string) bool {
+	switch username {
+	case "system:serviceaccount:" + env.CubeNamespace() + ":" + wardenServiceAccount,
+		user.KubeControllerManager, user.KubeScheduler:
+		return true
+	}
+	if strings.HasPrefix(username, kubeSystemServiceAccountPrefix) || strings.HasPrefix(username, "system:node:") {
+		return true
+	}
+	for _, g := range groups {
+		if g == user.SystemPrivilegedGroup || g == user.NodesGroup {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func TestIsSystemUser(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		username string
+		groups   []string
+		expected bool
+	}{
+		{"system:serviceaccount:kubecube-system:default", nil, true},
+		{"system:serviceaccount:kube-system:replicaset-controller", nil, true},
+		{"system:kube-controller-manager", nil, true},
+		{"system:node:node-1", []string{"system:nodes"}, true},
+		{"kubernetes-admin", []string{"system:masters"}, true},
+		// service accounts of users are not exempted
+		{"system:serviceaccount:ns-1:default", []string{"system:serviceaccounts"}, false},
+		{"system:serviceaccount:kubecube-system:other", nil, false},
+		{"system:anonymous", nil, false},
+		{"admin", []string{"system:authenticated"}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(test.expected, isSystemUser(test.username, test.groups), test.username)
+	}
+}
+
+func TestStateValidatorSubresources(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	suspended := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "ns-1",
+		Labels: map[string]string{constants.TenantStateLabel: string(tenantv1.TenantSuspended)},
+	}}
+	active := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-2"}}
+	validator := &StateValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(suspended, active).Build()}
+
+	tests := []struct {
+		operation   admissionv1.Operation
+		resource    string
+		subResource string
+	}{
+		{admissionv1.Update, "deployments", "scale"},
+		{admissionv1.Create, "pods", "eviction"},
+		{admissionv1.Connect, "pods", "exec"},
+		{admissionv1.Connect, "pods", "attach"},
+		{admissionv1.Connect, "pods", "portforward"},
+	}
+	for _, test := range tests {
+		for _, ns := range []string{suspended.Name, active.Name} {
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation:   test.operation,
+				Namespace:   ns,
+				Name:        "app",
+				Resource:    metav1.GroupVersionResource{Version: "v1", Resource: test.resource},
+				SubResource: test.subResource,
+				UserInfo:    authenticationv1.UserInfo{Username: "admin"},
+			}}
+			resp := validator.Handle(context.Background(), req)
+			assert.Equal(ns == active.Name, resp.Allowed, "%v/%v in %v", test.resource, test.subResource, ns)
+		}
+	}
+}
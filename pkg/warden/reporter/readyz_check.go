@@ -19,15 +19,33 @@ package reporter
 import (
 	"context"
 	"time"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 )
 
 type checkFunc func() bool
 
-var checkFuncs []checkFunc
+// namedCheckFunc is the readyz check func of a component
+type namedCheckFunc struct {
+	name string
+	fn   checkFunc
+}
+
+var checkFuncs []namedCheckFunc
 
-// RegisterCheckFunc should be used to registerIfNeed readyz check func
-func RegisterCheckFunc(fn checkFunc) {
-	checkFuncs = append(checkFuncs, fn)
+// RegisterCheckFunc should be used to registerIfNeed readyz check func,
+// the name of component will be reported to pivot cluster with its readiness
+func RegisterCheckFunc(name string, fn checkFunc) {
+	checkFuncs = append(checkFuncs, namedCheckFunc{name: name, fn: fn})
+}
+
+// componentsStatus returns the real-time readiness of all registered components
+func componentsStatus() []clusterv1.ComponentStatus {
+	components := make([]clusterv1.ComponentStatus, 0, len(checkFuncs))
+	for _, c := range checkFuncs {
+		components = append(components, clusterv1.ComponentStatus{Name: c.name, Ready: c.fn()})
+	}
+	return components
 }
 
 func readyzCheck(ctx context.Context, ch chan struct{}, checkFn checkFunc) {
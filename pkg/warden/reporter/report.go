@@ -25,6 +25,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/wait"
 
@@ -32,6 +33,15 @@ import (
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	"github.com/kubecube-io/kubecube/pkg/multicluster/scout"
 	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
+	"github.com/kubecube-io/kubecube/pkg/version"
+)
+
+const (
+	// collectTimeout is the timeout to collect info of local cluster
+	collectTimeout = 3 * time.Second
+
+	// metricsGroupVersion is the group version of metrics api
+	metricsGroupVersion = "metrics.k8s.io/v1beta1"
 )
 
 // reporting do real report loop
@@ -64,22 +74,43 @@ func (r *Reporter) registerIfNeed(ctx context.Context) error {
 	clog.Info("pivot kubecube address is %v", r.PivotCubeHost)
 
 	return wait.Poll(3*time.Second, 15*time.Second, func() (done bool, err error) {
-		cluster := &clusterv1.Cluster{
+		cli := r.PivotClient.Direct()
+
+		cluster := &clusterv1.Cluster{}
+		err = cli.Get(ctx, types.NamespacedName{Name: r.Cluster}, cluster)
+		if err == nil {
+			log.Debug("cluster cr %v is already exist", cluster.Name)
+			return true, nil
+		}
+		if !errors.IsNotFound(err) {
+			log.Warn("get cluster %v failed: %v", r.Cluster, err)
+			return false, nil
+		}
+
+		// kubeconfig of cluster is stored in secret of pivot cluster
+		ref, err := kubeconfig.EnsureSecretForCluster(ctx, cli, r.Cluster, r.rawLocalKubeConfig)
+		if err != nil {
+			log.Warn("store kubeconfig of cluster %v failed: %v", r.Cluster, err)
+			return false, nil
+		}
+
+		cluster = &clusterv1.Cluster{
 			ObjectMeta: metav1.ObjectMeta{Name: r.Cluster},
 			Spec: clusterv1.ClusterSpec{
-				KubeConfig:            r.rawLocalKubeConfig,
+				KubeConfigSecretRef:   ref,
 				IsMemberCluster:       r.IsMemberCluster,
 				IsWritable:            r.IsWritable,
 				KubernetesAPIEndpoint: cfg.Host,
+				ConnectionMode:        r.ConnectionMode,
 			},
 		}
-		err = r.PivotClient.Direct().Create(ctx, cluster)
+		err = cli.Create(ctx, cluster)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
 				log.Debug("cluster cr %v is already exist", cluster.Name)
 				return true, nil
 			}
-			log.Warn("create cluster %v failed: %v", err)
+			log.Warn("create cluster %v failed: %v", cluster.Name, err)
 			return false, nil
 		}
 		return true, nil
@@ -87,13 +118,15 @@ func (r *Reporter) registerIfNeed(ctx context.Context) error {
 }
 
 func (r *Reporter) report() bool {
-	w := scout.WardenInfo{Cluster: r.Cluster, ReportTime: time.Now()}
+	w := r.collect()
 
+	start := time.Now()
 	resp, err := r.do(w)
 	if err != nil {
 		log.Debug("warden report failed: %v", err)
 		return false
 	}
+	r.latency = time.Since(start)
 
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -104,6 +137,92 @@ func (r *Reporter) report() bool {
 	return true
 }
 
+// collect populates the warden info of local cluster, the info
+// which failed to collect will be omitted
+func (r *Reporter) collect() scout.WardenInfo {
+	w := scout.WardenInfo{
+		Cluster:       r.Cluster,
+		ReportTime:    time.Now(),
+		Latency:       r.latency,
+		WardenVersion: version.Version,
+		Components:    componentsStatus(),
+	}
+
+	if r.localClient == nil {
+		return w
+	}
+
+	serverVersion, err := r.localClient.Discovery().ServerVersion()
+	if err != nil {
+		log.Debug("get kubernetes version failed: %v", err)
+	} else {
+		w.KubernetesVersion = serverVersion.GitVersion
+	}
+
+	metricsAvailable := true
+	_, err = r.localClient.Discovery().ServerResourcesForGroupVersion(metricsGroupVersion)
+	if err != nil {
+		log.Debug("metrics api is unavailable: %v", err)
+		metricsAvailable = false
+	}
+	w.MetricsAvailable = &metricsAvailable
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	nodeCount, err := countOf(func(opts metav1.ListOptions) (metav1.ListInterface, int, error) {
+		l, err := r.localClient.CoreV1().Nodes().List(ctx, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return l, len(l.Items), nil
+	})
+	if err != nil {
+		log.Debug("count nodes failed: %v", err)
+	} else {
+		w.NodeCount = &nodeCount
+	}
+
+	podCount, err := countOf(func(opts metav1.ListOptions) (metav1.ListInterface, int, error) {
+		l, err := r.localClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return l, len(l.Items), nil
+	})
+	if err != nil {
+		log.Debug("count pods failed: %v", err)
+	} else {
+		w.PodCount = &podCount
+	}
+
+	return w
+}
+
+// countOf counts the objects by list with limit 1 and the remaining item count,
+// it falls back to full list when remaining item count is not supported.
+func countOf(list func(opts metav1.ListOptions) (metav1.ListInterface, int, error)) (int32, error) {
+	l, n, err := list(metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return 0, err
+	}
+
+	if remaining := l.GetRemainingItemCount(); remaining != nil {
+		return int32(n) + int32(*remaining), nil
+	}
+
+	if len(l.GetContinue()) == 0 {
+		return int32(n), nil
+	}
+
+	_, n, err = list(metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(n), nil
+}
+
 func (r *Reporter) do(info scout.WardenInfo) (*http.Response, error) {
 	data, err := json.Marshal(info)
 	if err != nil {
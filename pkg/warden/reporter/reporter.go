@@ -24,8 +24,12 @@ import (
 	"net/http"
 	"time"
 
+	"k8s.io/client-go/kubernetes"
+
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	multiclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
 )
 
 var log clog.CubeLogger
@@ -61,12 +65,24 @@ type Reporter struct {
 	// PivotClient used to connect to pivot cluster k8s-apiserver
 	PivotClient multiclient.Client
 
+	// ConnectionMode is how KubeCube connects to current cluster when registering
+	ConnectionMode clusterv1.ConnectionMode
+
+	// TLSConfig is used to verify KubeCube when reporting
+	TLSConfig *tls.Config
+
 	// rawLocalKubeConfig is load from LocalClusterKubeConfig
 	rawLocalKubeConfig []byte
 
+	// localClient used to collect info of local cluster
+	localClient kubernetes.Interface
+
 	// pivotHealthy the pivot cluster healthy status
 	pivotHealthy bool
 
+	// latency is the round-trip time of last heartbeat
+	latency time.Duration
+
 	// http.Client used to reporting heartbeat
 	*http.Client
 }
@@ -74,10 +90,9 @@ type Reporter struct {
 func (r *Reporter) Initialize() error {
 	log = clog.WithName("reporter")
 
-	// todo:(vela) support tls
 	r.Client = &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			TLSClientConfig: r.TLSConfig,
 		},
 		Timeout: 5 * time.Second,
 	}
@@ -89,6 +104,16 @@ func (r *Reporter) Initialize() error {
 
 	r.rawLocalKubeConfig = b
 
+	cfg, err := kubeconfig.LoadKubeConfigFromBytes(b)
+	if err != nil {
+		return err
+	}
+
+	r.localClient, err = kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -123,8 +148,8 @@ func (r *Reporter) waitForReady() error {
 
 	readyzCh := make(chan struct{})
 
-	for _, fn := range checkFuncs {
-		go readyzCheck(ctx, readyzCh, fn)
+	for _, c := range checkFuncs {
+		go readyzCheck(ctx, readyzCh, c.fn)
 	}
 
 	for {
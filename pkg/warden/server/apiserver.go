@@ -22,11 +22,18 @@ import (
 	"net/http"
 	"time"
 
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/metrics"
+	multiclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
 	"github.com/kubecube-io/kubecube/pkg/warden/reporter"
 	"github.com/kubecube-io/kubecube/pkg/warden/server/authproxy"
 )
 
+// component is the component name of requests in metrics
+const component = "warden-authproxy"
+
 var log clog.CubeLogger
 
 type Server struct {
@@ -38,25 +45,41 @@ type Server struct {
 	TlsKey                 string
 	LocalClusterKubeConfig string
 
+	// Cluster is the cluster where warden running in
+	Cluster string
+
+	// PivotClient reads Cluster from pivot cluster to resolve feature gates
+	PivotClient multiclient.Client
+
+	// MetricsPort serves metrics over http, metrics are not
+	// served if zero
+	MetricsPort   int
+	MetricsServer *http.Server
+
 	ready bool
 }
 
 func (s *Server) Initialize() error {
 	log = clog.WithName("authproxy")
 
-	reporter.RegisterCheckFunc(s.readyzCheck)
+	reporter.RegisterCheckFunc("apiserver", s.readyzCheck)
 
 	return nil
 }
 
 func (s *Server) Run(stop <-chan struct{}) {
-	authProxyHandler, err := authproxy.NewHandler(s.LocalClusterKubeConfig)
+	var pivotCache ctrlclient.Reader
+	if s.PivotClient != nil {
+		pivotCache = s.PivotClient.Cache()
+	}
+
+	authProxyHandler, err := authproxy.NewHandler(s.LocalClusterKubeConfig, s.Cluster, pivotCache)
 	if err != nil {
 		log.Fatal("new auth proxy handler failed: %v", err)
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/", authProxyHandler)
+	mux.Handle("/", metrics.InstrumentHandler(component, "/", s.Cluster, authProxyHandler))
 
 	s.Server = &http.Server{Handler: mux, Addr: fmt.Sprintf("%s:%d", s.BindAddr, s.Port)}
 
@@ -69,6 +92,22 @@ func (s *Server) Run(stop <-chan struct{}) {
 
 	log.Info("auth proxy server listen in %s:%d", s.BindAddr, s.Port)
 
+	if s.MetricsPort != 0 {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle(metrics.Path, metrics.Handler())
+
+		s.MetricsServer = &http.Server{Handler: metricsMux, Addr: fmt.Sprintf("%s:%d", s.BindAddr, s.MetricsPort)}
+
+		go func() {
+			err := s.MetricsServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatal("metrics server start err: %v", err)
+			}
+		}()
+
+		log.Info("metrics server listen in %s:%d", s.BindAddr, s.MetricsPort)
+	}
+
 	// mark auth proxy server ready
 	s.ready = true
 
@@ -83,6 +122,12 @@ func (s *Server) Run(stop <-chan struct{}) {
 		log.Fatal("auth proxy server forced to shutdown:", err)
 	}
 
+	if s.MetricsServer != nil {
+		if err := s.MetricsServer.Shutdown(ctx); err != nil {
+			log.Fatal("metrics server forced to shutdown: %v", err)
+		}
+	}
+
 	log.Info("auth proxy server exiting")
 }
 
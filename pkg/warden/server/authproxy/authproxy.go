@@ -18,22 +18,32 @@ package authproxy
 
 import (
 	"context"
-
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/authentication/authenticators"
 	"github.com/kubecube-io/kubecube/pkg/authentication/authenticators/jwt"
 	"github.com/kubecube-io/kubecube/pkg/authentication/authenticators/token"
 	"github.com/kubecube-io/kubecube/pkg/belongs"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/features"
 	"github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/tenancy"
+	"github.com/kubecube-io/kubecube/pkg/tracing"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/pkg/utils/path"
 	requestutil "github.com/kubecube-io/kubecube/pkg/utils/request"
 	"github.com/kubecube-io/kubecube/pkg/warden/server/authproxy/proxy"
 )
@@ -49,12 +59,18 @@ type Handler struct {
 
 	cli client.Client
 
+	// cluster is the cluster where warden running in
+	cluster string
+
+	// pivotCache reads Cluster from pivot cluster to resolve feature gates
+	pivotCache ctrlclient.Reader
+
 	// proxy do real proxy action with any inbound stream
 	proxy *proxy.UpgradeAwareHandler
 }
 
-func NewHandler(localClusterKubeConfig string) (*Handler, error) {
-	h := &Handler{}
+func NewHandler(localClusterKubeConfig, cluster string, pivotCache ctrlclient.Reader) (*Handler, error) {
+	h := &Handler{cluster: cluster, pivotCache: pivotCache}
 	h.authMgr = jwt.GetAuthJwtImpl()
 
 	// get cluster info from rest config
@@ -100,9 +116,24 @@ func NewHandler(localClusterKubeConfig string) (*Handler, error) {
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// join the trace propagated from cube if any
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "warden.authproxy",
+		attribute.String("http.method", r.Method),
+		attribute.String("http.target", r.URL.Path))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	// auth proxy is gated per cluster
+	if h.pivotCache != nil && !features.EnabledForCluster(ctx, h.pivotCache, h.cluster, features.WardenAuthProxy) {
+		span.SetAttributes(attribute.Int("http.status_code", http.StatusForbidden))
+		http.Error(w, fmt.Sprintf("feature %v is disabled in cluster %v", features.WardenAuthProxy, h.cluster), http.StatusForbidden)
+		return
+	}
+
 	// parse token transfer to user info
 	userInfo, err := token.GetUserFromReq(r)
 	if err != nil {
+		span.SetAttributes(attribute.Int("http.status_code", http.StatusUnauthorized))
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -113,10 +144,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		clog.Warn(err.Error())
 	} else if !allowed {
+		span.SetAttributes(attribute.Int("http.status_code", http.StatusForbidden))
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
+	// writes into namespaces of suspended or archived tenant are blocked
+	if code, reason := h.blockedByTenantState(ctx, r); code != 0 {
+		span.SetAttributes(attribute.Int("http.status_code", code))
+		http.Error(w, reason, code)
+		return
+	}
+
 	err = requestutil.AddFieldManager(r, userInfo.Username)
 	if err != nil {
 		clog.Error("fail to add fieldManager due to %s", err)
@@ -125,5 +164,39 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// impersonate given user to access k8s-apiserver
 	r.Header.Set(constants.ImpersonateUserKey, userInfo.Username)
 
+	// propagate trace context to k8s-apiserver
+	tracing.Inject(ctx, r.Header)
+
 	h.proxy.ServeHTTP(w, r)
 }
+
+// blockedByTenantState tells if the request writes into namespace of tenant
+// which is not active, returns the status code and reason to reject request
+// with, or zero if allowed. Writes are rejected if the state is unknown.
+func (h *Handler) blockedByTenantState(ctx context.Context, r *http.Request) (int, string) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return 0, ""
+	}
+
+	ri, err := path.Parse(r.URL.Path)
+	if err != nil || !ri.IsNamespaced || len(ri.Namespace) == 0 {
+		return 0, ""
+	}
+
+	ns := &corev1.Namespace{}
+	err = h.cli.Cache().Get(ctx, types.NamespacedName{Name: ri.Namespace}, ns)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return 0, ""
+		}
+		clog.Warn("get namespace %v failed: %v", ri.Namespace, err)
+		return http.StatusServiceUnavailable, fmt.Sprintf("state of namespace %v is unknown", ri.Namespace)
+	}
+
+	if state := tenancy.AppliedState(ns); state != tenantv1.TenantActive {
+		return http.StatusForbidden, fmt.Sprintf("tenant %v of namespace %v is %v", tenancy.TenantOfNamespace(ns), ns.Name, state)
+	}
+
+	return 0, ""
+}
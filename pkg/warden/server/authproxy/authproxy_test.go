@@ -0,0 +1,70 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client/fake"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+)
+
+func TestBlockedByTenantState(t *testing.T) {
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	h := &Handler{cli: fake.NewFakeClients(&fake.Options{
+		Scheme: scheme,
+		Objs: []client.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "active"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "suspended", Labels: map[string]string{constants.TenantStateLabel: "Suspended"}}},
+		},
+	})}
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/api/v1/namespaces/suspended/pods", 0},
+		{http.MethodPost, "/api/v1/namespaces/suspended/pods", http.StatusForbidden},
+		{http.MethodPost, "/api/v1/namespaces/active/pods", 0},
+		// namespace not found is not blocked
+		{http.MethodPost, "/api/v1/namespaces/unknown/pods", 0},
+		{http.MethodPost, "/api/v1/namespaces", 0},
+	}
+	for _, test := range tests {
+		code, _ := h.blockedByTenantState(context.Background(), httptest.NewRequest(test.method, test.path, nil))
+		assert.Equal(test.code, code, "%v %v", test.method, test.path)
+	}
+
+	// writes are rejected when state of namespace is unknown
+	h = &Handler{cli: fake.NewFakeClients(&fake.Options{Scheme: runtime.NewScheme()})}
+	code, _ := h.blockedByTenantState(context.Background(), httptest.NewRequest(http.MethodPost, "/api/v1/namespaces/active/pods", nil))
+	assert.Equal(http.StatusServiceUnavailable, code)
+}
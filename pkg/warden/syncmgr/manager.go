@@ -27,6 +27,7 @@ import (
 
 	"github.com/kubecube-io/kubecube/pkg/apis"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	cubeutils "github.com/kubecube-io/kubecube/pkg/utils"
 	"github.com/kubecube-io/kubecube/pkg/utils/exit"
 	"github.com/kubecube-io/kubecube/pkg/warden/reporter"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -58,6 +59,12 @@ type SyncManager struct {
 	ctrl.Manager
 	LocalClient            client.Client
 	PivotClusterKubeConfig string
+
+	// Cluster is the cluster where warden running in
+	Cluster string
+
+	// Maintenance tells if cluster is under maintenance
+	Maintenance *cubeutils.MaintenanceWatcher
 }
 
 func (s *SyncManager) Initialize() error {
@@ -90,7 +97,7 @@ func (s *SyncManager) Initialize() error {
 		return err
 	}
 
-	reporter.RegisterCheckFunc(s.readyzCheck)
+	reporter.RegisterCheckFunc("syncmgr", s.readyzCheck)
 
 	return nil
 }
@@ -19,6 +19,7 @@ package syncmgr
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -30,6 +31,7 @@ import (
 
 	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
+	"github.com/kubecube-io/kubecube/pkg/metrics"
 	"github.com/kubecube-io/kubecube/pkg/utils/constants"
 )
 
@@ -40,7 +42,10 @@ const (
 	Delete = "delete"
 
 	// pivot resource version key for compare with local resource
-	pivotResourceVersion = "pivotResourceVersion"
+	pivotResourceVersion = constants.PivotResourceVersionAnnotation
+
+	// maintenanceRequeueInterval is the interval to retry sync when cluster under maintenance
+	maintenanceRequeueInterval = 30 * time.Second
 )
 
 /*
@@ -68,6 +73,11 @@ func (s *SyncManager) SetupCtrlWithManager(resource client.Object, objFunc Gener
 			clog.Info("sync: %s %v, name: %v, namespace: %v, err: %v", action, obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), obj.GetNamespace(), err)
 		}()
 
+		// sync is paused when cluster under maintenance
+		if s.Maintenance.InMaintenance() {
+			return reconcile.Result{RequeueAfter: maintenanceRequeueInterval}, nil
+		}
+
 		deleteObjFunc := func() (reconcile.Result, error) {
 			gvk, err := apiutil.GVKForObject(obj, s.Manager.GetScheme())
 			if err != nil {
@@ -162,10 +172,22 @@ func (s *SyncManager) SetupCtrlWithManager(resource client.Object, objFunc Gener
 		return reconcile.Result{}, nil
 	})
 
+	gvk, err := apiutil.GVKForObject(resource, s.Manager.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	// record reconcile results into metrics by kind
+	instrumented := reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		result, err := r(ctx, req)
+		metrics.ObserveSyncReconcile(gvk.Kind, err)
+		return result, err
+	})
+
 	return ctrl.NewControllerManagedBy(s).
 		For(resource).
 		WithEventFilter(eventPredicate()).
-		Complete(r)
+		Complete(instrumented)
 }
 
 // trimObjMeta trim read-only field of obj metadata avoid of conflict
@@ -44,6 +44,7 @@ var syncResources = []client.Object{
 	&hotplug.Hotplug{},
 	&tenant.Tenant{},
 	&tenant.Project{},
+	&tenant.ProjectTemplate{},
 	&user.User{},
 	&extension.ExternalResource{},
 	&quota.CubeResourceQuota{},
@@ -70,6 +71,8 @@ func newGenericObj(obj client.Object) (client.Object, error) {
 		return &tenant.Project{}, nil
 	case *tenant.Tenant:
 		return &tenant.Tenant{}, nil
+	case *tenant.ProjectTemplate:
+		return &tenant.ProjectTemplate{}, nil
 	case *quota.CubeResourceQuota:
 		return &quota.CubeResourceQuota{}, nil
 	case *corev1.Namespace:
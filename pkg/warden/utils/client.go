@@ -16,6 +16,26 @@ limitations under the License.
 
 package utils
 
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
 // Cluster local cluster name
 // deprecated: global v is not good
 var Cluster string
+
+// NewUncachedClient returns a client of manager which reads the given objects
+// directly from apiserver instead of cache, so that no informer of all of them
+// is started when they are only watched by metadata partially.
+func NewUncachedClient(mgr manager.Manager, uncached ...client.Object) (client.Client, error) {
+	direct, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader:     mgr.GetCache(),
+		Client:          direct,
+		UncachedObjects: uncached,
+	})
+}
@@ -18,11 +18,19 @@ package warden
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
 
 	"k8s.io/client-go/tools/clientcmd"
 
+	clusterv1 "github.com/kubecube-io/kubecube/pkg/apis/cluster/v1"
 	"github.com/kubecube-io/kubecube/pkg/clog"
 	multiclient "github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/tunnel"
+	cubeutils "github.com/kubecube-io/kubecube/pkg/utils"
+	"github.com/kubecube-io/kubecube/pkg/utils/kubeconfig"
 	"github.com/kubecube-io/kubecube/pkg/warden/localmgr"
 	"github.com/kubecube-io/kubecube/pkg/warden/reporter"
 	"github.com/kubecube-io/kubecube/pkg/warden/server"
@@ -40,6 +48,7 @@ type Warden struct {
 	Server    *server.Server
 	Reporter  *reporter.Reporter
 	LocalCtrl *localmgr.LocalManager
+	Tunnel    *tunnel.Agent
 }
 
 func NewWardenWithOpts(opts *Config) *Warden {
@@ -48,6 +57,13 @@ func NewWardenWithOpts(opts *Config) *Warden {
 		clog.Fatal("init pivot client failed: %v", err)
 	}
 
+	// maintenance of cluster is kept in memory by watching cluster in pivot cluster
+	maintenance := cubeutils.NewMaintenanceWatcher(opts.Cluster)
+	err = maintenance.Watch(context.Background(), pivotClient.Cache())
+	if err != nil {
+		clog.Fatal("watch maintenance of cluster %v failed: %v", opts.Cluster, err)
+	}
+
 	w := new(Warden)
 
 	w.Server = &server.Server{
@@ -57,6 +73,21 @@ func NewWardenWithOpts(opts *Config) *Warden {
 		TlsKey:                 opts.TlsKey,
 		TlsCert:                opts.TlsCert,
 		LocalClusterKubeConfig: opts.LocalClusterKubeConfig,
+		Cluster:                opts.Cluster,
+		PivotClient:            pivotClient,
+		MetricsPort:            opts.MetricsPort,
+	}
+
+	// both reporter and tunnel agent connect to KubeCube
+	cubeTLS, err := cubeTLSConfig(opts.CubeCACert)
+	if err != nil {
+		clog.Fatal("load ca of KubeCube failed: %v", err)
+	}
+
+	connectionMode := clusterv1.ConnectionDirect
+	if opts.EnableTunnel {
+		connectionMode = clusterv1.ConnectionTunnel
+		w.Tunnel = makeTunnelAgent(opts, cubeTLS)
 	}
 
 	w.Reporter = &reporter.Reporter{
@@ -68,6 +99,8 @@ func NewWardenWithOpts(opts *Config) *Warden {
 		WaitSecond:             opts.WaitSecond,
 		LocalClusterKubeConfig: opts.LocalClusterKubeConfig,
 		PivotClient:            pivotClient,
+		ConnectionMode:         connectionMode,
+		TLSConfig:              cubeTLS,
 	}
 
 	w.LocalCtrl = &localmgr.LocalManager{
@@ -78,6 +111,7 @@ func NewWardenWithOpts(opts *Config) *Warden {
 		WebhookCert:              opts.WebhookCert,
 		WebhookServerPort:        opts.WebhookServerPort,
 		PivotClient:              pivotClient,
+		Maintenance:              maintenance,
 		NginxNamespace:           opts.NginxNamespace,
 		NginxTcpServiceConfigMap: opts.NginxTcpServiceConfigMap,
 		NginxUdpServiceConfigMap: opts.NginxUdpServiceConfigMap,
@@ -89,6 +123,8 @@ func NewWardenWithOpts(opts *Config) *Warden {
 	if opts.InMemberCluster {
 		w.SyncCtrl = &syncmgr.SyncManager{
 			PivotClusterKubeConfig: opts.PivotClusterKubeConfig,
+			Cluster:                opts.Cluster,
+			Maintenance:            maintenance,
 		}
 	}
 
@@ -132,6 +168,10 @@ func (w *Warden) Run(stop <-chan struct{}) {
 		go w.SyncCtrl.Run(stop)
 	}
 
+	if w.Tunnel != nil {
+		go w.Tunnel.Run(stop)
+	}
+
 	w.Reporter.Run(stop)
 }
 
@@ -149,3 +189,52 @@ func makePivotClient(kubeconfig string) (multiclient.Client, error) {
 
 	return cli, nil
 }
+
+// makeTunnelAgent make agent to open tunnel for local cluster
+func makeTunnelAgent(opts *Config, tlsConfig *tls.Config) *tunnel.Agent {
+	cfg, err := clientcmd.BuildConfigFromFlags("", opts.LocalClusterKubeConfig)
+	if err != nil {
+		clog.Fatal("load local kubeconfig failed: %v", err)
+	}
+
+	upstream, err := tunnel.UpstreamOf(cfg)
+	if err != nil {
+		clog.Fatal("parse address of local kube-apiserver failed: %v", err)
+	}
+
+	// the kubeconfig registered to KubeCube proves the ownership of cluster
+	credential, err := kubeconfig.LoadKubeConfigFromFlags(opts.LocalClusterKubeConfig)
+	if err != nil {
+		clog.Fatal("read local kubeconfig failed: %v", err)
+	}
+
+	return &tunnel.Agent{
+		Cluster:       opts.Cluster,
+		PivotCubeHost: opts.PivotCubeHost,
+		Upstream:      upstream,
+		Credential:    credential,
+		TLSConfig:     tlsConfig,
+	}
+}
+
+// cubeTLSConfig returns tls config to verify KubeCube by given ca bundle, which
+// is the ca.crt of cube-tls-secret by default. KubeCube is not verified if no ca
+// given to keep compatible with wardens deployed before.
+func cubeTLSConfig(caFile string) (*tls.Config, error) {
+	if len(caFile) == 0 {
+		clog.Warn("ca of KubeCube not given, certificate of KubeCube will not be verified")
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	caData, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no valid certificate found in %v", caFile)
+	}
+
+	return &tls.Config{RootCAs: pool}, nil
+}
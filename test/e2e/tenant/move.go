@@ -0,0 +1,168 @@
+/*
+Copyright 2021 KubeCube Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/onsi/ginkgo"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	hnc "sigs.k8s.io/hierarchical-namespaces/api/v1alpha2"
+
+	tenantv1 "github.com/kubecube-io/kubecube/pkg/apis/tenant/v1"
+	"github.com/kubecube-io/kubecube/pkg/clients"
+	"github.com/kubecube-io/kubecube/pkg/multicluster/client"
+	"github.com/kubecube-io/kubecube/pkg/utils/constants"
+	"github.com/kubecube-io/kubecube/test/e2e/framework"
+)
+
+// move project runs against HNC deployed with KubeCube, so that re-parenting
+// namespaces of project is validated by the admission of HNC
+var _ = ginkgo.Describe("Test Move Project", func() {
+	f := framework.NewDefaultFramework("move")
+	ginkgo.Context("move project between tenants", func() {
+		randnum := strconv.Itoa(rand.Intn(10000))
+		var fromTenant = fmt.Sprintf("e2etest-from-%s", randnum)
+		var toTenant = fmt.Sprintf("e2etest-to-%s", randnum)
+		var projectName = fmt.Sprintf("e2etest-move-%s", randnum)
+		var subNs = fmt.Sprintf("e2etest-move-ns-%s", randnum)
+		var cli client.Client
+		ginkgo.BeforeEach(func() {
+			cli = clients.Interface().Kubernetes(constants.LocalCluster)
+		})
+
+		ginkgo.It("create tenants and project with subnamespace", func() {
+			for _, tenant := range []string{fromTenant, toTenant} {
+				tenantJson := "{\"apiVersion\":\"tenant.kubecube.io/v1\",\"kind\":\"Tenant\",\"metadata\":{\"name\":\"" + tenant + "\"},\"spec\":{\"displayName\":\"" + tenant + "\"}}"
+				req := f.HttpHelper.Post(f.HttpHelper.FormatUrl("/proxy/clusters/pivot-cluster/apis/tenant.kubecube.io/v1/tenants"), tenantJson, nil)
+				_, err := f.HttpHelper.Client.Do(&req)
+				framework.ExpectNoError(err)
+				err = waitNamespace(f, cli, constants.TenantNsPrefix+tenant)
+				framework.ExpectNoError(err)
+			}
+
+			projectJson := "{\"apiVersion\":\"tenant.kubecube.io/v1\",\"kind\":\"Project\",\"metadata\":{\"labels\":{\"kubecube.io/tenant\":\"" + fromTenant + "\"},\"name\":\"" + projectName + "\"},\"spec\":{\"displayName\":\"" + projectName + "\"}}"
+			req := f.HttpHelper.Post(f.HttpHelper.FormatUrl("/proxy/clusters/pivot-cluster/apis/tenant.kubecube.io/v1/projects"), projectJson, nil)
+			_, err := f.HttpHelper.Client.Do(&req)
+			framework.ExpectNoError(err)
+			err = waitNamespace(f, cli, constants.ProjectNsPrefix+projectName)
+			framework.ExpectNoError(err)
+
+			anchor := &hnc.SubnamespaceAnchor{
+				ObjectMeta: metav1.ObjectMeta{Name: subNs, Namespace: constants.ProjectNsPrefix + projectName},
+				Spec: hnc.SubnamespaceAnchorSpec{Labels: []hnc.MetaKVP{
+					{Key: constants.HncTenantLabel, Value: fromTenant},
+					{Key: constants.HncProjectLabel, Value: projectName},
+				}},
+			}
+			err = cli.Direct().Create(context.TODO(), anchor)
+			framework.ExpectNoError(err)
+			err = waitNamespace(f, cli, subNs)
+			framework.ExpectNoError(err)
+		})
+
+		ginkgo.It("move project to other tenant", func() {
+			req := f.HttpHelper.Post(f.HttpHelper.FormatUrl("/projects/"+projectName+"/move"), "{\"tenant\":\""+toTenant+"\"}", nil)
+			resp, err := f.HttpHelper.Client.Do(&req)
+			framework.ExpectNoError(err)
+			defer resp.Body.Close()
+			framework.ExpectEqual(http.StatusOK, resp.StatusCode)
+		})
+
+		ginkgo.It("reparent namespaces of project under tenant", func() {
+			err := wait.Poll(f.Timeouts.WaitInterval, f.Timeouts.WaitTimeout, func() (bool, error) {
+				hierarchy := &hnc.HierarchyConfiguration{}
+				err := cli.Direct().Get(context.TODO(), types.NamespacedName{Name: hnc.Singleton, Namespace: constants.ProjectNsPrefix + projectName}, hierarchy)
+				if err != nil {
+					return false, nil
+				}
+				anchor := &hnc.SubnamespaceAnchor{}
+				err = cli.Direct().Get(context.TODO(), types.NamespacedName{Name: constants.ProjectNsPrefix + projectName, Namespace: constants.TenantNsPrefix + toTenant}, anchor)
+				if err != nil {
+					return false, nil
+				}
+				return hierarchy.Spec.Parent == constants.TenantNsPrefix+toTenant && anchor.Status.State == hnc.Ok, nil
+			})
+			framework.ExpectNoError(err)
+
+			// old anchor is deleted without deleting namespace of project
+			err = cli.Direct().Get(context.TODO(), types.NamespacedName{Name: constants.ProjectNsPrefix + projectName, Namespace: constants.TenantNsPrefix + fromTenant}, &hnc.SubnamespaceAnchor{})
+			framework.ExpectEqual(true, apierrors.IsNotFound(err))
+
+			// subnamespace follows project with tenant label relabeled
+			err = wait.Poll(f.Timeouts.WaitInterval, f.Timeouts.WaitTimeout, func() (bool, error) {
+				ns := &v1.Namespace{}
+				err := cli.Direct().Get(context.TODO(), types.NamespacedName{Name: subNs}, ns)
+				if err != nil {
+					return false, nil
+				}
+				return ns.Labels[constants.HncTenantLabel] == toTenant, nil
+			})
+			framework.ExpectNoError(err)
+
+			err = wait.Poll(f.Timeouts.WaitInterval, f.Timeouts.WaitTimeout, func() (bool, error) {
+				project := &tenantv1.Project{}
+				err := cli.Direct().Get(context.TODO(), types.NamespacedName{Name: projectName}, project)
+				if err != nil {
+					return false, nil
+				}
+				return meta.IsStatusConditionTrue(project.Status.Conditions, tenantv1.Reparented), nil
+			})
+			framework.ExpectNoError(err)
+		})
+
+		ginkgo.It("clean up moved project and tenants", func() {
+			ctx := context.TODO()
+			err := cli.Direct().Delete(ctx, &hnc.SubnamespaceAnchor{ObjectMeta: metav1.ObjectMeta{Name: subNs, Namespace: constants.ProjectNsPrefix + projectName}})
+			framework.ExpectNoError(err)
+			err = cli.Direct().Delete(ctx, &hnc.SubnamespaceAnchor{ObjectMeta: metav1.ObjectMeta{Name: constants.ProjectNsPrefix + projectName, Namespace: constants.TenantNsPrefix + toTenant}})
+			framework.ExpectNoError(err)
+			err = wait.Poll(f.Timeouts.WaitInterval, f.Timeouts.WaitTimeout, func() (bool, error) {
+				err := cli.Direct().Get(ctx, types.NamespacedName{Name: constants.ProjectNsPrefix + projectName}, &v1.Namespace{})
+				return apierrors.IsNotFound(err), nil
+			})
+			framework.ExpectNoError(err)
+			err = cli.Direct().Delete(ctx, &tenantv1.Project{ObjectMeta: metav1.ObjectMeta{Name: projectName}})
+			framework.ExpectNoError(err)
+
+			for _, tenant := range []string{fromTenant, toTenant} {
+				ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: constants.TenantNsPrefix + tenant}}
+				err = framework.DeleteNamespace(ns)
+				framework.ExpectNoError(err)
+				err = cli.Direct().Delete(ctx, &tenantv1.Tenant{ObjectMeta: metav1.ObjectMeta{Name: tenant}})
+				framework.ExpectNoError(err)
+			}
+		})
+	})
+})
+
+// waitNamespace waits until namespace is created
+func waitNamespace(f *framework.Framework, cli client.Client, name string) error {
+	return wait.Poll(f.Timeouts.WaitInterval, f.Timeouts.WaitTimeout, func() (bool, error) {
+		err := cli.Direct().Get(context.TODO(), types.NamespacedName{Name: name}, &v1.Namespace{})
+		return err == nil, nil
+	})
+}